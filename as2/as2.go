@@ -0,0 +1,374 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+// Package as2 converts between microformats and ActivityStreams 2.0.
+//
+// h-entry items are converted to an object or activity based on their post
+// type, as determined by the ptd package: likes and reposts become Like and
+// Announce activities, RSVPs become Accept, Reject, or TentativeAccept
+// activities, follows become Follow activities, articles become Article
+// objects, and all other posts become Note objects.  h-card items are
+// converted to Person or Organization actors, and h-event items are
+// converted to Event objects.
+//
+// Decode and ParseObject perform the reverse conversion, producing items
+// with the same property names and value shapes as the microformats parser.
+//
+// See also https://www.w3.org/TR/activitystreams-core/
+package as2
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"willnorris.com/go/microformats"
+	"willnorris.com/go/microformats/internal/feed"
+	"willnorris.com/go/microformats/internal/mftext"
+	"willnorris.com/go/microformats/ptd"
+)
+
+// Context is the JSON-LD context of ActivityStreams 2.0 documents.
+const Context = "https://www.w3.org/ns/activitystreams"
+
+// ErrUnsupportedType is returned when converting a microformat or
+// ActivityStreams object whose type has no equivalent.
+var ErrUnsupportedType = errors.New("as2: unsupported type")
+
+// rsvpTypes maps RSVP values to the corresponding activity type.
+var rsvpTypes = map[string]string{
+	"yes":        "Accept",
+	"no":         "Reject",
+	"maybe":      "TentativeAccept",
+	"interested": "TentativeAccept",
+}
+
+// Convert converts the top-level h-entry, h-card, and h-event items in data
+// to an ActivityStreams OrderedCollection.  Other top-level items, such as
+// h-feed, are replaced by their converted children.
+func Convert(data *microformats.Data) map[string]any {
+	items := []any{}
+	if data != nil {
+		items = appendItems(items, data.Items)
+	}
+	return map[string]any{
+		"@context":     Context,
+		"type":         "OrderedCollection",
+		"totalItems":   len(items),
+		"orderedItems": items,
+	}
+}
+
+func appendItems(objects []any, items []*microformats.Microformat) []any {
+	for _, item := range items {
+		if obj, err := object(item); err == nil {
+			objects = append(objects, obj)
+		} else {
+			objects = appendItems(objects, item.Children)
+		}
+	}
+	return objects
+}
+
+// ConvertItem converts an h-entry, h-card, or h-event item to an
+// ActivityStreams object or activity with the ActivityStreams context.  It
+// returns ErrUnsupportedType for all other items.
+func ConvertItem(item *microformats.Microformat) (map[string]any, error) {
+	obj, err := object(item)
+	if err != nil {
+		return nil, err
+	}
+	obj["@context"] = Context
+	return obj, nil
+}
+
+// object converts item to an ActivityStreams object without a context.
+func object(item *microformats.Microformat) (map[string]any, error) {
+	if item == nil {
+		return nil, ErrUnsupportedType
+	}
+	switch {
+	case slices.Contains(item.Type, "h-entry"):
+		return entryObject(item), nil
+	case slices.Contains(item.Type, "h-card"):
+		return cardObject(item), nil
+	case slices.Contains(item.Type, "h-event"):
+		return eventObject(item), nil
+	}
+	return nil, ErrUnsupportedType
+}
+
+// entryObject converts an h-entry to an object or activity.
+func entryObject(item *microformats.Microformat) map[string]any {
+	obj := map[string]any{}
+	setID(obj, item)
+	set(obj, "published", formatTime(mftext.First(item, "published")))
+	set(obj, "updated", formatTime(mftext.First(item, "updated")))
+
+	var target string
+	postType := ptd.PostType(item)
+	switch postType {
+	case "photo", "video", "audio":
+		// media posts are notes or articles with attachments
+		props := maps.Clone(item.Properties)
+		delete(props, "photo")
+		delete(props, "video")
+		delete(props, "audio")
+		postType = ptd.PostType(&microformats.Microformat{Type: item.Type, Properties: props})
+	case "like":
+		obj["type"], target = "Like", "like-of"
+	case "repost":
+		obj["type"], target = "Announce", "repost-of"
+	case "follow":
+		obj["type"], target = "Follow", "follow-of"
+	case "rsvp":
+		// use the first recognized value, or else convert the rsvp to a
+		// note replying to the event
+		for _, v := range mftext.Values(item, "rsvp") {
+			if typ, ok := rsvpTypes[v]; ok {
+				obj["type"], target = typ, "in-reply-to"
+				break
+			}
+		}
+	}
+	if target != "" {
+		// activities refer to their object, and have an actor rather than
+		// an author
+		set(obj, "actor", actor(item))
+		set(obj, "object", unwrap(urls(item, target)))
+		return obj
+	}
+
+	obj["type"] = "Note"
+	if postType == "article" {
+		obj["type"] = "Article"
+		set(obj, "name", mftext.First(item, "name"))
+	}
+	set(obj, "attributedTo", actor(item))
+	set(obj, "summary", mftext.First(item, "summary"))
+	for _, v := range item.Properties["content"] {
+		switch v := v.(type) {
+		case map[string]string:
+			set(obj, "content", v["html"])
+		case string:
+			set(obj, "content", v)
+		}
+		break
+	}
+	set(obj, "inReplyTo", unwrap(urls(item, "in-reply-to")))
+	set(obj, "attachment", unwrap(attachments(item)))
+	set(obj, "tag", unwrap(tags(item)))
+	return obj
+}
+
+// cardObject converts an h-card to an actor.
+func cardObject(item *microformats.Microformat) map[string]any {
+	obj := map[string]any{"type": "Person"}
+	name := mftext.First(item, "name")
+	if name != "" && name == mftext.First(item, "org") {
+		obj["type"] = "Organization"
+	}
+	setID(obj, item)
+	set(obj, "name", name)
+	set(obj, "preferredUsername", mftext.First(item, "nickname"))
+	set(obj, "summary", mftext.First(item, "note"))
+	if photo := mftext.First(item, "photo"); photo != "" {
+		obj["icon"] = map[string]any{"type": "Image", "url": photo}
+	}
+	return obj
+}
+
+// eventObject converts an h-event to an Event.
+func eventObject(item *microformats.Microformat) map[string]any {
+	obj := map[string]any{"type": "Event"}
+	setID(obj, item)
+	set(obj, "name", mftext.First(item, "name"))
+	set(obj, "summary", mftext.First(item, "summary"))
+	set(obj, "content", mftext.First(item, "description"))
+	set(obj, "startTime", formatTime(mftext.First(item, "start")))
+	set(obj, "endTime", formatTime(mftext.First(item, "end")))
+	set(obj, "duration", mftext.First(item, "duration"))
+	set(obj, "attributedTo", actor(item))
+	for _, v := range item.Properties["location"] {
+		set(obj, "location", place(v))
+		break
+	}
+	set(obj, "tag", unwrap(tags(item)))
+	return obj
+}
+
+// setID sets the id and url of obj to the url of item.
+func setID(obj map[string]any, item *microformats.Microformat) {
+	u := mftext.First(item, "url")
+	set(obj, "url", u)
+	if uid := mftext.First(item, "uid"); isURL(uid) {
+		u = uid
+	}
+	set(obj, "id", u)
+}
+
+// actor returns the author of item as an embedded actor, or the URL or name
+// of the author if it is not an h-card.
+func actor(item *microformats.Microformat) any {
+	for _, v := range item.Properties["author"] {
+		switch v := v.(type) {
+		case *microformats.Microformat:
+			return cardObject(v)
+		default:
+			s := mftext.Value(v)
+			if isURL(s) {
+				return s
+			}
+			if s != "" {
+				return map[string]any{"type": "Person", "name": s}
+			}
+		}
+	}
+	return nil
+}
+
+// attachments returns the photos, videos, and audio of item as Image,
+// Video, and Audio objects.
+func attachments(item *microformats.Microformat) []any {
+	var values []any
+	for _, prop := range []struct{ name, typ string }{
+		{"photo", "Image"},
+		{"video", "Video"},
+		{"audio", "Audio"},
+	} {
+		for _, v := range item.Properties[prop.name] {
+			u := mftext.Value(v)
+			if u == "" {
+				continue
+			}
+			a := map[string]any{"type": prop.typ, "url": u, "mediaType": feed.MediaType(u)}
+			if m, ok := v.(map[string]string); ok {
+				set(a, "name", m["alt"])
+			}
+			values = append(values, a)
+		}
+	}
+	return values
+}
+
+// tags returns the categories of item as Hashtag objects, or as Mention
+// objects for person tags.
+func tags(item *microformats.Microformat) []any {
+	var values []any
+	for _, v := range item.Properties["category"] {
+		switch v := v.(type) {
+		case *microformats.Microformat:
+			m := map[string]any{"type": "Mention"}
+			set(m, "href", mftext.First(v, "url"))
+			name := mftext.First(v, "name")
+			if name == "" {
+				name = v.Value
+			}
+			set(m, "name", name)
+			values = append(values, m)
+		default:
+			s := mftext.Value(v)
+			switch {
+			case s == "":
+			case isURL(s):
+				values = append(values, map[string]any{"type": "Mention", "href": s})
+			default:
+				values = append(values, map[string]any{"type": "Hashtag", "name": "#" + strings.TrimPrefix(s, "#")})
+			}
+		}
+	}
+	return values
+}
+
+// place returns an event location as a Place object.
+func place(v any) any {
+	loc, ok := v.(*microformats.Microformat)
+	if !ok {
+		if s := mftext.Value(v); s != "" {
+			return map[string]any{"type": "Place", "name": s}
+		}
+		return nil
+	}
+	p := map[string]any{"type": "Place"}
+	set(p, "name", mftext.Value(loc))
+	set(p, "url", mftext.First(loc, "url"))
+	lat, long := mftext.First(loc, "latitude"), mftext.First(loc, "longitude")
+	for _, g := range loc.Properties["geo"] {
+		if g, ok := g.(*microformats.Microformat); ok && lat == "" {
+			lat, long = mftext.First(g, "latitude"), mftext.First(g, "longitude")
+		}
+	}
+	if lat, err := strconv.ParseFloat(lat, 64); err == nil {
+		p["latitude"] = lat
+	}
+	if long, err := strconv.ParseFloat(long, 64); err == nil {
+		p["longitude"] = long
+	}
+	return p
+}
+
+// urls returns the URLs of the named property of item, which may be plain
+// URLs or nested microformats such as h-cite.
+func urls(item *microformats.Microformat, name string) []any {
+	var values []any
+	for _, v := range item.Properties[name] {
+		u := mftext.Value(v)
+		if mf, ok := v.(*microformats.Microformat); ok {
+			if s := mftext.First(mf, "url"); s != "" {
+				u = s
+			}
+		}
+		if u != "" {
+			values = append(values, u)
+		}
+	}
+	return values
+}
+
+// formatTime converts a microformats datetime value to RFC 3339.  Values
+// without a timezone are treated as UTC, and values without a date are
+// ignored.
+func formatTime(s string) string {
+	dt, ok := microformats.ParseDateTime(s)
+	if !ok || !dt.HasDate {
+		return ""
+	}
+	return dt.Time.Format(time.RFC3339)
+}
+
+// set sets obj[name] to v, unless v is empty.
+func set(obj map[string]any, name string, v any) {
+	switch v := v.(type) {
+	case nil:
+		return
+	case string:
+		if v == "" {
+			return
+		}
+	case map[string]any:
+		if v == nil {
+			return
+		}
+	}
+	obj[name] = v
+}
+
+// unwrap returns the only value in values, values itself if it has more
+// than one value, or nil if it is empty.
+func unwrap(values []any) any {
+	switch len(values) {
+	case 0:
+		return nil
+	case 1:
+		return values[0]
+	}
+	return values
+}
+
+// isURL returns whether s is an absolute http or https URL.
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "http://")
+}
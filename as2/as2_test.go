@@ -0,0 +1,210 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package as2
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"willnorris.com/go/microformats"
+)
+
+func parse(t *testing.T, html string) *microformats.Data {
+	t.Helper()
+	base, _ := url.Parse("https://example.com/")
+	return microformats.Parse(strings.NewReader(html), base)
+}
+
+func TestConvertItem(t *testing.T) {
+	alice := map[string]any{
+		"type": "Person",
+		"id":   "https://example.com/",
+		"url":  "https://example.com/",
+		"name": "Alice",
+		"icon": map[string]any{"type": "Image", "url": "https://example.com/alice.jpg"},
+	}
+
+	tests := []struct {
+		name string
+		html string
+		want map[string]any
+	}{
+		{
+			name: "note",
+			html: `<article class="h-entry"><a class="u-url" href="/1">x</a>
+			  <div class="p-author h-card"><a class="p-name u-url" href="/">Alice</a><img class="u-photo" src="/alice.jpg"></div>
+			  <time class="dt-published" datetime="2024-05-01 10:00:00-07:00">May 1</time>
+			  <p class="e-content">Hello <b>world</b></p>
+			  <a class="u-in-reply-to" href="https://other.example/post">in reply to</a>
+			  <img class="u-photo" src="/a.jpg" alt="A cat">
+			  <span class="p-category">go</span>
+			  <a class="u-category h-card" href="https://bob.example/">Bob</a></article>`,
+			want: map[string]any{
+				"@context":     Context,
+				"type":         "Note",
+				"id":           "https://example.com/1",
+				"url":          "https://example.com/1",
+				"published":    "2024-05-01T10:00:00-07:00",
+				"attributedTo": alice,
+				"content":      "Hello <b>world</b>",
+				"inReplyTo":    "https://other.example/post",
+				"attachment":   map[string]any{"type": "Image", "url": "https://example.com/a.jpg", "mediaType": "image/jpeg", "name": "A cat"},
+				"tag": []any{
+					map[string]any{"type": "Hashtag", "name": "#go"},
+					map[string]any{"type": "Mention", "href": "https://bob.example/", "name": "Bob"},
+				},
+			},
+		},
+		{
+			name: "article",
+			html: `<article class="h-entry"><h1 class="p-name">Title</h1><a class="u-url" href="/2">x</a>
+			  <video class="u-video" src="/v.mp4"></video>
+			  <data class="u-uid" value="https://example.com/posts/2"></data>
+			  <p class="p-summary">Short</p>
+			  <div class="e-content"><p>Some long content</p></div></article>`,
+			want: map[string]any{
+				"@context":   Context,
+				"type":       "Article",
+				"id":         "https://example.com/posts/2",
+				"url":        "https://example.com/2",
+				"name":       "Title",
+				"summary":    "Short",
+				"content":    "<p>Some long content</p>",
+				"attachment": map[string]any{"type": "Video", "url": "https://example.com/v.mp4", "mediaType": "video/mp4"},
+			},
+		},
+		{
+			name: "like",
+			html: `<article class="h-entry"><a class="u-url" href="/3">x</a>
+			  <a class="p-author" href="/">Alice</a>
+			  <a class="u-like-of" href="https://other.example/post">liked</a></article>`,
+			want: map[string]any{
+				"@context": Context,
+				"type":     "Like",
+				"id":       "https://example.com/3",
+				"url":      "https://example.com/3",
+				"actor":    map[string]any{"type": "Person", "name": "Alice"},
+				"object":   "https://other.example/post",
+			},
+		},
+		{
+			name: "repost",
+			html: `<article class="h-entry"><a class="u-url" href="/4">x</a>
+			  <a class="u-repost-of" href="https://other.example/post">reposted</a></article>`,
+			want: map[string]any{
+				"@context": Context,
+				"type":     "Announce",
+				"id":       "https://example.com/4",
+				"url":      "https://example.com/4",
+				"object":   "https://other.example/post",
+			},
+		},
+		{
+			name: "rsvp",
+			html: `<article class="h-entry"><a class="u-url" href="/5">x</a>
+			  <data class="p-rsvp" value="maybe">Maybe</data>
+			  <a class="u-in-reply-to" href="https://other.example/event">event</a></article>`,
+			want: map[string]any{
+				"@context": Context,
+				"type":     "TentativeAccept",
+				"id":       "https://example.com/5",
+				"url":      "https://example.com/5",
+				"object":   "https://other.example/event",
+			},
+		},
+		{
+			name: "rsvp with other values",
+			html: `<article class="h-entry"><a class="u-url" href="/6">x</a>
+			  <span class="p-rsvp">Count me in</span><data class="p-rsvp" value="yes">Yes</data>
+			  <a class="u-in-reply-to" href="https://other.example/event">event</a></article>`,
+			want: map[string]any{
+				"@context": Context,
+				"type":     "Accept",
+				"id":       "https://example.com/6",
+				"url":      "https://example.com/6",
+				"object":   "https://other.example/event",
+			},
+		},
+		{
+			name: "card",
+			html: `<div class="h-card"><a class="p-name p-org u-url" href="/">Example Inc</a>
+			  <span class="p-nickname">example</span><p class="p-note">We make things</p></div>`,
+			want: map[string]any{
+				"@context":          Context,
+				"type":              "Organization",
+				"id":                "https://example.com/",
+				"url":               "https://example.com/",
+				"name":              "Example Inc",
+				"preferredUsername": "example",
+				"summary":           "We make things",
+			},
+		},
+		{
+			name: "event",
+			html: `<div class="h-event"><h1 class="p-name">Meetup</h1><a class="u-url" href="/e">x</a>
+			  <time class="dt-start" datetime="2024-05-01 18:00:00Z">May 1</time>
+			  <time class="dt-end" datetime="2024-05-01 20:00:00Z">8pm</time>
+			  <p class="p-description">Talks and food</p>
+			  <div class="p-location h-card"><span class="p-name">Library</span>
+			    <data class="p-latitude" value="37.7749"></data><data class="p-longitude" value="-122.4194"></data></div></div>`,
+			want: map[string]any{
+				"@context":  Context,
+				"type":      "Event",
+				"id":        "https://example.com/e",
+				"url":       "https://example.com/e",
+				"name":      "Meetup",
+				"content":   "Talks and food",
+				"startTime": "2024-05-01T18:00:00Z",
+				"endTime":   "2024-05-01T20:00:00Z",
+				"location":  map[string]any{"type": "Place", "name": "Library", "latitude": 37.7749, "longitude": -122.4194},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := parse(t, tt.html)
+			got, err := ConvertItem(data.Items[0])
+			if err != nil {
+				t.Fatalf("ConvertItem() returned error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ConvertItem() differs:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConvertItem_Unsupported(t *testing.T) {
+	for _, item := range []*microformats.Microformat{nil, {Type: []string{"h-feed"}}} {
+		if _, err := ConvertItem(item); !errors.Is(err, ErrUnsupportedType) {
+			t.Errorf("ConvertItem(%v) returned %v, want %v", item, err, ErrUnsupportedType)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	data := parse(t, `<main class="h-feed"><h1 class="p-name">Blog</h1>
+	  <article class="h-entry"><a class="u-url" href="/1">x</a><p class="p-content">One</p></article>
+	  <article class="h-entry"><a class="u-url" href="/2">x</a><p class="p-content">Two</p></article>
+	</main>
+	<div class="h-product"><span class="p-name">Widget</span></div>`)
+
+	got := Convert(data)
+	want := map[string]any{
+		"@context":   Context,
+		"type":       "OrderedCollection",
+		"totalItems": 2,
+		"orderedItems": []any{
+			map[string]any{"type": "Note", "id": "https://example.com/1", "url": "https://example.com/1", "content": "One"},
+			map[string]any{"type": "Note", "id": "https://example.com/2", "url": "https://example.com/2", "content": "Two"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Convert() differs:\n%s", diff)
+	}
+}
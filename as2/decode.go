@@ -0,0 +1,404 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package as2
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"willnorris.com/go/microformats"
+	"willnorris.com/go/microformats/internal/feed"
+	"willnorris.com/go/microformats/internal/mftext"
+)
+
+// Decode reads an ActivityStreams 2.0 document from r and returns its
+// objects as microformats.  A collection or collection page is converted to
+// its supported items, and other documents are converted to a single item.
+// Create and Update activities are converted to their object.
+func Decode(r io.Reader) ([]*microformats.Microformat, error) {
+	var obj map[string]any
+	if err := json.NewDecoder(r).Decode(&obj); err != nil {
+		return nil, fmt.Errorf("as2: %w", err)
+	}
+
+	switch typeOf(obj) {
+	case "Collection", "OrderedCollection", "CollectionPage", "OrderedCollectionPage":
+		var items []*microformats.Microformat
+		for _, name := range []string{"orderedItems", "items"} {
+			for _, v := range values(obj[name]) {
+				if o, ok := v.(map[string]any); ok {
+					if item, err := ParseObject(o); err == nil {
+						items = append(items, item)
+					}
+				}
+			}
+		}
+		return items, nil
+	}
+
+	item, err := ParseObject(obj)
+	if err != nil {
+		return nil, err
+	}
+	return []*microformats.Microformat{item}, nil
+}
+
+// ParseObject converts a decoded ActivityStreams object or activity to a
+// microformat.  Actors are converted to h-card, events to h-event, and all
+// other supported objects and activities to h-entry.  It returns
+// ErrUnsupportedType for other types, such as collections.
+func ParseObject(obj map[string]any) (*microformats.Microformat, error) {
+	switch t := typeOf(obj); t {
+	case "Note", "Article", "Page", "Image", "Video", "Audio", "Question":
+		return parseEntry(obj), nil
+	case "Person", "Organization", "Group", "Service", "Application":
+		return parseCard(obj), nil
+	case "Event":
+		return parseEvent(obj), nil
+	case "Create", "Update":
+		inner, ok := obj["object"].(map[string]any)
+		if !ok {
+			return nil, ErrUnsupportedType
+		}
+		item, err := ParseObject(inner)
+		if err == nil && slices.Contains(item.Type, "h-entry") && item.Properties["author"] == nil {
+			add(item.Properties, "author", author(obj["actor"]))
+		}
+		return item, err
+	case "Like", "Announce", "Follow", "Accept", "Reject", "TentativeAccept":
+		return parseActivity(obj, t), nil
+	}
+	return nil, ErrUnsupportedType
+}
+
+// parseEntry converts an object to an h-entry.
+func parseEntry(obj map[string]any) *microformats.Microformat {
+	item := newItem("h-entry")
+	props := item.Properties
+	if typeOf(obj) == "Article" || typeOf(obj) == "Page" {
+		add(props, "name", text(obj, "name"))
+	}
+	addURLs(props, obj)
+	add(props, "summary", text(obj, "summary"))
+	if content := text(obj, "content"); content != "" {
+		if mediaType, _ := obj["mediaType"].(string); mediaType == "text/plain" {
+			content = html.EscapeString(content)
+		}
+		add(props, "content", map[string]string{"html": content, "value": feed.TextContent(content)})
+	}
+	add(props, "published", parseTime(obj["published"]))
+	add(props, "updated", parseTime(obj["updated"]))
+	add(props, "author", author(obj["attributedTo"]))
+	for _, v := range values(obj["inReplyTo"]) {
+		add(props, "in-reply-to", urlOf(v))
+	}
+
+	// media objects are their own attachment, and their name is a title
+	// rather than alt text
+	media := values(obj["attachment"])
+	self := false
+	if t := typeOf(obj); t == "Image" || t == "Video" || t == "Audio" {
+		media, self = append([]any{obj}, media...), true
+	}
+	for i, v := range media {
+		a, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		u := urlOf(a["url"])
+		if u == "" {
+			u = urlOf(a)
+		}
+		var name string
+		switch mediaType, _ := a["mediaType"].(string); {
+		case typeOf(a) == "Image" || strings.HasPrefix(mediaType, "image/"):
+			name = "photo"
+		case typeOf(a) == "Video" || strings.HasPrefix(mediaType, "video/"):
+			name = "video"
+		case typeOf(a) == "Audio" || strings.HasPrefix(mediaType, "audio/"):
+			name = "audio"
+		default:
+			continue
+		}
+		if alt := text(a, "name"); alt != "" && name == "photo" && !(self && i == 0) {
+			add(props, name, map[string]string{"value": u, "alt": alt})
+		} else {
+			add(props, name, u)
+		}
+	}
+
+	for _, v := range values(obj["tag"]) {
+		tag, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		switch typeOf(tag) {
+		case "Hashtag":
+			add(props, "category", strings.TrimPrefix(text(tag, "name"), "#"))
+		case "Mention":
+			card := newItem("h-card")
+			add(card.Properties, "name", text(tag, "name"))
+			add(card.Properties, "url", urlOf(tag["href"]))
+			card.Value = text(tag, "name")
+			if card.Value == "" {
+				card.Value = urlOf(tag["href"])
+			}
+			if card.Value != "" {
+				add(props, "category", card)
+			}
+		}
+	}
+	return item
+}
+
+// parseActivity converts an activity to an h-entry for a like, repost,
+// follow, or RSVP.
+func parseActivity(obj map[string]any, typ string) *microformats.Microformat {
+	item := newItem("h-entry")
+	props := item.Properties
+	addURLs(props, obj)
+	add(props, "published", parseTime(obj["published"]))
+	add(props, "updated", parseTime(obj["updated"]))
+	add(props, "author", author(obj["actor"]))
+
+	target := map[string]string{
+		"Like":     "like-of",
+		"Announce": "repost-of",
+		"Follow":   "follow-of",
+	}[typ]
+	if target == "" {
+		target = "in-reply-to"
+		for rsvp, t := range rsvpTypes {
+			// TentativeAccept is used for both maybe and interested
+			if t == typ && rsvp != "interested" {
+				add(props, "rsvp", rsvp)
+			}
+		}
+	}
+	for _, v := range values(obj["object"]) {
+		add(props, target, urlOf(v))
+	}
+	return item
+}
+
+// parseCard converts an actor to an h-card.
+func parseCard(obj map[string]any) *microformats.Microformat {
+	item := newItem("h-card")
+	props := item.Properties
+	name := text(obj, "name")
+	add(props, "name", name)
+	addURLs(props, obj)
+	add(props, "nickname", text(obj, "preferredUsername"))
+	add(props, "note", text(obj, "summary"))
+	for _, v := range values(obj["icon"]) {
+		add(props, "photo", urlOf(v))
+	}
+	if typeOf(obj) == "Organization" {
+		add(props, "org", name)
+	}
+	item.Value = name
+	if item.Value == "" {
+		item.Value = mftext.First(item, "url")
+	}
+	return item
+}
+
+// parseEvent converts an Event to an h-event.
+func parseEvent(obj map[string]any) *microformats.Microformat {
+	item := newItem("h-event")
+	props := item.Properties
+	add(props, "name", text(obj, "name"))
+	addURLs(props, obj)
+	add(props, "summary", text(obj, "summary"))
+	add(props, "description", feed.TextContent(text(obj, "content")))
+	add(props, "start", parseTime(obj["startTime"]))
+	add(props, "end", parseTime(obj["endTime"]))
+	add(props, "duration", text(obj, "duration"))
+	add(props, "author", author(obj["attributedTo"]))
+
+	for _, v := range values(obj["location"]) {
+		switch loc := v.(type) {
+		case string:
+			add(props, "location", loc)
+		case map[string]any:
+			card := newItem("h-card")
+			add(card.Properties, "name", text(loc, "name"))
+			add(card.Properties, "url", urlOf(loc["url"]))
+			add(card.Properties, "latitude", number(loc["latitude"]))
+			add(card.Properties, "longitude", number(loc["longitude"]))
+			card.Value = text(loc, "name")
+			if len(card.Properties) > 0 {
+				add(props, "location", card)
+			}
+		}
+	}
+	for _, v := range values(obj["tag"]) {
+		if tag, ok := v.(map[string]any); ok && typeOf(tag) == "Hashtag" {
+			add(props, "category", strings.TrimPrefix(text(tag, "name"), "#"))
+		}
+	}
+	return item
+}
+
+// author returns the h-card for an embedded actor, or the URL of an actor
+// that is not embedded.
+func author(v any) any {
+	for _, v := range values(v) {
+		switch v := v.(type) {
+		case string:
+			return v
+		case map[string]any:
+			return parseCard(v)
+		}
+	}
+	return nil
+}
+
+// addURLs adds the url and uid properties of item for obj.  The url is the
+// url of the object, or else its id.  The uid is the id, if it differs from
+// the url.
+func addURLs(props map[string][]any, obj map[string]any) {
+	id, _ := obj["id"].(string)
+	u := ""
+	for _, v := range values(obj["url"]) {
+		// prefer an HTML link, such as the profile page of an actor
+		if l, ok := v.(map[string]any); ok {
+			if mt, _ := l["mediaType"].(string); mt != "" && mt != "text/html" {
+				continue
+			}
+		}
+		if u = urlOf(v); u != "" {
+			break
+		}
+	}
+	if u == "" {
+		u = id
+	}
+	add(props, "url", u)
+	if id != u {
+		add(props, "uid", id)
+	}
+}
+
+// newItem returns a new microformat of the specified type.
+func newItem(typ string) *microformats.Microformat {
+	return &microformats.Microformat{
+		Type:       []string{typ},
+		Properties: make(map[string][]any),
+	}
+}
+
+// add adds v to the values of the named property in props, unless v is
+// empty.
+func add(props map[string][]any, name string, v any) {
+	switch v := v.(type) {
+	case nil:
+		return
+	case string:
+		if v == "" {
+			return
+		}
+	case *microformats.Microformat:
+		if v == nil {
+			return
+		}
+	}
+	props[name] = append(props[name], v)
+}
+
+// typeOf returns the first type of obj.
+func typeOf(obj map[string]any) string {
+	for _, v := range values(obj["type"]) {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// values returns v as a slice, since ActivityStreams properties may have a
+// single value or an array of values.
+func values(v any) []any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []any:
+		return v
+	}
+	return []any{v}
+}
+
+// text returns the string value of the named property of obj.  Natural
+// language values are read from the property's map form if necessary,
+// preferring the first language in sorted order.
+func text(obj map[string]any, name string) string {
+	if s, ok := obj[name].(string); ok {
+		return s
+	}
+	if m, ok := obj[name+"Map"].(map[string]any); ok {
+		for _, lang := range slices.Sorted(maps.Keys(m)) {
+			if s, ok := m[lang].(string); ok && s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// urlOf returns the URL of a link value, which may be a URL string, a Link
+// object, or an object with an id.
+func urlOf(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case map[string]any:
+		if typeOf(v) == "Link" || v["href"] != nil {
+			s, _ := v["href"].(string)
+			return s
+		}
+		if u := urlOf(v["url"]); u != "" && typeOf(v) != "Image" {
+			return u
+		}
+		if id, ok := v["id"].(string); ok {
+			return id
+		}
+		return urlOf(v["url"])
+	case []any:
+		for _, v := range v {
+			if u := urlOf(v); u != "" {
+				return u
+			}
+		}
+	}
+	return ""
+}
+
+// number returns the string form of a numeric value.
+func number(v any) string {
+	switch v := v.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	}
+	return ""
+}
+
+// parseTime converts an RFC 3339 timestamp to the normalized microformats
+// datetime format.
+func parseTime(v any) string {
+	s, _ := v.(string)
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return ""
+	}
+	return microformats.DateTime{Time: t, HasDate: true, HasTime: true, HasTZ: true, HasSeconds: true}.String()
+}
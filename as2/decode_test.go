@@ -0,0 +1,260 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package as2
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"willnorris.com/go/microformats"
+)
+
+// ignoreUnexported ignores the parser's internal state when comparing
+// microformats.
+var ignoreUnexported = cmpopts.IgnoreUnexported(microformats.Microformat{})
+
+func TestDecode(t *testing.T) {
+	alice := &microformats.Microformat{
+		Type: []string{"h-card"},
+		Properties: map[string][]any{
+			"name":     {"Alice"},
+			"url":      {"https://example.com/@alice"},
+			"uid":      {"https://example.com/users/alice"},
+			"nickname": {"alice"},
+			"photo":    {"https://example.com/alice.jpg"},
+		},
+		Value: "Alice",
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  []*microformats.Microformat
+	}{
+		{
+			name: "create note",
+			input: `{
+			  "@context": "https://www.w3.org/ns/activitystreams",
+			  "type": "Create",
+			  "actor": {
+			    "type": "Person",
+			    "id": "https://example.com/users/alice",
+			    "url": "https://example.com/@alice",
+			    "name": "Alice",
+			    "preferredUsername": "alice",
+			    "icon": {"type": "Image", "mediaType": "image/jpeg", "url": "https://example.com/alice.jpg"}
+			  },
+			  "object": {
+			    "type": "Note",
+			    "id": "https://example.com/notes/1",
+			    "published": "2024-05-01T10:00:00-07:00",
+			    "contentMap": {"en": "<p>Hello <b>world</b></p>"},
+			    "inReplyTo": "https://other.example/post",
+			    "attachment": [
+			      {"type": "Document", "mediaType": "image/png", "url": "https://example.com/a.png", "name": "A cat"},
+			      {"type": "Document", "mediaType": "application/pdf", "url": "https://example.com/b.pdf"}
+			    ],
+			    "tag": [
+			      {"type": "Hashtag", "href": "https://example.com/tags/go", "name": "#go"},
+			      {"type": "Mention", "href": "https://bob.example/", "name": "@bob"}
+			    ]
+			  }
+			}`,
+			want: []*microformats.Microformat{{
+				Type: []string{"h-entry"},
+				Properties: map[string][]any{
+					"url":         {"https://example.com/notes/1"},
+					"content":     {map[string]string{"html": "<p>Hello <b>world</b></p>", "value": "Hello world"}},
+					"published":   {"2024-05-01 10:00:00-0700"},
+					"in-reply-to": {"https://other.example/post"},
+					"photo":       {map[string]string{"value": "https://example.com/a.png", "alt": "A cat"}},
+					"category": {"go", &microformats.Microformat{
+						Type:       []string{"h-card"},
+						Properties: map[string][]any{"name": {"@bob"}, "url": {"https://bob.example/"}},
+						Value:      "@bob",
+					}},
+					"author": {alice},
+				},
+			}},
+		},
+		{
+			name: "article",
+			input: `{
+			  "type": "Article",
+			  "id": "https://example.com/posts/2",
+			  "url": "https://example.com/2",
+			  "name": "Title",
+			  "summary": "Short",
+			  "content": "Plain <text>",
+			  "mediaType": "text/plain",
+			  "updated": "2024-05-02T00:00:00Z",
+			  "attributedTo": "https://example.com/"
+			}`,
+			want: []*microformats.Microformat{{
+				Type: []string{"h-entry"},
+				Properties: map[string][]any{
+					"name":    {"Title"},
+					"url":     {"https://example.com/2"},
+					"uid":     {"https://example.com/posts/2"},
+					"summary": {"Short"},
+					"content": {map[string]string{"html": "Plain &lt;text&gt;", "value": "Plain <text>"}},
+					"updated": {"2024-05-02 00:00:00Z"},
+					"author":  {"https://example.com/"},
+				},
+			}},
+		},
+		{
+			name: "collection",
+			input: `{
+			  "type": "OrderedCollection",
+			  "orderedItems": [
+			    {"type": "Like", "id": "https://example.com/likes/1", "actor": "https://example.com/", "object": "https://other.example/post"},
+			    {"type": "Announce", "id": "https://example.com/reposts/1", "object": {"type": "Note", "id": "https://other.example/post"}},
+			    {"type": "Reject", "id": "https://example.com/rsvps/1", "object": "https://other.example/event"},
+			    {"type": "Delete", "id": "https://example.com/deletes/1", "object": "https://example.com/notes/1"},
+			    {"type": "Organization", "id": "https://example.com/org", "name": "Example Inc"},
+			    {
+			      "type": "Event",
+			      "id": "https://example.com/events/1",
+			      "name": "Meetup",
+			      "content": "<p>Talks and food</p>",
+			      "startTime": "2024-05-01T18:00:00Z",
+			      "location": {"type": "Place", "name": "Library", "latitude": 37.7749, "longitude": -122.4194}
+			    }
+			  ]
+			}`,
+			want: []*microformats.Microformat{
+				{
+					Type: []string{"h-entry"},
+					Properties: map[string][]any{
+						"url":     {"https://example.com/likes/1"},
+						"author":  {"https://example.com/"},
+						"like-of": {"https://other.example/post"},
+					},
+				},
+				{
+					Type: []string{"h-entry"},
+					Properties: map[string][]any{
+						"url":       {"https://example.com/reposts/1"},
+						"repost-of": {"https://other.example/post"},
+					},
+				},
+				{
+					Type: []string{"h-entry"},
+					Properties: map[string][]any{
+						"url":         {"https://example.com/rsvps/1"},
+						"rsvp":        {"no"},
+						"in-reply-to": {"https://other.example/event"},
+					},
+				},
+				{
+					Type: []string{"h-card"},
+					Properties: map[string][]any{
+						"name": {"Example Inc"},
+						"url":  {"https://example.com/org"},
+						"org":  {"Example Inc"},
+					},
+					Value: "Example Inc",
+				},
+				{
+					Type: []string{"h-event"},
+					Properties: map[string][]any{
+						"name":        {"Meetup"},
+						"url":         {"https://example.com/events/1"},
+						"description": {"Talks and food"},
+						"start":       {"2024-05-01 18:00:00Z"},
+						"location": {&microformats.Microformat{
+							Type:       []string{"h-card"},
+							Properties: map[string][]any{"name": {"Library"}, "latitude": {"37.7749"}, "longitude": {"-122.4194"}},
+							Value:      "Library",
+						}},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Decode() returned error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got, ignoreUnexported); diff != "" {
+				t.Errorf("Decode() differs:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{"", nil},
+		{"[]", nil},
+		{`{"type": "Delete"}`, ErrUnsupportedType},
+		{`{"type": "Create", "object": "https://example.com/1"}`, ErrUnsupportedType},
+	}
+	for _, tt := range tests {
+		_, err := Decode(strings.NewReader(tt.input))
+		if err == nil || (tt.want != nil && !errors.Is(err, tt.want)) {
+			t.Errorf("Decode(%q) returned %v, want %v", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestDecode_RoundTrip(t *testing.T) {
+	data := parse(t, `<main class="h-feed">
+	  <article class="h-entry"><h1 class="p-name">Title</h1><a class="u-url" href="/1">x</a>
+	    <div class="p-author h-card"><a class="p-name u-url" href="/">Alice</a></div>
+	    <time class="dt-published" datetime="2024-05-01 10:00:00-07:00">May 1</time>
+	    <div class="e-content"><p>Some <b>long</b> content</p></div>
+	    <span class="p-category">go</span></article>
+	  <article class="h-entry"><a class="u-url" href="/2">x</a>
+	    <a class="u-like-of" href="https://other.example/post">liked</a></article>
+	</main>`)
+
+	b, err := json.Marshal(Convert(data))
+	if err != nil {
+		t.Fatalf("json.Marshal(Convert()) returned error: %v", err)
+	}
+	got, err := Decode(strings.NewReader(string(b)))
+	if err != nil {
+		t.Fatalf("Decode() returned error: %v", err)
+	}
+
+	want := []*microformats.Microformat{
+		{
+			Type: []string{"h-entry"},
+			Properties: map[string][]any{
+				"name":      {"Title"},
+				"url":       {"https://example.com/1"},
+				"published": {"2024-05-01 10:00:00-0700"},
+				"content":   {map[string]string{"html": "<p>Some <b>long</b> content</p>", "value": "Some long content"}},
+				"category":  {"go"},
+				"author": {&microformats.Microformat{
+					Type:       []string{"h-card"},
+					Properties: map[string][]any{"name": {"Alice"}, "url": {"https://example.com/"}},
+					Value:      "Alice",
+				}},
+			},
+		},
+		{
+			Type: []string{"h-entry"},
+			Properties: map[string][]any{
+				"url":     {"https://example.com/2"},
+				"like-of": {"https://other.example/post"},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got, ignoreUnexported); diff != "" {
+		t.Errorf("Decode(Convert()) differs:\n%s", diff)
+	}
+}
@@ -6,6 +6,7 @@
 package microformats
 
 import (
+	"fmt"
 	"net/url"
 	"path"
 	"strings"
@@ -320,8 +321,16 @@ func (p *parser) backcompatIncludeNode(node *html.Node, refs []string, replace b
 	}
 
 	for _, ref := range refs {
-		if n := findNodeByID(p.root, ref); n != nil {
+		if n := p.findNodeByID(p.root, ref); n != nil {
 			if node != n && !isAncestorNode(node, n) {
+				if limit := p.opts.Limits.MaxIncludes; limit > 0 && p.includes >= limit {
+					p.report(Diagnostic{
+						Code:    DiagnosticMaxIncludes,
+						Message: fmt.Sprintf("includes beyond the first %d were ignored", limit),
+					})
+					return node
+				}
+				p.includes++
 				if replace {
 					return n
 				}
@@ -335,12 +344,12 @@ func (p *parser) backcompatIncludeNode(node *html.Node, refs []string, replace b
 
 // findNodeByID searches node and its children, returning the node with the
 // specified id value.
-func findNodeByID(node *html.Node, id string) *html.Node {
+func (p *parser) findNodeByID(node *html.Node, id string) *html.Node {
 	if getAttr(node, "id") == id {
 		return node
 	}
-	for c := node.FirstChild; c != nil; c = c.NextSibling {
-		if n := findNodeByID(c, id); n != nil {
+	for c := p.children(node); c != nil; c = c.NextSibling {
+		if n := p.findNodeByID(c, id); n != nil {
 			return n
 		}
 	}
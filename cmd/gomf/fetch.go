@@ -0,0 +1,160 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"willnorris.com/go/microformats/fetch"
+)
+
+var (
+	userAgentFlag    = flag.String("user-agent", "gomf (+https://willnorris.com/go/microformats)", "User-Agent header sent when fetching URLs")
+	acceptFlag       = flag.String("accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8", "Accept header sent when fetching URLs")
+	cookiesFlag      = flag.String("cookies", "", "file of cookies to send when fetching URLs, in Netscape cookies.txt format")
+	timeoutFlag      = flag.Duration("timeout", 30*time.Second, "timeout for fetching each URL, including redirects")
+	maxRedirectsFlag = flag.Int("max-redirects", 10, "maximum number of redirects to follow")
+	maxSizeFlag      = flag.Int64("max-size", fetch.DefaultMaxBodySize, "maximum size in bytes of fetched response bodies")
+	cacheDirFlag     = flag.String("cache-dir", "", "directory to cache fetched pages in, honoring HTTP caching headers")
+
+	headerFlag stringsFlag
+)
+
+func init() {
+	flag.Var(&headerFlag, "header", `additional header sent when fetching URLs, formatted as "Name: value"; may be repeated`)
+}
+
+// newFetcher returns a fetch.Client configured by the fetch flags.
+func newFetcher() (*fetch.Client, error) {
+	hc := &http.Client{
+		CheckRedirect: func(_ *http.Request, via []*http.Request) error {
+			if len(via) > *maxRedirectsFlag {
+				return fmt.Errorf("stopped after %d redirects", *maxRedirectsFlag)
+			}
+			return nil
+		},
+	}
+	if *cookiesFlag != "" {
+		jar, err := loadCookies(*cookiesFlag)
+		if err != nil {
+			return nil, fmt.Errorf("error loading cookies: %w", err)
+		}
+		hc.Jar = jar
+	}
+
+	header := make(http.Header)
+	header.Set("User-Agent", *userAgentFlag)
+	header.Set("Accept", *acceptFlag)
+	for _, h := range headerFlag {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid header %q", h)
+		}
+		header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	// pages are parsed by the caller of fetchURL, with the options given
+	// by the parsing flags, and feeds are not HTML
+	f := &fetch.Client{
+		HTTPClient:  hc,
+		Header:      header,
+		MaxBodySize: *maxSizeFlag,
+		Timeout:     *timeoutFlag,
+		SkipParse:   true,
+	}
+	if *cacheDirFlag != "" {
+		f.Cache = fetch.NewDiskCache(*cacheDirFlag)
+	}
+	return f, nil
+}
+
+// fetchURL retrieves the contents of u.  The returned URL is the base URL of
+// the response, which accounts for any redirects and Content-Location
+// header.  The Content-Type of the response is also returned.
+func fetchURL(f fetch.Fetcher, u *url.URL) (io.ReadCloser, *url.URL, string, error) {
+	resp, err := f.Fetch(context.Background(), u.String())
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if resp.URL.String() != u.String() {
+		log.Printf("%s: redirected to %s", u, resp.URL)
+	}
+	return io.NopCloser(bytes.NewReader(resp.Body)), resp.BaseURL(), resp.Header.Get("Content-Type"), nil
+}
+
+// loadCookies returns a cookie jar populated with the cookies in the named
+// file, which uses the Netscape cookies.txt format.
+func loadCookies(name string) (http.CookieJar, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s := bufio.NewScanner(f)
+	var n int
+	for s.Scan() {
+		n++
+		line := strings.TrimSpace(s.Text())
+
+		// curl marks HttpOnly cookies with a prefix that otherwise looks like a comment
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("%s:%d: expected 7 tab-separated fields, found %d", name, n, len(fields))
+		}
+		domain, subdomains, path, secure, expires := fields[0], fields[1], fields[2], fields[3], fields[4]
+
+		c := &http.Cookie{
+			Name:   fields[5],
+			Value:  fields[6],
+			Path:   path,
+			Secure: strings.EqualFold(secure, "TRUE"),
+		}
+		if strings.EqualFold(subdomains, "TRUE") {
+			c.Domain = domain
+		}
+		if exp, err := strconv.ParseInt(expires, 10, 64); err == nil && exp > 0 {
+			c.Expires = time.Unix(exp, 0)
+		}
+
+		scheme := "http"
+		if c.Secure {
+			scheme = "https"
+		}
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: strings.TrimPrefix(domain, "."), Path: path}, []*http.Cookie{c})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, errors.New("cookies file is empty")
+	}
+	return jar, nil
+}
@@ -0,0 +1,202 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"willnorris.com/go/microformats"
+	"willnorris.com/go/microformats/as2"
+	"willnorris.com/go/microformats/feedxml"
+	"willnorris.com/go/microformats/ical"
+	"willnorris.com/go/microformats/jsonfeed"
+	"willnorris.com/go/microformats/jsonld"
+	"willnorris.com/go/microformats/render"
+	"willnorris.com/go/microformats/vcard"
+)
+
+// format is an output format supported by gomf.
+type format struct {
+	// encode writes data to w in this format.
+	encode func(w io.Writer, data *microformats.Data) error
+
+	// file extension used when writing output to a directory
+	ext string
+}
+
+// formats maps the names used with the -format flag to their format.
+var formats = map[string]format{
+	"as2":          {encodeAS2, ".json"},
+	"atom":         {feedxml.Atom, ".atom"},
+	"json":         {encodeJSON, ".json"},
+	"json-compact": {encodeJSONCompact, ".json"},
+	"html":         {encodeHTML, ".html"},
+	"ics":          {encodeICS, ".ics"},
+	"jcard":        {encodeJCard, ".json"},
+	"jsonfeed":     {jsonfeed.Encode, ".json"},
+	"jsonld":       {encodeJSONLD, ".jsonld"},
+	"rss":          {feedxml.RSS, ".rss"},
+	"text":         {encodeText, ".txt"},
+	"vcard":        {encodeVCard, ".vcf"},
+}
+
+// formatNames returns the sorted names of all supported formats.
+func formatNames() []string {
+	return slices.Sorted(maps.Keys(formats))
+}
+
+// encodeJSON writes data to w as indented mf2 JSON.
+func encodeJSON(w io.Writer, data *microformats.Data) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(data)
+}
+
+// encodeJSONCompact writes data to w as mf2 JSON on a single line.
+func encodeJSONCompact(w io.Writer, data *microformats.Data) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(data)
+}
+
+// encodeJSONLD writes data to w as indented schema.org JSON-LD.
+func encodeJSONLD(w io.Writer, data *microformats.Data) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jsonld.Convert(data))
+}
+
+// encodeAS2 writes data to w as an indented ActivityStreams 2.0 collection.
+func encodeAS2(w io.Writer, data *microformats.Data) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(as2.Convert(data))
+}
+
+// encodeHTML writes the items in data to w as microformats HTML.
+func encodeHTML(w io.Writer, data *microformats.Data) error {
+	return render.Encode(w, data.Items...)
+}
+
+// encodeVCard writes the h-cards in data to w in vCard format.
+func encodeVCard(w io.Writer, data *microformats.Data) error {
+	return vcard.Encode(w, findType(data.Items, "h-card")...)
+}
+
+// encodeJCard writes the h-cards in data to w as a JSON array of jCards.
+func encodeJCard(w io.Writer, data *microformats.Data) error {
+	cards := []any{}
+	for _, card := range findType(data.Items, "h-card") {
+		jc, err := vcard.JCard(card)
+		if err != nil {
+			return err
+		}
+		cards = append(cards, jc)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cards)
+}
+
+// encodeICS writes the h-events in data to w as an iCalendar calendar.
+func encodeICS(w io.Writer, data *microformats.Data) error {
+	return ical.Encode(w, findType(data.Items, "h-event")...)
+}
+
+// findType returns the items of the specified type in items and their
+// children, in document order.
+func findType(items []*microformats.Microformat, typ string) []*microformats.Microformat {
+	var found []*microformats.Microformat
+	for _, item := range items {
+		if slices.Contains(item.Type, typ) {
+			found = append(found, item)
+		}
+		found = append(found, findType(item.Children, typ)...)
+	}
+	return found
+}
+
+// encodeText writes data to w as a human-readable tree.
+func encodeText(w io.Writer, data *microformats.Data) error {
+	tw := &textWriter{w: w}
+	for _, item := range data.Items {
+		tw.item(item, 0)
+	}
+	if len(data.Rels) > 0 {
+		tw.line(0, "rels:")
+		for _, rel := range slices.Sorted(maps.Keys(data.Rels)) {
+			for _, u := range data.Rels[rel] {
+				tw.line(1, "%s: %s", rel, u)
+			}
+		}
+	}
+	return tw.err
+}
+
+// textWriter writes indented lines, recording the first error encountered.
+type textWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (tw *textWriter) line(depth int, format string, args ...any) {
+	if tw.err != nil {
+		return
+	}
+	_, tw.err = fmt.Fprintf(tw.w, "%s%s\n", strings.Repeat("  ", depth), fmt.Sprintf(format, args...))
+}
+
+// item writes item and its properties at the specified depth.
+func (tw *textWriter) item(item *microformats.Microformat, depth int) {
+	header := strings.Join(item.Type, " ")
+	if item.ID != "" {
+		header += " #" + item.ID
+	}
+	tw.line(depth, "%s", header)
+
+	for _, name := range slices.Sorted(maps.Keys(item.Properties)) {
+		for _, value := range item.Properties[name] {
+			switch v := value.(type) {
+			case *microformats.Microformat:
+				tw.line(depth+1, "%s: %s", name, singleLine(v.Value))
+				tw.item(v, depth+2)
+			case map[string]string:
+				tw.line(depth+1, "%s: %s", name, singleLine(v["value"]))
+				for _, k := range slices.Sorted(maps.Keys(v)) {
+					if k != "value" {
+						tw.line(depth+2, "%s: %s", k, singleLine(v[k]))
+					}
+				}
+			default:
+				tw.line(depth+1, "%s: %s", name, singleLine(fmt.Sprint(v)))
+			}
+		}
+	}
+
+	if len(item.Children) > 0 {
+		tw.line(depth+1, "children:")
+		for _, child := range item.Children {
+			tw.item(child, depth+2)
+		}
+	}
+}
+
+// singleLine collapses whitespace in s, truncating long values.
+func singleLine(s string) string {
+	const maxLen = 100
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > maxLen {
+		s = string(r[:maxLen]) + "…"
+	}
+	return s
+}
@@ -2,58 +2,473 @@
 // SPDX-License-Identifier: MIT
 
 // The gomf tool is a command line tool which parses microformats from the
-// specified URL.  If selector is provided, the first element that matches the
-// selector will be used as the root node for parsing.
+// specified URLs or files.  If a selector is provided, the first element that
+// matches the selector will be used as the root node for parsing.  The -select
+// flag may be repeated, and the -all flag parses every matching element rather
+// than only the first, merging the results.  Elements matching an -exclude
+// selector are removed from the document before parsing.
 //
-// Usage: gomf <URL> [optional selector]
+// Usage: gomf [flags] <URL | file | -> ...
+//
+// Inputs may be http or https URLs, paths to local files, or "-" to read from
+// standard input.  File paths may include glob patterns, which is useful for
+// converting a directory of saved pages.  Relative URLs in fetched pages are
+// resolved against the final page URL after any redirects (or the
+// Content-Location of the response, if present); use the -base flag to
+// specify the base URL for files and standard input.  Flags such as
+// -user-agent, -header, -cookies, and -timeout control how URLs are fetched.
+// Atom, RSS, and JSON Feed documents, identified by their Content-Type or a
+// file extension of .atom, .rss, or .feed.json, are converted to an h-feed
+// rather than parsed as HTML.  Documents served as generic XML or JSON are
+// only converted if they look like a feed.
 //
 // For example, to parse all microformats from https://microformats.io inside
 // the <main> element, call:
 //
+//	gomf -select "main" "https://microformats.io"
+//
+// To parse every h-entry in the page, ignoring any in the comments section:
+//
+//	gomf -all -select ".h-entry" -exclude "#comments" "https://example.com/"
+//
+// The -format flag selects the output format.  The default is indented mf2
+// JSON; run "gomf -help" to list the available formats.
+//
+// To convert a directory of saved pages to JSON files, call:
+//
+//	gomf -base "https://example.com/" -outdir json "pages/*.html"
+//
+// Each output file is named after its input, so inputs with the same name in
+// different directories cannot be written to the same output directory.
+//
+// For compatibility with earlier versions, the selector may also be provided
+// as a second argument after a single URL:
+//
 //	gomf "https://microformats.io" "main"
 package main
 
 import (
-	"encoding/json"
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log"
-	"net/http"
+	"mime"
 	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
 	"willnorris.com/go/microformats"
+	"willnorris.com/go/microformats/feedxml"
+	"willnorris.com/go/microformats/fetch"
+	"willnorris.com/go/microformats/internal/flagutil"
+	"willnorris.com/go/microformats/jsonfeed"
+	"willnorris.com/go/microformats/sanitize"
 )
 
+var (
+	baseFlag     = flag.String("base", "", "base URL used to resolve relative URLs (default is the URL of fetched pages)")
+	allFlag      = flag.Bool("all", false, "parse all elements matching the selectors, rather than only the first")
+	outdirFlag   = flag.String("outdir", "", "write output for each input to a separate file in this directory")
+	formatFlag   = flag.String("format", "json", "output format: "+strings.Join(formatNames(), ", "))
+	sanitizeFlag = flag.Bool("sanitize", false, "sanitize the html of e-* properties")
+	schemesFlag  = flag.String("url-schemes", "", "comma separated list of allowed URL schemes; URLs with other schemes are dropped")
+
+	selectFlag  stringsFlag
+	excludeFlag stringsFlag
+)
+
+func init() {
+	flag.Var(&selectFlag, "select", "CSS selector of the element to parse (default is the entire document); may be repeated")
+	flag.Var(&excludeFlag, "exclude", "CSS selector of elements to remove before parsing; may be repeated")
+}
+
+// stringsFlag is a flag.Value that collects the values of a repeated flag.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *stringsFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <URL | file | -> ...\n\n", filepath.Base(os.Args[0]))
+	fmt.Fprintf(flag.CommandLine.Output(), "Parse microformats from URLs, files (including glob patterns), or standard input (-).\n\nFlags:\n")
+	flag.PrintDefaults()
+}
+
 func main() {
-	u, _ := url.Parse(strings.TrimSpace(os.Args[1]))
-	resp, err := http.Get(u.String())
+	log.SetFlags(0)
+	log.SetPrefix("gomf: ")
+	flag.Usage = usage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// support legacy usage of "gomf <URL> <selector>"
+	if len(args) == 2 && len(selectFlag) == 0 && isURL(args[0]) && !isInput(args[1]) {
+		selectFlag = append(selectFlag, args[1])
+		args = args[:1]
+	}
+
+	if *allFlag && len(selectFlag) == 0 {
+		log.Fatal("-all requires at least one -select selector")
+	}
+
+	outFormat, ok := formats[*formatFlag]
+	if !ok {
+		log.Fatalf("unknown format %q (supported formats: %s)", *formatFlag, strings.Join(formatNames(), ", "))
+	}
+
+	var base *url.URL
+	if *baseFlag != "" {
+		var err error
+		if base, err = url.Parse(*baseFlag); err != nil {
+			log.Fatalf("invalid base URL: %v", err)
+		}
+	}
+
+	inputs, err := expandInputs(args)
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	if *outdirFlag != "" {
+		if err := checkOutputNames(inputs, outFormat.ext); err != nil {
+			log.Fatal(err)
+		}
+		if err := os.MkdirAll(*outdirFlag, 0o755); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	fetcher, err := newFetcher()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var failed bool
+	for _, input := range inputs {
+		if err := process(fetcher, input, base, outFormat); err != nil {
+			log.Printf("%s: %v", input, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// isURL returns whether s is an http or https URL.
+func isURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+// isInput returns whether s looks like an input, rather than a selector.
+func isInput(s string) bool {
+	if s == "-" || isURL(s) {
+		return true
+	}
+	if _, err := os.Stat(s); err == nil {
+		return true
+	}
+	matches, _ := filepath.Glob(s)
+	return len(matches) > 0
+}
+
+// expandInputs expands any glob patterns in args that match local files.
+func expandInputs(args []string) ([]string, error) {
+	var inputs []string
+	for _, arg := range args {
+		if arg == "-" || isURL(arg) || !strings.ContainsAny(arg, `*?[`) {
+			inputs = append(inputs, arg)
+			continue
+		}
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", arg, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %q", arg)
+		}
+		inputs = append(inputs, matches...)
+	}
+	return inputs, nil
+}
+
+// process parses the microformats in input and writes them to the output in
+// the specified format.  If base is non-nil, it is used to resolve relative
+// URLs.  URLs are retrieved using fetcher.
+func process(fetcher fetch.Fetcher, input string, base *url.URL, f format) error {
+	r, inputURL, contentType, err := open(fetcher, input)
+	if err != nil {
+		return err
+	}
 	defer func() {
-		_ = resp.Body.Close()
+		_ = r.Close()
 	}()
+	if base == nil {
+		base = inputURL
+	}
 
+	br := bufio.NewReaderSize(r, sniffLen)
 	var data *microformats.Data
-	if len(os.Args) > 2 {
-		doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if decode := feedDecoder(contentType, br); decode != nil {
+		data, err = decode(br, base)
+	} else {
+		data, err = parse(br, contentType, base)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *outdirFlag == "" {
+		return f.encode(os.Stdout, data)
+	}
+	out, err := os.Create(filepath.Join(*outdirFlag, outputName(input, f.ext)))
+	if err != nil {
+		return err
+	}
+	if err := f.encode(out, data); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// open returns a reader for the contents of input.  If input is a URL, it is
+// retrieved using fetcher, and the base URL and Content-Type of the response
+// are also returned.
+func open(fetcher fetch.Fetcher, input string) (io.ReadCloser, *url.URL, string, error) {
+	switch {
+	case input == "-":
+		return io.NopCloser(os.Stdin), nil, "", nil
+	case isURL(input):
+		u, err := url.Parse(strings.TrimSpace(input))
 		if err != nil {
-			log.Fatal(err)
+			return nil, nil, "", err
 		}
-		s := doc.Find(os.Args[2])
-		if s.Length() == 0 {
-			log.Fatal("selector did not match any elements")
+		return fetchURL(fetcher, u)
+	default:
+		f, err := os.Open(input)
+		return f, nil, feedType(input), err
+	}
+}
+
+// feedType returns the content type of the feed document in the named file,
+// or an empty string if it is not a feed.  Other .json and .xml files are not
+// assumed to be feeds.
+func feedType(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".atom"):
+		return "application/atom+xml"
+	case strings.HasSuffix(name, ".rss"):
+		return "application/rss+xml"
+	case strings.HasSuffix(name, ".feed.json"):
+		return "application/feed+json"
+	}
+	return ""
+}
+
+// sniffLen is the number of bytes of a generic XML or JSON document examined
+// to determine whether it is a feed.
+const sniffLen = 4096
+
+// feedDecoder returns the function to decode feed documents with the
+// specified content type, or nil if it is not a feed.  Generic XML and JSON
+// documents are only treated as feeds if the start of r looks like one, so
+// that other documents are parsed as HTML.
+func feedDecoder(contentType string, r *bufio.Reader) func(io.Reader, *url.URL) (*microformats.Data, error) {
+	mediatype, _, _ := mime.ParseMediaType(contentType)
+	switch mediatype {
+	case "application/atom+xml", "application/rss+xml":
+		return feedxml.Decode
+	case "application/feed+json":
+		return jsonfeed.Decode
+	case "application/xml", "text/xml":
+		if b, _ := r.Peek(sniffLen); isXMLFeed(b) {
+			return feedxml.Decode
+		}
+	case "application/json":
+		if b, _ := r.Peek(sniffLen); isJSONFeed(b) {
+			return jsonfeed.Decode
 		}
-		data = microformats.ParseNode(s.Get(0), u)
-	} else {
-		data = microformats.Parse(resp.Body, u)
 	}
+	return nil
+}
 
-	enc := json.NewEncoder(os.Stdout)
-	enc.SetEscapeHTML(false)
-	enc.SetIndent("", "  ")
-	if err := enc.Encode(data); err != nil {
-		log.Fatal(err)
+// isXMLFeed returns whether the root element of the XML document starting
+// with b is an Atom feed or RSS channel.
+func isXMLFeed(b []byte) bool {
+	d := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return false
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			return se.Name.Local == "feed" || se.Name.Local == "rss"
+		}
+	}
+}
+
+// isJSONFeed returns whether the JSON document starting with b appears to
+// be a JSON Feed, whose required version is a jsonfeed.org URL.
+func isJSONFeed(b []byte) bool {
+	return bytes.Contains(b, []byte("jsonfeed.org"))
+}
+
+// parse the microformats from r, limited to the elements matched by the
+// select flags, if specified, and ignoring elements matched by the exclude
+// flags.  contentType is used to determine the character encoding of r.
+func parse(r io.Reader, contentType string, base *url.URL) (*microformats.Data, error) {
+	var opts microformats.ParseOptions
+	if *sanitizeFlag {
+		opts.Sanitizer = sanitize.DefaultPolicy()
+	}
+	if *schemesFlag != "" {
+		opts.URLPolicy = &microformats.URLPolicy{Schemes: flagutil.Schemes(*schemesFlag)}
+	}
+
+	if len(selectFlag) == 0 && len(excludeFlag) == 0 {
+		return opts.ParseWithContentType(r, contentType, base), nil
+	}
+
+	r, err := charset.NewReader(r, contentType)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// detach excluded elements from the DOM so they are not parsed
+	for _, sel := range excludeFlag {
+		doc.Find(sel).Remove()
+	}
+
+	if len(selectFlag) == 0 {
+		return opts.ParseNode(doc.Get(0), base), nil
+	}
+
+	var nodes []*html.Node
+	for _, sel := range selectFlag {
+		s := doc.Find(sel)
+		if !*allFlag {
+			s = s.First()
+		}
+		nodes = append(nodes, s.Nodes...)
+	}
+	nodes = outermostNodes(nodes)
+	if len(nodes) == 0 {
+		return nil, errors.New("selector did not match any elements")
+	}
+
+	data := opts.ParseNode(nodes[0], base)
+	for _, n := range nodes[1:] {
+		mergeData(data, opts.ParseNode(n, base))
+	}
+	return data, nil
+}
+
+// outermostNodes returns nodes with duplicates and any nodes nested inside
+// of other nodes removed, so that no element is parsed more than once.
+func outermostNodes(nodes []*html.Node) []*html.Node {
+	var result []*html.Node
+	for i, n := range nodes {
+		var nested bool
+		for j, other := range nodes {
+			if i == j {
+				continue
+			}
+			if (n == other && j < i) || (n != other && isAncestor(other, n)) {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+// isAncestor returns whether parent is an ancestor of node.
+func isAncestor(parent, node *html.Node) bool {
+	for n := node.Parent; n != nil; n = n.Parent {
+		if n == parent {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeData merges the items, rels, and diagnostics from src into dst.
+func mergeData(dst, src *microformats.Data) {
+	dst.Items = append(dst.Items, src.Items...)
+	dst.Diagnostics = append(dst.Diagnostics, src.Diagnostics...)
+	for rel, urls := range src.Rels {
+		for _, u := range urls {
+			if !slices.Contains(dst.Rels[rel], u) {
+				dst.Rels[rel] = append(dst.Rels[rel], u)
+			}
+		}
+	}
+	for u, relURL := range src.RelURLs {
+		if _, ok := dst.RelURLs[u]; !ok {
+			dst.RelURLs[u] = relURL
+		}
+	}
+}
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// checkOutputNames returns an error if any of inputs would be written to the
+// same output file, using the file extension ext.
+func checkOutputNames(inputs []string, ext string) error {
+	seen := make(map[string]string)
+	for _, input := range inputs {
+		name := outputName(input, ext)
+		if other, ok := seen[name]; ok && other != input {
+			return fmt.Errorf("%s and %s would both be written to %s", other, input, name)
+		}
+		seen[name] = input
+	}
+	return nil
+}
+
+// outputName returns the name of the file to write the output for input to,
+// using the file extension ext.
+func outputName(input, ext string) string {
+	var name string
+	switch {
+	case input == "-":
+		name = "stdin"
+	case isURL(input):
+		u, _ := url.Parse(input)
+		name = strings.Trim(unsafeFilenameChars.ReplaceAllString(u.Host+u.Path, "_"), "_")
+	default:
+		name = strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))
 	}
+	return name + ext
 }
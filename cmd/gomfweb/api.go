@@ -0,0 +1,302 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"willnorris.com/go/microformats"
+	"willnorris.com/go/microformats/fetch"
+)
+
+// apiError is an error returned to API clients.
+type apiError struct {
+	Status  int    `json:"status"`
+	Code    string `json:"error"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+// newAPIError returns an apiError with the specified status and message.
+// The error code is derived from the status.
+func newAPIError(status int, format string, args ...any) *apiError {
+	code := strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+	return &apiError{Status: status, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// fetchError converts an error returned by fetcher to an apiError.
+func fetchError(err error) *apiError {
+	switch {
+	case errors.Is(err, fetch.ErrDisallowedScheme), errors.Is(err, fetch.ErrDisallowedAddress):
+		return newAPIError(http.StatusBadRequest, "url not allowed: %v", err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return newAPIError(http.StatusGatewayTimeout, "timeout fetching url: %v", err)
+	}
+	return newAPIError(http.StatusBadGateway, "error fetching url: %v", err)
+}
+
+// parseResult is the result of parsing a page or snippet of HTML.
+type parseResult struct {
+	Data *microformats.Data
+
+	// URL is the URL of the fetched page, or the base URL of the provided
+	// HTML.  It may be nil if no base URL was provided.
+	URL *url.URL
+
+	// BaseURL is the URL that relative URLs in the page are resolved
+	// against, which may differ from URL for fetched pages with a
+	// Content-Location header.
+	BaseURL *url.URL
+
+	// Header is the response header of the fetched page.  It is nil for
+	// provided HTML.
+	Header http.Header
+
+	// Body is the HTML document that was parsed, and ContentType is its
+	// media type, if known.
+	Body        []byte
+	ContentType string
+}
+
+// parseRequest parses the microformats from the page or HTML specified in r.
+//
+// For GET requests, the "url" parameter specifies a page to fetch.  POST
+// requests may either be a form with "html" and optional "base-url" fields
+// (or a "url" field to fetch), or have the HTML document as the request body,
+// with an optional "base-url" query parameter.
+func parseRequest(r *http.Request) (*parseResult, error) {
+	start := time.Now()
+	defer func() { stats.observeParse(time.Since(start)) }()
+
+	if r.Method == http.MethodPost {
+		mediatype, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediatype != "application/x-www-form-urlencoded" && mediatype != "multipart/form-data" {
+			base, err := baseURL(r.URL.Query().Get("base-url"))
+			if err != nil {
+				return nil, err
+			}
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				return nil, bodyError("error reading request body", err)
+			}
+			contentType := r.Header.Get("Content-Type")
+			data := parseOptions.ParseWithContentType(bytes.NewReader(body), contentType, base)
+			return &parseResult{Data: data, URL: base, BaseURL: base, Body: body, ContentType: contentType}, nil
+		}
+
+		if err := parseForm(r); err != nil {
+			return nil, err
+		}
+		if html := r.PostFormValue("html"); html != "" {
+			base, err := baseURL(r.PostFormValue("base-url"))
+			if err != nil {
+				return nil, err
+			}
+			return &parseResult{
+				Data:        parseOptions.Parse(strings.NewReader(html), base),
+				URL:         base,
+				BaseURL:     base,
+				Body:        []byte(html),
+				ContentType: "text/html; charset=utf-8",
+			}, nil
+		}
+	}
+
+	u := strings.TrimSpace(r.FormValue("url"))
+	if u == "" {
+		return nil, newAPIError(http.StatusBadRequest, "missing url or html parameter")
+	}
+	parsedURL, err := url.Parse(u)
+	if err != nil || !parsedURL.IsAbs() {
+		return nil, newAPIError(http.StatusBadRequest, "invalid url: %q", u)
+	}
+	resp, err := fetcher.Fetch(r.Context(), parsedURL.String())
+	if err != nil {
+		stats.fetchErrors.Add(1)
+		return nil, fetchError(err)
+	}
+	return &parseResult{
+		Data:        resp.Data,
+		URL:         resp.URL,
+		BaseURL:     resp.BaseURL(),
+		Header:      resp.Header,
+		Body:        resp.Body,
+		ContentType: resp.Header.Get("Content-Type"),
+	}, nil
+}
+
+// limitBody limits the size of request bodies read by h to the maximum
+// body size.  It must wrap any handler which reads the body or its form
+// values.
+func limitBody(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, *maxBodySize)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// parseForm parses the form values of r, returning an apiError if the form
+// is invalid or too large.
+func parseForm(r *http.Request) *apiError {
+	// ParseMultipartForm ignores errors from ParseForm for forms that are
+	// not multipart, so call it first
+	err := r.ParseForm()
+	if err == nil {
+		if err = r.ParseMultipartForm(*maxBodySize); errors.Is(err, http.ErrNotMultipart) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return bodyError("error parsing form", err)
+	}
+	return nil
+}
+
+// bodyError returns an apiError for err, an error reading the request body.
+// Bodies exceeding the maximum size are reported with a 413 status.
+func bodyError(msg string, err error) *apiError {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return newAPIError(http.StatusRequestEntityTooLarge, "%s: request body larger than %d bytes", msg, maxErr.Limit)
+	}
+	return newAPIError(http.StatusBadRequest, "%s: %v", msg, err)
+}
+
+// baseURL parses the base URL s, which may be empty.
+func baseURL(s string) (*url.URL, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, newAPIError(http.StatusBadRequest, "invalid base url: %q", s)
+	}
+	return u, nil
+}
+
+// apiParse handles requests to parse a page or HTML snippet, returning mf2
+// JSON.  See parseRequest for the supported parameters.
+func apiParse(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if !checkCallback(w, r) {
+		return
+	}
+	result, err := parseRequest(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, "application/mf2+json", result.Data)
+}
+
+// apiDerived handles requests to report data derived from the parsed
+// microformats of a page or HTML snippet.  It accepts the same parameters
+// as apiParse.
+func apiDerived(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+	if !checkCallback(w, r) {
+		return
+	}
+	result, err := parseRequest(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, "application/json", derive(result))
+}
+
+// apiOptions handles CORS preflight requests for API endpoints.
+func apiOptions(w http.ResponseWriter, _ *http.Request) {
+	setCORSHeaders(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func setCORSHeaders(w http.ResponseWriter) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Max-Age", "86400")
+}
+
+// writeError writes err to w as a JSON error response.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		apiErr = newAPIError(http.StatusInternalServerError, "%v", err)
+	}
+	writeJSON(w, r, apiErr.Status, "application/json", apiErr)
+}
+
+// validCallback matches JSONP callback names, which are limited to
+// JavaScript identifiers optionally separated by dots.
+var validCallback = regexp.MustCompile(`^[A-Za-z_$][0-9A-Za-z_$]*(\.[A-Za-z_$][0-9A-Za-z_$]*)*$`)
+
+// jsonpCallback returns the JSONP callback name requested by r, if any, and
+// whether it is valid.
+func jsonpCallback(r *http.Request) (string, bool) {
+	callback := r.URL.Query().Get("callback")
+	if callback != "" && (len(callback) > 128 || !validCallback.MatchString(callback)) {
+		return "", false
+	}
+	return callback, true
+}
+
+// checkCallback returns whether the JSONP callback requested by r is valid,
+// writing an error response to w if it is not.  It is called before any
+// work is done for the request.
+func checkCallback(w http.ResponseWriter, r *http.Request) bool {
+	if _, ok := jsonpCallback(r); !ok {
+		writeJSON(w, r, http.StatusBadRequest, "application/json", newAPIError(http.StatusBadRequest, "invalid callback name"))
+		return false
+	}
+	return true
+}
+
+// writeJSON writes v to w as JSON with the specified status and content type.
+// If r includes a valid "callback" parameter, the response is wrapped in a
+// JSONP callback.  Invalid callbacks are ignored, and should be rejected by
+// checkCallback before writing the response.
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, contentType string, v any) {
+	callback, _ := jsonpCallback(r)
+
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		http.Error(w, fmt.Sprintf("error marshaling json: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if callback != "" {
+		// JSONP responses always use a 200 status so the callback runs
+		w.Header().Set("Content-Type", "application/javascript")
+		_, _ = fmt.Fprintf(w, "/**/%s(%s);", callback, bytes.TrimSpace(buf.Bytes()))
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	if _, err := io.Copy(w, buf); err != nil {
+		log.Print(err)
+	}
+}
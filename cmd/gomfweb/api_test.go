@@ -0,0 +1,199 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"willnorris.com/go/microformats/fetch"
+)
+
+// stubFetcher is a fetch.Fetcher that returns a fixed page, recording the
+// URLs it is asked to fetch.
+type stubFetcher struct {
+	body    string
+	header  http.Header // additional response headers
+	fetched []string
+}
+
+func (f *stubFetcher) Fetch(_ context.Context, rawURL string) (*fetch.Response, error) {
+	f.fetched = append(f.fetched, rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{"Content-Type": {"text/html"}}
+	for k, v := range f.header {
+		header[k] = v
+	}
+	return &fetch.Response{
+		URL:        u,
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       []byte(f.body),
+		Data:       parseOptions.Parse(strings.NewReader(f.body), u),
+	}, nil
+}
+
+// useFetcher sets the package fetcher to f for the duration of the test.
+func useFetcher(t *testing.T, f fetch.Fetcher) {
+	t.Helper()
+	orig := fetcher
+	fetcher = f
+	t.Cleanup(func() { fetcher = orig })
+}
+
+// useMaxBodySize sets the max body size flag to n for the duration of the
+// test.
+func useMaxBodySize(t *testing.T, n int64) {
+	t.Helper()
+	orig := *maxBodySize
+	*maxBodySize = n
+	t.Cleanup(func() { *maxBodySize = orig })
+}
+
+func TestAPIParse(t *testing.T) {
+	f := &stubFetcher{body: `<p class="h-card">Alice</p>`}
+	useFetcher(t, f)
+
+	tests := []struct {
+		name       string
+		req        *http.Request
+		wantStatus int
+		wantType   string
+		wantBody   string
+	}{
+		{
+			name:       "url",
+			req:        httptest.NewRequest("GET", "/api/parse?url=https://example.com/", nil),
+			wantStatus: http.StatusOK,
+			wantType:   "application/mf2+json",
+			wantBody:   `"Alice"`,
+		},
+		{
+			name:       "html body",
+			req:        httptest.NewRequest("POST", "/api/parse", strings.NewReader(`<p class="h-card">Bob</p>`)),
+			wantStatus: http.StatusOK,
+			wantType:   "application/mf2+json",
+			wantBody:   `"Bob"`,
+		},
+		{
+			name:       "missing url",
+			req:        httptest.NewRequest("GET", "/api/parse", nil),
+			wantStatus: http.StatusBadRequest,
+			wantType:   "application/json",
+			wantBody:   `"missing url or html parameter"`,
+		},
+		{
+			name:       "relative url",
+			req:        httptest.NewRequest("GET", "/api/parse?url=/foo", nil),
+			wantStatus: http.StatusBadRequest,
+			wantType:   "application/json",
+			wantBody:   `"error": "bad_request"`,
+		},
+		{
+			name:       "jsonp",
+			req:        httptest.NewRequest("GET", "/api/parse?url=https://example.com/&callback=cb.fn", nil),
+			wantStatus: http.StatusOK,
+			wantType:   "application/javascript",
+			wantBody:   `/**/cb.fn({`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			newMux().ServeHTTP(w, tt.req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status is %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantType {
+				t.Errorf("Content-Type is %q, want %q", got, tt.wantType)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body %q does not contain %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestAPIParse_InvalidCallback(t *testing.T) {
+	for _, callback := range []string{"alert(1)", "a..b", "1a", "x;y", strings.Repeat("a", 129)} {
+		f := &stubFetcher{body: `<p class="h-card">Alice</p>`}
+		useFetcher(t, f)
+
+		q := url.Values{"url": {"https://example.com/"}, "callback": {callback}}
+		w := httptest.NewRecorder()
+		newMux().ServeHTTP(w, httptest.NewRequest("GET", "/api/parse?"+q.Encode(), nil))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("callback %q returned status %d, want %d", callback, w.Code, http.StatusBadRequest)
+		}
+		if got := w.Header().Get("Content-Type"); got != "application/json" {
+			t.Errorf("callback %q returned Content-Type %q, want application/json", callback, got)
+		}
+		if len(f.fetched) != 0 {
+			t.Errorf("callback %q fetched %v before rejecting the request", callback, f.fetched)
+		}
+	}
+}
+
+func TestAPIParse_BodyErrors(t *testing.T) {
+	useMaxBodySize(t, 64)
+	large := strings.Repeat("x", 100)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		{"html too large", "text/html", large, http.StatusRequestEntityTooLarge},
+		{"form too large", "application/x-www-form-urlencoded", "html=" + large, http.StatusRequestEntityTooLarge},
+		{"invalid form", "application/x-www-form-urlencoded", "html=%zz", http.StatusBadRequest},
+		{"invalid multipart", "multipart/form-data; boundary=x", "not multipart", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/parse", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			newMux().ServeHTTP(w, req)
+
+			var got apiError
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("error decoding response %q: %v", w.Body.String(), err)
+			}
+			if w.Code != tt.wantStatus || got.Status != tt.wantStatus {
+				t.Errorf("status is %d (%d in body), want %d: %s", w.Code, got.Status, tt.wantStatus, got.Message)
+			}
+		})
+	}
+}
+
+func TestIndex_BodyLimit(t *testing.T) {
+	useMaxBodySize(t, 64)
+	f := &stubFetcher{}
+	useFetcher(t, f)
+
+	body := url.Values{"html": {strings.Repeat("x", 100)}}.Encode()
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	newMux().ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status is %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+	if !strings.Contains(w.Body.String(), "request body larger than 64 bytes") {
+		t.Errorf("body does not report the size limit: %s", w.Body.String())
+	}
+}
@@ -0,0 +1,320 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"willnorris.com/go/microformats"
+	"willnorris.com/go/microformats/fetch"
+)
+
+// cachingFetcher is a fetch.Fetcher that reuses successful responses for a
+// fixed TTL, avoiding repeated fetching and parsing of the same URL.  The
+// cache is limited to a maximum number of entries and total size, discarding
+// the least recently used responses when full.
+//
+// cachingFetcher is also a fetch.Cache, which stores the HTTP responses of a
+// fetch.Client in the same entries and within the same limits.  Once a
+// parsed response expires, the client uses the stored HTTP response to
+// revalidate the page with a conditional request.
+//
+// Responses are shared by all users of the server, so those marked with
+// Cache-Control no-store or private are not cached.  Each caller receives
+// its own copy of a cached response, which it is free to modify.
+type cachingFetcher struct {
+	fetcher    fetch.Fetcher
+	ttl        time.Duration
+	maxEntries int
+	maxBytes   int64
+
+	// now returns the current time; it can be overridden in tests.
+	now func() time.Time
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+	size  int64
+}
+
+// cachedResponse is an element in cachingFetcher's list.
+type cachedResponse struct {
+	url string
+
+	// resp is the parsed response, which is reused until expires.  It is
+	// nil once expired.
+	resp     *fetch.Response
+	respSize int64
+	expires  time.Time
+
+	// entry is the value stored by a fetch.Client, used to revalidate
+	// the page.  It is nil if the fetcher is not a fetch.Client.
+	entry []byte
+}
+
+// size returns the total size of e.
+func (e *cachedResponse) size() int64 {
+	return e.respSize + int64(len(e.entry))
+}
+
+// newCachingFetcher returns a cachingFetcher that caches responses from f for
+// ttl, holding up to maxEntries responses with a total size of up to
+// maxBytes.  A limit of zero or less means no limit.  If f is a fetch.Client
+// without a Cache, the returned cachingFetcher is used as its Cache.
+func newCachingFetcher(f fetch.Fetcher, ttl time.Duration, maxEntries int, maxBytes int64) *cachingFetcher {
+	c := &cachingFetcher{
+		fetcher:    f,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		now:        time.Now,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+	if client, ok := f.(*fetch.Client); ok && client.Cache == nil {
+		client.Cache = c
+	}
+	return c
+}
+
+// Fetch implements fetch.Fetcher.
+func (c *cachingFetcher) Fetch(ctx context.Context, url string) (*fetch.Response, error) {
+	if resp := c.get(url); resp != nil {
+		stats.cacheHits.Add(1)
+		return copyResponse(resp), nil
+	}
+	stats.cacheMisses.Add(1)
+
+	resp, err := c.fetcher.Fetch(ctx, url)
+	if err != nil {
+		return resp, err
+	}
+	if shareable(resp.Header) {
+		// cache a private copy, so that changes made by the caller to
+		// resp do not affect later responses
+		c.set(url, copyResponse(resp))
+	} else {
+		c.Delete(url)
+	}
+	return resp, nil
+}
+
+// shareable returns whether a response with header h may be stored in a
+// cache shared by all users.
+func shareable(h http.Header) bool {
+	for _, v := range h.Values("Cache-Control") {
+		for _, directive := range strings.Split(v, ",") {
+			name, _, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name = strings.ToLower(name); name == "no-store" || name == "private" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// responseSize estimates the memory used by resp, as twice the size of its
+// body to allow for the parsed microformats.  Cached responses do not keep
+// the parsed document alive (see copyItem), so it is not included.
+func responseSize(resp *fetch.Response) int64 {
+	return 2 * int64(len(resp.Body))
+}
+
+// get returns the unexpired response cached for url, or nil.
+func (c *cachingFetcher) get(url string) *fetch.Response {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[url]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*cachedResponse)
+	if e.resp == nil {
+		return nil
+	}
+	if c.now().After(e.expires) {
+		// keep the entry, if any, for revalidation
+		c.size -= e.respSize
+		e.resp, e.respSize = nil, 0
+		if e.entry == nil {
+			c.remove(el)
+		}
+		return nil
+	}
+	c.ll.MoveToFront(el)
+	return e.resp
+}
+
+// set caches resp for url, discarding the least recently used responses if
+// the cache is full.  Responses larger than the cache are not stored.
+func (c *cachingFetcher) set(url string, resp *fetch.Response) {
+	c.update(url, func(e *cachedResponse) {
+		e.resp = resp
+		e.respSize = responseSize(resp)
+		e.expires = c.now().Add(c.ttl)
+	})
+}
+
+// update applies fn to the cache entry for url, creating it if needed, and
+// then discards the least recently used entries if the cache is full.
+// Entries larger than the cache are removed.
+func (c *cachingFetcher) update(url string, fn func(e *cachedResponse)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[url]
+	if !ok {
+		el = c.ll.PushFront(&cachedResponse{url: url})
+		c.items[url] = el
+	}
+	e := el.Value.(*cachedResponse)
+	c.size -= e.size()
+	fn(e)
+	c.size += e.size()
+	c.ll.MoveToFront(el)
+
+	if c.maxBytes > 0 && e.size() > c.maxBytes {
+		c.remove(el)
+	}
+	for (c.maxEntries > 0 && c.ll.Len() > c.maxEntries) || (c.maxBytes > 0 && c.size > c.maxBytes) {
+		c.remove(c.ll.Back())
+	}
+}
+
+// remove removes el from the cache.  c.mu must be held.
+func (c *cachingFetcher) remove(el *list.Element) {
+	e := el.Value.(*cachedResponse)
+	c.ll.Remove(el)
+	delete(c.items, e.url)
+	c.size -= e.size()
+}
+
+// Get implements fetch.Cache.
+func (c *cachingFetcher) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		if e := el.Value.(*cachedResponse); e.entry != nil {
+			return e.entry, true
+		}
+	}
+	return nil, false
+}
+
+// Set implements fetch.Cache.
+func (c *cachingFetcher) Set(key string, value []byte) {
+	c.update(key, func(e *cachedResponse) {
+		e.entry = value
+	})
+}
+
+// Delete implements fetch.Cache.
+func (c *cachingFetcher) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+}
+
+// len returns the number of cached responses and their total size,
+// including any which have expired but not yet been removed.
+func (c *cachingFetcher) len() (int, int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len(), c.size
+}
+
+// copyResponse returns a deep copy of resp.
+func copyResponse(resp *fetch.Response) *fetch.Response {
+	r := *resp
+	if resp.URL != nil {
+		u := *resp.URL
+		r.URL = &u
+	}
+	r.Header = resp.Header.Clone()
+	r.Body = bytes.Clone(resp.Body)
+	r.Data = copyData(resp.Data)
+	return &r
+}
+
+// copyData returns a deep copy of data.  As with copyItem, the copy does not
+// refer to the parsed document.
+func copyData(data *microformats.Data) *microformats.Data {
+	if data == nil {
+		return nil
+	}
+	d := *data
+	d.Items = copyItems(data.Items)
+	d.Rels = make(map[string][]string, len(data.Rels))
+	for k, v := range data.Rels {
+		d.Rels[k] = slices.Clone(v)
+	}
+	d.RelURLs = make(map[string]*microformats.RelURL, len(data.RelURLs))
+	for k, v := range data.RelURLs {
+		r := *v
+		r.Rels = slices.Clone(v.Rels)
+		d.RelURLs[k] = &r
+	}
+	d.Diagnostics = slices.Clone(data.Diagnostics)
+	for i := range d.Diagnostics {
+		d.Diagnostics[i].Node = nil
+	}
+	return &d
+}
+
+// copyItems returns a deep copy of items.
+func copyItems(items []*microformats.Microformat) []*microformats.Microformat {
+	if items == nil {
+		return nil
+	}
+	c := make([]*microformats.Microformat, len(items))
+	for i, item := range items {
+		c[i] = copyItem(item)
+	}
+	return c
+}
+
+// copyItem returns a deep copy of item.  Only the exported fields of item
+// are copied, so that cached items do not keep the parsed document alive
+// through their Node.
+func copyItem(item *microformats.Microformat) *microformats.Microformat {
+	if item == nil {
+		return nil
+	}
+	c := microformats.Microformat{
+		ID:       item.ID,
+		Value:    item.Value,
+		HTML:     item.HTML,
+		Type:     slices.Clone(item.Type),
+		Shape:    item.Shape,
+		Coords:   item.Coords,
+		Children: copyItems(item.Children),
+	}
+	if item.Properties != nil {
+		c.Properties = make(map[string][]any, len(item.Properties))
+		for name, values := range item.Properties {
+			vc := make([]any, len(values))
+			for i, v := range values {
+				switch v := v.(type) {
+				case map[string]string:
+					vc[i] = maps.Clone(v)
+				case *microformats.Microformat:
+					vc[i] = copyItem(v)
+				default:
+					vc[i] = v
+				}
+			}
+			c.Properties[name] = vc
+		}
+	}
+	return &c
+}
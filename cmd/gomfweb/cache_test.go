@@ -0,0 +1,244 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+	"willnorris.com/go/microformats"
+	"willnorris.com/go/microformats/fetch"
+)
+
+func TestCachingFetcher(t *testing.T) {
+	ctx := context.Background()
+	f := &stubFetcher{body: `<p class="h-card">Alice</p>`}
+	now := time.Now()
+	c := newCachingFetcher(f, time.Minute, 0, 0)
+	c.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		resp, err := c.Fetch(ctx, "https://example.com/")
+		if err != nil {
+			t.Fatalf("Fetch returned error: %v", err)
+		}
+		if got := resp.Data.Items[0].Properties["name"][0]; got != "Alice" {
+			t.Errorf("Fetch returned name %q, want %q", got, "Alice")
+		}
+	}
+	if len(f.fetched) != 1 {
+		t.Errorf("underlying fetcher called %d times, want 1", len(f.fetched))
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := c.Fetch(ctx, "https://example.com/"); err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+	if len(f.fetched) != 2 {
+		t.Errorf("underlying fetcher called %d times after expiry, want 2", len(f.fetched))
+	}
+}
+
+func TestCachingFetcher_Revalidate(t *testing.T) {
+	var requests, conditional int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<p class="h-card">Alice</p>`))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	c := newCachingFetcher(&fetch.Client{}, time.Minute, 0, 0)
+	c.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Fetch(ctx, srv.URL); err != nil {
+			t.Fatalf("Fetch returned error: %v", err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("server received %d requests, want 1", requests)
+	}
+
+	// once expired, the page is revalidated rather than fetched again
+	now = now.Add(2 * time.Minute)
+	resp, err := c.Fetch(ctx, srv.URL)
+	if err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+	if requests != 2 || conditional != 1 {
+		t.Errorf("server received %d requests and %d conditional requests, want 2 and 1", requests, conditional)
+	}
+	if !resp.FromCache {
+		t.Errorf("revalidated response does not have FromCache set")
+	}
+	if got := resp.Data.Items[0].Properties["name"][0]; got != "Alice" {
+		t.Errorf("revalidated response has name %q, want %q", got, "Alice")
+	}
+}
+
+func TestCachingFetcher_NoDocument(t *testing.T) {
+	f := &stubFetcher{body: `<div class="h-entry"><p class="p-author h-card">Alice</p></div>`}
+	c := newCachingFetcher(f, time.Minute, 0, 0)
+	if _, err := c.Fetch(context.Background(), "https://example.com/"); err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+
+	// cached responses do not keep the parsed document alive
+	resp := c.get("https://example.com/")
+	item := resp.Data.Items[0]
+	if item.Node() != nil || item.Properties["author"][0].(*microformats.Microformat).Node() != nil {
+		t.Errorf("cached item refers to the parsed document")
+	}
+
+	data := copyData(&microformats.Data{Diagnostics: []microformats.Diagnostic{{Code: "test", Node: &html.Node{}}}})
+	if data.Diagnostics[0].Node != nil {
+		t.Errorf("copied diagnostic refers to the parsed document")
+	}
+}
+
+func TestCachingFetcher_Limits(t *testing.T) {
+	ctx := context.Background()
+	body := strings.Repeat("x", 100) // each response is 200 bytes in the cache
+
+	tests := []struct {
+		name       string
+		maxEntries int
+		maxBytes   int64
+		wantLen    int
+		wantCached []string
+	}{
+		{"max entries", 2, 0, 2, []string{"/b", "/c"}},
+		{"max bytes", 0, 500, 2, []string{"/b", "/c"}},
+		{"response too large", 0, 100, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &stubFetcher{body: body}
+			c := newCachingFetcher(f, time.Minute, tt.maxEntries, tt.maxBytes)
+			for _, path := range []string{"/a", "/b", "/c"} {
+				if _, err := c.Fetch(ctx, "https://example.com"+path); err != nil {
+					t.Fatalf("Fetch returned error: %v", err)
+				}
+			}
+			n, size := c.len()
+			if n != tt.wantLen || size != int64(200*tt.wantLen) {
+				t.Errorf("len() returned %d, %d, want %d, %d", n, size, tt.wantLen, 200*tt.wantLen)
+			}
+			for _, path := range tt.wantCached {
+				if c.get("https://example.com"+path) == nil {
+					t.Errorf("%s is not cached", path)
+				}
+			}
+		})
+	}
+}
+
+func TestCachingFetcher_NotShareable(t *testing.T) {
+	for _, cc := range []string{"no-store", "Private, max-age=60", `max-age=60, private="Set-Cookie"`} {
+		f := &stubFetcher{body: "x", header: http.Header{"Cache-Control": {cc}}}
+		c := newCachingFetcher(f, time.Minute, 0, 0)
+		for i := 0; i < 2; i++ {
+			if _, err := c.Fetch(context.Background(), "https://example.com/"); err != nil {
+				t.Fatalf("Fetch returned error: %v", err)
+			}
+		}
+		if len(f.fetched) != 2 {
+			t.Errorf("Cache-Control %q: underlying fetcher called %d times, want 2", cc, len(f.fetched))
+		}
+	}
+}
+
+func TestCachingFetcher_Copies(t *testing.T) {
+	ctx := context.Background()
+	f := &stubFetcher{body: `<div class="h-entry"><span class="p-author h-card">Alice</span><div class="e-content">Hi</div></div>`}
+	c := newCachingFetcher(f, time.Minute, 0, 0)
+
+	// modify every part of each response, including the first response
+	// which was stored in the cache
+	for i := 0; i < 2; i++ {
+		resp, err := c.Fetch(ctx, "https://example.com/")
+		if err != nil {
+			t.Fatalf("Fetch returned error: %v", err)
+		}
+		item := resp.Data.Items[0]
+		resp.Body[0] = 'X'
+		resp.Header.Set("Content-Type", "changed")
+		resp.URL.Path = "/changed"
+		item.Type[0] = "h-changed"
+		item.Properties["content"][0].(map[string]string)["value"] = "changed"
+		item.Properties["author"][0].(*microformats.Microformat).Properties["name"][0] = "changed"
+		resp.Data.Items = nil
+	}
+
+	resp, err := c.Fetch(ctx, "https://example.com/")
+	if err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+	if len(f.fetched) != 1 {
+		t.Fatalf("underlying fetcher called %d times, want 1", len(f.fetched))
+	}
+	if resp.Body[0] != '<' || resp.Header.Get("Content-Type") != "text/html" || resp.URL.Path != "/" {
+		t.Errorf("cached response was modified: %q, %v, %v", resp.Body, resp.Header, resp.URL)
+	}
+	if len(resp.Data.Items) != 1 {
+		t.Fatalf("cached response has %d items, want 1", len(resp.Data.Items))
+	}
+	item := resp.Data.Items[0]
+	if item.Type[0] != "h-entry" || item.Properties["content"][0].(map[string]string)["value"] != "Hi" {
+		t.Errorf("cached item was modified: %v", item)
+	}
+	if got := item.Properties["author"][0].(*microformats.Microformat).Properties["name"][0]; got != "Alice" {
+		t.Errorf("cached nested item was modified: name is %q", got)
+	}
+}
+
+// syncFetcher wraps a fetcher to make it safe for concurrent use.
+type syncFetcher struct {
+	mu sync.Mutex
+	f  fetch.Fetcher
+}
+
+func (f *syncFetcher) Fetch(ctx context.Context, url string) (*fetch.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.f.Fetch(ctx, url)
+}
+
+func TestCachingFetcher_Concurrent(t *testing.T) {
+	f := &syncFetcher{f: &stubFetcher{body: `<p class="h-card">Alice</p>`}}
+	c := newCachingFetcher(f, time.Minute, 2, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				url := "https://example.com/" + string(rune('a'+(i+j)%4))
+				resp, err := c.Fetch(context.Background(), url)
+				if err != nil {
+					t.Errorf("Fetch returned error: %v", err)
+					return
+				}
+				resp.Data.Items[0].Properties["name"][0] = "changed"
+			}
+		}(i)
+	}
+	wg.Wait()
+}
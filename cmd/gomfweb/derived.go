@@ -0,0 +1,186 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"willnorris.com/go/microformats"
+	"willnorris.com/go/microformats/ptd"
+	"willnorris.com/go/microformats/rhc"
+)
+
+// endpointRels are the rel values of IndieWeb endpoints reported by derive.
+var endpointRels = []string{
+	"authorization_endpoint",
+	"hub",
+	"indieauth-metadata",
+	"micropub",
+	"microsub",
+	"self",
+	"token_endpoint",
+	"webmention",
+}
+
+// derived is data derived from parsed microformats.
+type derived struct {
+	// Entries includes the post type of each h-entry.
+	Entries []entryType `json:"entries"`
+
+	// RepresentativeHcard is the representative h-card of the page, if
+	// one was found.
+	RepresentativeHcard *microformats.Microformat `json:"representative-hcard"`
+
+	// Endpoints maps IndieWeb endpoint rel values to their discovered
+	// URLs, from HTTP Link headers followed by rel links in the page.
+	Endpoints map[string][]string `json:"endpoints"`
+}
+
+// entryType is the post type of a single h-entry.
+type entryType struct {
+	// Path identifies the h-entry within the parsed data, such as
+	// "items[0].children[1]".
+	Path string `json:"path"`
+
+	URL          string `json:"url,omitempty"`
+	Name         string `json:"name,omitempty"`
+	PostType     string `json:"post-type"`
+	ResponseType string `json:"response-type"`
+}
+
+// derive returns the derived data for result.
+func derive(result *parseResult) *derived {
+	d := &derived{
+		Entries:   []entryType{},
+		Endpoints: make(map[string][]string),
+	}
+
+	for i, item := range result.Data.Items {
+		d.Entries = appendEntries(d.Entries, item, fmt.Sprintf("items[%d]", i))
+	}
+
+	if result.URL != nil {
+		d.RepresentativeHcard = rhc.RepresentativeHcard(result.Data, result.URL.String())
+	}
+
+	// HTTP Link headers take precedence over links in the document
+	for _, l := range parseLinkHeader(result.Header, result.URL) {
+		for _, rel := range l.rels {
+			if slices.Contains(endpointRels, rel) && !slices.Contains(d.Endpoints[rel], l.url) {
+				d.Endpoints[rel] = append(d.Endpoints[rel], l.url)
+			}
+		}
+	}
+	for _, rel := range endpointRels {
+		for _, u := range result.Data.Rels[rel] {
+			if !slices.Contains(d.Endpoints[rel], u) {
+				d.Endpoints[rel] = append(d.Endpoints[rel], u)
+			}
+		}
+	}
+
+	return d
+}
+
+// appendEntries appends the post types of item and any h-entry nested within
+// it to entries.  path is the path to item.
+func appendEntries(entries []entryType, item *microformats.Microformat, path string) []entryType {
+	if slices.Contains(item.Type, "h-entry") {
+		entries = append(entries, entryType{
+			Path:         path,
+			URL:          firstString(item.Properties["url"]),
+			Name:         firstString(item.Properties["name"]),
+			PostType:     ptd.PostType(item),
+			ResponseType: ptd.ResponseType(item),
+		})
+	}
+	for _, name := range slices.Sorted(maps.Keys(item.Properties)) {
+		for i, v := range item.Properties[name] {
+			if mf, ok := v.(*microformats.Microformat); ok {
+				entries = appendEntries(entries, mf, fmt.Sprintf("%s.properties.%s[%d]", path, name, i))
+			}
+		}
+	}
+	for i, child := range item.Children {
+		entries = appendEntries(entries, child, fmt.Sprintf("%s.children[%d]", path, i))
+	}
+	return entries
+}
+
+// firstString returns the first string value in values.
+func firstString(values []any) string {
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// link is a single link parsed from an HTTP Link header.
+type link struct {
+	url  string
+	rels []string
+}
+
+// parseLinkHeader parses the links in the Link headers of h, resolving them
+// relative to base.  This only supports the subset of RFC 8288 commonly used
+// for endpoint discovery.
+func parseLinkHeader(h http.Header, base *url.URL) []link {
+	var links []link
+	for _, value := range h.Values("Link") {
+		for _, part := range splitUnquoted(value, ',') {
+			params := splitUnquoted(part, ';')
+			target := strings.TrimSpace(params[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			u := strings.Trim(target, "<>")
+			if base != nil {
+				if ref, err := base.Parse(u); err == nil {
+					u = ref.String()
+				}
+			}
+
+			l := link{url: u}
+			for _, param := range params[1:] {
+				k, v, _ := strings.Cut(param, "=")
+				if strings.EqualFold(strings.TrimSpace(k), "rel") {
+					l.rels = append(l.rels, strings.Fields(strings.ToLower(strings.Trim(strings.TrimSpace(v), `"`)))...)
+				}
+			}
+			links = append(links, l)
+		}
+	}
+	return links
+}
+
+// splitUnquoted splits s at each sep which is not within a quoted string or
+// a URL enclosed in angle brackets.
+func splitUnquoted(s string, sep byte) []string {
+	var parts []string
+	var quoted, bracketed bool
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quoted && c == '\\':
+			i++ // skip the escaped character
+		case c == '"' && !bracketed:
+			quoted = !quoted
+		case c == '<' && !quoted:
+			bracketed = true
+		case c == '>' && !quoted:
+			bracketed = false
+		case c == sep && !quoted && !bracketed:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
@@ -0,0 +1,102 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseLinkHeader(t *testing.T) {
+	base, _ := url.Parse("https://example.com/post/1")
+	tests := []struct {
+		header []string
+		want   []link
+	}{
+		{nil, nil},
+		{[]string{`<https://example.com/wm>; rel="webmention"`}, []link{{"https://example.com/wm", []string{"webmention"}}}},
+		{[]string{`</wm>; rel=webmention`}, []link{{"https://example.com/wm", []string{"webmention"}}}},
+		{[]string{`<wm>; REL="Webmention Micropub"`}, []link{{"https://example.com/post/wm", []string{"webmention", "micropub"}}}},
+		{
+			[]string{`</a>; rel="hub", </b>; rel="self"`, `</c>; title="x"`},
+			[]link{
+				{"https://example.com/a", []string{"hub"}},
+				{"https://example.com/b", []string{"self"}},
+				{"https://example.com/c", nil},
+			},
+		},
+
+		// commas and semicolons in URLs and quoted strings
+		{
+			[]string{`</wm?a=1,2;b>; title="a, b; c"; rel="webmention", </hub>; rel=hub`},
+			[]link{
+				{"https://example.com/wm?a=1,2;b", []string{"webmention"}},
+				{"https://example.com/hub", []string{"hub"}},
+			},
+		},
+		{[]string{`</x>; title="say \"hi\", ok"; rel="self"`}, []link{{"https://example.com/x", []string{"self"}}}},
+
+		// invalid links are skipped
+		{[]string{`https://example.com/wm; rel="webmention"`, `<`}, nil},
+	}
+
+	for _, tt := range tests {
+		h := http.Header{"Link": tt.header}
+		got := parseLinkHeader(h, base)
+		if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(link{})); diff != "" {
+			t.Errorf("parseLinkHeader(%q) differs:\n%s", tt.header, diff)
+		}
+	}
+}
+
+func TestAPIDerived(t *testing.T) {
+	f := &stubFetcher{
+		body: `<link rel="webmention" href="/page-wm"><link rel="micropub" href="/micropub">
+		<div class="h-card"><a class="u-url u-uid p-name" href="/">Alice</a></div>
+		<div class="h-feed">
+		  <div class="h-entry"><a class="u-url" href="/1"></a><a class="u-like-of" href="https://other.example/">liked</a></div>
+		  <div class="h-entry"><h1 class="p-name">Title</h1><div class="e-content">Some content</div></div>
+		</div>`,
+		header: http.Header{"Link": {`</header-wm>; rel="webmention"`}},
+	}
+	useFetcher(t, f)
+
+	w := httptest.NewRecorder()
+	newMux().ServeHTTP(w, httptest.NewRequest("GET", "/api/derived?url=https://example.com/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status is %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var got struct {
+		Entries             []entryType         `json:"entries"`
+		RepresentativeHcard json.RawMessage     `json:"representative-hcard"`
+		Endpoints           map[string][]string `json:"endpoints"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("error decoding response: %v", err)
+	}
+
+	wantEntries := []entryType{
+		{Path: "items[1].children[0]", URL: "https://example.com/1", Name: "liked", PostType: "like", ResponseType: "like"},
+		{Path: "items[1].children[1]", Name: "Title", PostType: "article", ResponseType: "mention"},
+	}
+	if diff := cmp.Diff(wantEntries, got.Entries); diff != "" {
+		t.Errorf("entries differ:\n%s", diff)
+	}
+	wantEndpoints := map[string][]string{
+		"webmention": {"https://example.com/header-wm", "https://example.com/page-wm"},
+		"micropub":   {"https://example.com/micropub"},
+	}
+	if diff := cmp.Diff(wantEndpoints, got.Endpoints); diff != "" {
+		t.Errorf("endpoints differ:\n%s", diff)
+	}
+	if string(got.RepresentativeHcard) == "null" {
+		t.Errorf("no representative h-card found")
+	}
+}
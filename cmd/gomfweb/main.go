@@ -4,34 +4,115 @@
 // The gomfweb command runs a simple web server that demonstrates the use of
 // the go microformats library.  It can parse the microformats found at a URL
 // or in a provided snippet of HTML.
+//
+// In addition to the HTML interface at "/", gomfweb provides a JSON API at
+// "/api/parse".  GET requests fetch and parse the page specified by the "url"
+// parameter.  POST requests parse either an HTML document in the request body
+// (with an optional "base-url" query parameter), or a form with "html" and
+// "base-url" fields.  Errors are returned as a JSON object with "status",
+// "error", and "message" fields.  CORS requests are supported, as are JSONP
+// requests using the "callback" parameter.
+//
+// The HTML interface also has a validator mode, which displays the source of
+// the page with each microformats element highlighted, alongside the parsed
+// items and any problems found by the lint package.
+//
+// The "/api/derived" endpoint accepts the same parameters, and reports data
+// derived from the parsed microformats: the post type of each h-entry, the
+// representative h-card, and any discovered IndieWeb endpoints.
+//
+// Fetched pages and their parsed microformats are cached for a short time,
+// up to a limited total size (see the -cache-ttl and -cache-bytes flags),
+// and revalidated with conditional requests once expired.  The rate of
+// requests from each client is limited.  Server statistics
+// are reported in the Prometheus text format at "/metrics".
+//
+// Since URLs are provided by users, gomfweb only fetches http and https URLs
+// that resolve to public IP addresses (including after any redirects), and
+// limits the size of and time spent fetching each page.  These restrictions
+// can be adjusted with flags; run "gomfweb -help" for details.
 package main
 
 import (
 	"bytes"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"html/template"
-	"io"
 	"log"
 	"net/http"
-	"net/url"
+	"net/netip"
 	"os"
 	"strings"
 	"time"
 
 	"willnorris.com/go/microformats"
+	"willnorris.com/go/microformats/fetch"
+	"willnorris.com/go/microformats/internal/flagutil"
 )
 
-var addr = flag.String("addr", ":4001", "Address and port to listen on")
+var (
+	addr = flag.String("addr", ":4001", "Address and port to listen on")
+
+	// options for fetching user-provided URLs
+	allowSchemes = flag.String("allow-schemes", "http,https", "Comma-separated list of URL schemes that may be fetched")
+	allowPrivate = flag.Bool("allow-private", false, "Allow fetching URLs that resolve to loopback, private, or link-local addresses")
+	maxBodySize  = flag.Int64("max-body-size", fetch.DefaultMaxBodySize, "Maximum size in bytes of fetched pages")
+	maxRedirects = flag.Int("max-redirects", fetch.DefaultMaxRedirects, "Maximum number of redirects to follow when fetching pages")
+	fetchTimeout = flag.Duration("fetch-timeout", 15*time.Second, "Timeout for fetching each page, including redirects")
+
+	// caching and rate limiting
+	cacheTTL       = flag.Duration("cache-ttl", 5*time.Minute, "How long to reuse fetched pages before revalidating them (0 disables caching)")
+	cacheSize      = flag.Int("cache-size", 1000, "Maximum number of fetched pages to cache")
+	cacheBytes     = flag.Int64("cache-bytes", 256<<20, "Maximum total size in bytes of cached pages, including their parsed microformats")
+	rateLimit      = flag.Float64("rate-limit", 2, "Requests per second allowed from each client (0 disables rate limiting)")
+	rateBurst      = flag.Int("rate-burst", 20, "Maximum burst of requests allowed from each client")
+	trustForwarded = flag.Bool("trust-forwarded", false, "Identify clients by the X-Forwarded-For header, when running behind a proxy")
+)
+
+// fetcher retrieves the pages at user-provided URLs.
+var fetcher fetch.Fetcher
+
+// parseOptions are used to parse all user-provided documents, limiting the
+// resources used by maliciously large or deeply nested documents.
+var parseOptions = microformats.ParseOptions{Limits: microformats.DefaultLimits()}
+
+// newFetcher returns a fetcher configured by the fetch flags.  Because the
+// URLs are provided by untrusted users, the fetcher refuses to connect to
+// non-public addresses unless explicitly allowed.  Unless disabled, fetched
+// pages are cached as described by the cache flags.
+func newFetcher() fetch.Fetcher {
+	r := fetch.Restrictions{
+		Schemes:      flagutil.Schemes(*allowSchemes),
+		MaxRedirects: *maxRedirects,
+	}
+	if *maxRedirects == 0 {
+		r.MaxRedirects = -1
+	}
+	if *allowPrivate {
+		r.AllowAddr = func(netip.Addr) bool { return true }
+	}
+	client := &fetch.Client{
+		HTTPClient:   fetch.NewRestrictedClient(r),
+		MaxBodySize:  *maxBodySize,
+		Timeout:      *fetchTimeout,
+		ParseOptions: parseOptions,
+	}
+	if *cacheTTL <= 0 {
+		return client
+	}
+	return newCachingFetcher(client, *cacheTTL, *cacheSize, *cacheBytes)
+}
 
 func main() {
 	flag.Parse()
+	fetcher = newFetcher()
 
 	srv := &http.Server{
 		Addr:    *addr,
-		Handler: http.HandlerFunc(index),
+		Handler: newHandler(),
 
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
@@ -46,66 +127,100 @@ func main() {
 	log.Fatal(srv.ListenAndServe())
 }
 
-func index(w http.ResponseWriter, r *http.Request) {
-	var parsedURL *url.URL
-	var err error
+// newHandler returns the handler for all gomfweb routes.  Requests other than
+// for metrics are rate limited as configured by the rate limit flags.
+func newHandler() http.Handler {
+	var h http.Handler = newMux()
+	if *rateLimit > 0 {
+		h = newRateLimiter(*rateLimit, *rateBurst, *trustForwarded).limit(h)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", metrics)
+	mux.Handle("/", h)
+	return countRequests(mux)
+}
 
-	u := strings.TrimSpace(r.FormValue("url"))
-	if u != "" {
-		parsedURL, err = url.Parse(u)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("error parsing url: %v", err), http.StatusBadRequest)
-		}
+// newMux returns the handler for the user interface and API routes.  The
+// size of request bodies is limited by the max body size flag.
+func newMux() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", index)
+	mux.HandleFunc("GET /api/parse", apiParse)
+	mux.HandleFunc("POST /api/parse", apiParse)
+	mux.HandleFunc("OPTIONS /api/parse", apiOptions)
+	mux.HandleFunc("GET /api/derived", apiDerived)
+	mux.HandleFunc("POST /api/derived", apiDerived)
+	mux.HandleFunc("OPTIONS /api/derived", apiOptions)
+	return limitBody(mux)
+}
+
+// index serves the HTML user interface.  If a URL or snippet of HTML is
+// provided, the parsed microformats are displayed, along with the validation
+// of the page if the "validate" parameter is set.
+func index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
 	}
 
-	buf := new(bytes.Buffer)
-	enc := json.NewEncoder(buf)
-	enc.SetEscapeHTML(false)
-	enc.SetIndent("", "  ")
+	// parse the form before reading any values from it, so that errors
+	// such as exceeding the maximum body size are reported
+	formErr := parseForm(r)
 
-	if r.Method == "GET" && parsedURL != nil {
-		resp, err := http.Get(parsedURL.String())
-		if err != nil {
-			http.Error(w, fmt.Sprintf("error fetching url content: %v", err), http.StatusInternalServerError)
-		}
-		defer func() {
-			_ = resp.Body.Close()
-		}()
+	data := struct {
+		HTML    string
+		URL     string
+		BaseURL string
+		JSON    string
+		Derived *derived
 
-		mf := microformats.Parse(resp.Body, parsedURL)
-		if err := enc.Encode(mf); err != nil {
-			http.Error(w, fmt.Sprintf("error marshaling json: %v", err), http.StatusInternalServerError)
-		}
+		Validate   bool
+		Validation *validation
 
-		if callback := r.FormValue("callback"); callback != "" {
-			_, _ = fmt.Fprintf(w, "%s(%s)", callback, buf.String())
+		Error string
+	}{
+		HTML:     r.FormValue("html"),
+		URL:      strings.TrimSpace(r.URL.Query().Get("url")),
+		BaseURL:  r.FormValue("base-url"),
+		Validate: r.FormValue("validate") != "",
+	}
+
+	status := http.StatusOK
+	if formErr != nil {
+		status = formErr.Status
+		data.Error = formErr.Error()
+	} else if data.URL != "" || data.HTML != "" {
+		result, err := parseRequest(r)
+		if err != nil {
+			var apiErr *apiError
+			if errors.As(err, &apiErr) {
+				status = apiErr.Status
+			} else {
+				status = http.StatusInternalServerError
+			}
+			data.Error = err.Error()
 		} else {
-			w.Header().Set("Content-Type", "application/mf2+json")
-			if _, err := io.Copy(w, buf); err != nil {
-				log.Print(err)
+			buf := new(bytes.Buffer)
+			enc := json.NewEncoder(buf)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(result.Data); err != nil {
+				http.Error(w, fmt.Sprintf("error marshaling json: %v", err), http.StatusInternalServerError)
+				return
 			}
-		}
-		return
-	}
+			data.JSON = buf.String()
+			data.Derived = derive(result)
 
-	html := r.FormValue("html")
-	if html != "" {
-		mf := microformats.Parse(strings.NewReader(html), parsedURL)
-		if err := enc.Encode(mf); err != nil {
-			http.Error(w, fmt.Sprintf("error marshaling json: %v", err), http.StatusInternalServerError)
+			if data.Validate {
+				if data.Validation, err = validate(result); err != nil {
+					data.Error = err.Error()
+				}
+			}
 		}
 	}
 
-	data := struct {
-		HTML string
-		URL  string
-		JSON string
-	}{
-		html,
-		u,
-		buf.String(),
-	}
-
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
 	if err := tpl.Execute(w, data); err != nil {
 		log.Print(err)
 	}
@@ -0,0 +1,98 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// durationBuckets are the upper bounds, in seconds, of the parse duration
+// histogram buckets.
+var durationBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// stats holds the server statistics reported by the metrics endpoint.
+var stats = newServerStats()
+
+// serverStats are counters describing the requests handled by the server.
+type serverStats struct {
+	start time.Time
+
+	requests    atomic.Int64
+	rateLimited atomic.Int64
+	cacheHits   atomic.Int64
+	cacheMisses atomic.Int64
+	fetchErrors atomic.Int64
+
+	mu        sync.Mutex
+	parses    int64
+	parseSum  time.Duration
+	parseHist []int64 // counts for each of durationBuckets
+}
+
+func newServerStats() *serverStats {
+	return &serverStats{
+		start:     time.Now(),
+		parseHist: make([]int64, len(durationBuckets)),
+	}
+}
+
+// observeParse records the duration of a single parse request.
+func (s *serverStats) observeParse(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.parses++
+	s.parseSum += d
+	for i, le := range durationBuckets {
+		if d.Seconds() <= le {
+			s.parseHist[i]++
+		}
+	}
+}
+
+// countRequests wraps h, counting each request in stats.
+func countRequests(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stats.requests.Add(1)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// metrics serves server statistics in the Prometheus text exposition format.
+func metrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	counter := func(name, help string, v int64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, v)
+	}
+	gauge := func(name, help string, v float64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", name, help, name, name, v)
+	}
+
+	counter("gomfweb_requests_total", "Total HTTP requests received.", stats.requests.Load())
+	counter("gomfweb_rate_limited_total", "Requests rejected by the rate limiter.", stats.rateLimited.Load())
+	counter("gomfweb_cache_hits_total", "Fetches served from the parse cache.", stats.cacheHits.Load())
+	counter("gomfweb_cache_misses_total", "Fetches not found in the parse cache.", stats.cacheMisses.Load())
+	counter("gomfweb_fetch_errors_total", "Errors fetching user-provided URLs.", stats.fetchErrors.Load())
+	if cf, ok := fetcher.(*cachingFetcher); ok {
+		entries, size := cf.len()
+		gauge("gomfweb_cache_entries", "Responses in the parse cache.", float64(entries))
+		gauge("gomfweb_cache_bytes", "Estimated size in bytes of responses in the parse cache.", float64(size))
+	}
+	gauge("gomfweb_uptime_seconds", "Time since the server started.", time.Since(stats.start).Seconds())
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	const name = "gomfweb_parse_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Time taken to fetch and parse microformats.\n# TYPE %s histogram\n", name, name)
+	for i, le := range durationBuckets {
+		fmt.Fprintf(w, "%s_bucket{le=\"%g\"} %d\n", name, le, stats.parseHist[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, stats.parses)
+	fmt.Fprintf(w, "%s_sum %g\n", name, stats.parseSum.Seconds())
+	fmt.Fprintf(w, "%s_count %d\n", name, stats.parses)
+}
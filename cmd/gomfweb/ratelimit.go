@@ -0,0 +1,121 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimiter limits the rate of requests from each client using a token
+// bucket per client address.
+type rateLimiter struct {
+	rate  float64 // tokens added per second
+	burst float64 // maximum number of tokens
+
+	// trustForwarded is whether to identify clients by the
+	// X-Forwarded-For header, when running behind a proxy.
+	trustForwarded bool
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	lastGC  time.Time
+}
+
+// bucket is the token bucket for a single client.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a rateLimiter allowing rate requests per second per
+// client, with bursts of up to burst requests.
+func newRateLimiter(rate float64, burst int, trustForwarded bool) *rateLimiter {
+	return &rateLimiter{
+		rate:           rate,
+		burst:          float64(max(burst, 1)),
+		trustForwarded: trustForwarded,
+		buckets:        make(map[string]*bucket),
+		lastGC:         time.Now(),
+	}
+}
+
+// allow reports whether a request from client is allowed at time now.  If
+// not, it also returns how long the client should wait before retrying.
+func (l *rateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.gc(now)
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+// gc removes the buckets of clients which have been idle long enough for
+// their bucket to refill.  It is run at most once a minute.
+func (l *rateLimiter) gc(now time.Time) {
+	if now.Sub(l.lastGC) < time.Minute {
+		return
+	}
+	l.lastGC = now
+	full := time.Duration(l.burst / l.rate * float64(time.Second))
+	for client, b := range l.buckets {
+		if now.Sub(b.last) > full {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// clientAddr returns the address identifying the client making r.
+func (l *rateLimiter) clientAddr(r *http.Request) string {
+	if l.trustForwarded {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			// the last address was added by the trusted proxy
+			parts := strings.Split(fwd, ",")
+			return strings.TrimSpace(parts[len(parts)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// limit wraps h, rejecting requests from clients that exceed the rate limit.
+func (l *rateLimiter) limit(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, wait := l.allow(l.clientAddr(r), time.Now())
+		if !ok {
+			stats.rateLimited.Add(1)
+			retry := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(retry))
+			err := newAPIError(http.StatusTooManyRequests, "rate limit exceeded, retry in %ds", retry)
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				setCORSHeaders(w)
+				writeError(w, r, err)
+			} else {
+				http.Error(w, err.Message, err.Status)
+			}
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,129 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	l := newRateLimiter(2, 3, false)
+	now := time.Now()
+
+	// the burst is allowed, then requests are limited
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.allow("a", now); !ok {
+			t.Fatalf("request %d was not allowed", i)
+		}
+	}
+	ok, wait := l.allow("a", now)
+	if ok {
+		t.Fatalf("request beyond burst was allowed")
+	}
+	if wait != 500*time.Millisecond {
+		t.Errorf("allow returned wait %v, want %v", wait, 500*time.Millisecond)
+	}
+
+	// other clients have their own bucket
+	if ok, _ := l.allow("b", now); !ok {
+		t.Errorf("request from another client was not allowed")
+	}
+
+	// tokens are added at the configured rate
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.allow("a", now); !ok {
+		t.Errorf("request after waiting was not allowed")
+	}
+	if ok, _ := l.allow("a", now); ok {
+		t.Errorf("second request after waiting was allowed")
+	}
+
+	// buckets do not fill beyond the burst
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.allow("a", now); !ok {
+			t.Fatalf("request %d after idling was not allowed", i)
+		}
+	}
+	if ok, _ := l.allow("a", now); ok {
+		t.Errorf("request beyond burst after idling was allowed")
+	}
+}
+
+func TestRateLimiter_GC(t *testing.T) {
+	l := newRateLimiter(1, 2, false)
+	now := time.Now()
+	l.allow("a", now)
+	l.allow("b", now.Add(59*time.Second))
+
+	// the bucket for a has refilled, but b was used too recently
+	l.allow("c", now.Add(time.Minute))
+	if _, ok := l.buckets["a"]; ok {
+		t.Errorf("idle bucket was not removed")
+	}
+	if _, ok := l.buckets["b"]; !ok {
+		t.Errorf("recently used bucket was removed")
+	}
+}
+
+func TestRateLimiter_ClientAddr(t *testing.T) {
+	tests := []struct {
+		remoteAddr     string
+		forwarded      string
+		trustForwarded bool
+		want           string
+	}{
+		{"192.0.2.1:1234", "", false, "192.0.2.1"},
+		{"[2001:db8::1]:1234", "", false, "2001:db8::1"},
+		{"192.0.2.1", "", false, "192.0.2.1"},
+		{"192.0.2.1:1234", "198.51.100.1", false, "192.0.2.1"},
+		{"192.0.2.1:1234", "198.51.100.1", true, "198.51.100.1"},
+		{"192.0.2.1:1234", "10.0.0.1, 198.51.100.1", true, "198.51.100.1"},
+		{"192.0.2.1:1234", "", true, "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remoteAddr
+		if tt.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		l := newRateLimiter(1, 1, tt.trustForwarded)
+		if got := l.clientAddr(r); got != tt.want {
+			t.Errorf("clientAddr(%q, %q, %t) = %q, want %q", tt.remoteAddr, tt.forwarded, tt.trustForwarded, got, tt.want)
+		}
+	}
+}
+
+func TestRateLimiter_Limit(t *testing.T) {
+	l := newRateLimiter(0.5, 1, false)
+	h := l.limit(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantType   string
+	}{
+		{"/api/parse", http.StatusNoContent, ""},
+		{"/api/parse", http.StatusTooManyRequests, "application/json"},
+		{"/", http.StatusTooManyRequests, "text/plain; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s returned status %d, want %d", tt.path, w.Code, tt.wantStatus)
+		}
+		if got := w.Header().Get("Content-Type"); got != tt.wantType {
+			t.Errorf("%s returned Content-Type %q, want %q", tt.path, got, tt.wantType)
+		}
+		if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "2" {
+			t.Errorf("%s returned Retry-After %q, want %q", tt.path, w.Header().Get("Retry-After"), "2")
+		}
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"willnorris.com/go/microformats"
+	"willnorris.com/go/microformats/lint"
+)
+
+// mfClassNames matches microformats2 root and property class names.
+var mfClassNames = regexp.MustCompile(`^(h|p|u|dt|e)-`)
+
+// validation is the result of validating a page, for display in the
+// validator view.
+type validation struct {
+	// Source is the original HTML source of the page, escaped for display,
+	// with the start tags of microformats elements highlighted and
+	// annotated.
+	Source template.HTML
+
+	// Items lists each microformat found, linked to its source element.
+	Items []validatedItem
+
+	Findings []validatedFinding
+}
+
+// validatedItem is a microformat listed in the validator view.
+type validatedItem struct {
+	Path   string
+	Type   []string
+	Depth  int
+	Anchor string // ID of the item's element in Source
+}
+
+// validatedFinding is a lint finding listed in the validator view.
+type validatedFinding struct {
+	lint.Finding
+	ID     string
+	Anchor string // ID of the finding's element in Source, if known
+}
+
+// validate parses the document in result and returns its validation.
+//
+// The document is parsed again, rather than using result.Data, so that each
+// microformat can be linked to the element it was parsed from.
+func validate(result *parseResult) (*validation, error) {
+	var r io.Reader = bytes.NewReader(result.Body)
+	if cr, err := charset.NewReader(r, result.ContentType); err == nil {
+		r = cr
+	}
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding html: %v", err)
+	}
+	doc, err := html.Parse(bytes.NewReader(src))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing html: %v", err)
+	}
+
+	// the parser expands relative URLs within e-* properties in place, so
+	// restore the original attributes afterwards for display and linting.
+	attrs := make(map[*html.Node][]html.Attribute)
+	for _, n := range elements(doc) {
+		attrs[n] = slices.Clone(n.Attr)
+	}
+	data := parseOptions.ParseNode(doc, result.BaseURL)
+	for n, a := range attrs {
+		n.Attr = a
+	}
+
+	sw := &sourceWriter{
+		anchors:  make(map[*html.Node]string),
+		roots:    make(map[*html.Node][]string),
+		findings: make(map[*html.Node][]validatedFinding),
+	}
+	v := new(validation)
+	for i, item := range data.Items {
+		v.Items = sw.appendItems(v.Items, item, fmt.Sprintf("items[%d]", i), 0)
+	}
+	for i, f := range append(lint.Check(data), lint.CheckNode(doc)...) {
+		vf := validatedFinding{Finding: f, ID: fmt.Sprintf("finding-%d", i+1)}
+		if f.Node != nil {
+			vf.Anchor = sw.anchor(f.Node)
+			sw.findings[f.Node] = append(sw.findings[f.Node], vf)
+		}
+		v.Findings = append(v.Findings, vf)
+	}
+
+	sw.writeSource(src, elements(doc))
+	v.Source = template.HTML(sw.b.String())
+	return v, nil
+}
+
+// sourceWriter writes an HTML document as escaped source code, annotating
+// microformats elements.
+type sourceWriter struct {
+	b strings.Builder
+
+	// anchors are the IDs assigned to annotated elements
+	anchors map[*html.Node]string
+
+	// roots are the types of microformats parsed from each element
+	roots map[*html.Node][]string
+
+	// findings are the lint findings for each element
+	findings map[*html.Node][]validatedFinding
+}
+
+// anchor returns the ID for node, assigning one if needed.
+func (sw *sourceWriter) anchor(node *html.Node) string {
+	if a, ok := sw.anchors[node]; ok {
+		return a
+	}
+	a := fmt.Sprintf("src-%d", len(sw.anchors)+1)
+	sw.anchors[node] = a
+	return a
+}
+
+// appendItems appends item and the microformats nested within it to items.
+// path is the path to item, and depth is its nesting depth.
+func (sw *sourceWriter) appendItems(items []validatedItem, item *microformats.Microformat, path string, depth int) []validatedItem {
+	vi := validatedItem{Path: path, Type: item.Type, Depth: depth}
+	if node := item.Node(); node != nil {
+		vi.Anchor = sw.anchor(node)
+		sw.roots[node] = item.Type
+	}
+	items = append(items, vi)
+
+	for _, name := range slices.Sorted(maps.Keys(item.Properties)) {
+		for i, v := range item.Properties[name] {
+			if mf, ok := v.(*microformats.Microformat); ok {
+				items = sw.appendItems(items, mf, fmt.Sprintf("%s.properties.%s[%d]", path, name, i), depth+1)
+			}
+		}
+	}
+	for i, child := range item.Children {
+		items = sw.appendItems(items, child, fmt.Sprintf("%s.children[%d]", path, i), depth+1)
+	}
+	return items
+}
+
+// matchWindow is the number of parsed elements searched for the element
+// created by a start tag.  Elements implied by the HTML parser, such as a
+// missing html or tbody, have no start tag in the source and are skipped.
+const matchWindow = 8
+
+// writeSource writes the original source src, annotating the start tags of
+// microformats elements.  elems are the elements parsed from src, in
+// document order.
+//
+// The HTML parser does not record source positions, so each start tag is
+// matched to the next parsed element with the same name and attributes.
+// Start tags that the parser ignores or merges into another element are
+// written without annotation.
+func (sw *sourceWriter) writeSource(src []byte, elems []*html.Node) {
+	z := html.NewTokenizer(bytes.NewReader(src))
+	next := 0
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return
+		}
+		raw := string(z.Raw())
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			var node *html.Node
+			for i := next; i < len(elems) && i < next+matchWindow; i++ {
+				if sameTag(elems[i], tok) {
+					node, next = elems[i], i+1
+					break
+				}
+			}
+			sw.writeStartTag(node, raw)
+		case html.CommentToken:
+			sw.b.WriteString(`<span class="src-comment">`)
+			sw.b.WriteString(template.HTMLEscapeString(raw))
+			sw.b.WriteString("</span>")
+		default:
+			sw.b.WriteString(template.HTMLEscapeString(raw))
+		}
+	}
+}
+
+// writeStartTag writes raw, the source of the start tag of node, highlighting
+// it if node is a microformats root or property, or has lint findings.  node
+// may be nil if the start tag did not create an element.
+func (sw *sourceWriter) writeStartTag(node *html.Node, raw string) {
+	if node == nil {
+		sw.b.WriteString(template.HTMLEscapeString(raw))
+		return
+	}
+
+	var classes []string
+	for _, class := range strings.Fields(getAttr(node, "class")) {
+		if mfClassNames.MatchString(class) {
+			classes = append(classes, class)
+		}
+	}
+	types, isRoot := sw.roots[node]
+	findings := sw.findings[node]
+	if !isRoot && len(classes) == 0 && len(findings) == 0 {
+		sw.b.WriteString(template.HTMLEscapeString(raw))
+		return
+	}
+
+	kind := "mf-property"
+	if isRoot {
+		kind = "mf-root"
+	} else if len(classes) == 0 {
+		kind = "mf-finding"
+	}
+	fmt.Fprintf(&sw.b, `<span id="%s" class="mf %s" title="%s">`,
+		sw.anchor(node), kind, template.HTMLEscapeString(strings.Join(classes, " ")))
+	sw.b.WriteString(template.HTMLEscapeString(raw))
+	for _, t := range types {
+		fmt.Fprintf(&sw.b, `<span class="badge badge-info">%s</span>`, template.HTMLEscapeString(t))
+	}
+	for _, f := range findings {
+		badge := "badge-default"
+		switch f.Severity {
+		case lint.Warning:
+			badge = "badge-warning"
+		case lint.Error:
+			badge = "badge-danger"
+		}
+		fmt.Fprintf(&sw.b, `<a href="#%s" class="badge %s" title="%s">%s</a>`,
+			f.ID, badge, template.HTMLEscapeString(f.Message), template.HTMLEscapeString(f.Rule))
+	}
+	sw.b.WriteString("</span>")
+}
+
+// sameTag returns whether node was created from the start tag tok.
+func sameTag(node *html.Node, tok html.Token) bool {
+	if !strings.EqualFold(node.Data, tok.Data) || len(node.Attr) != len(tok.Attr) {
+		return false
+	}
+	for i, attr := range node.Attr {
+		if !strings.EqualFold(attr.Key, tok.Attr[i].Key) || attr.Val != tok.Attr[i].Val {
+			return false
+		}
+	}
+	return true
+}
+
+// elements returns the elements in doc in document order.  The tree is
+// walked iteratively, since it may be arbitrarily deep.
+func elements(doc *html.Node) []*html.Node {
+	var elems []*html.Node
+	for n := doc; n != nil; {
+		if n.Type == html.ElementNode {
+			elems = append(elems, n)
+		}
+		if n.FirstChild != nil {
+			n = n.FirstChild
+			continue
+		}
+		for n != doc && n.NextSibling == nil {
+			n = n.Parent
+		}
+		if n == doc {
+			break
+		}
+		n = n.NextSibling
+	}
+	return elems
+}
+
+// getAttr returns the value of the specified attribute on node.
+func getAttr(node *html.Node, name string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == name {
+			return attr.Val
+		}
+	}
+	return ""
+}
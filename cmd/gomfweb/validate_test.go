@@ -0,0 +1,87 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidate_Source(t *testing.T) {
+	src := "<!doctype html>\n<P CLASS='h-card'>\n  <a class=\"u-url\" href=\"/me\">Alice</a> <!-- note -->\n  <br><img class=\"u-photo\" src=\"a.jpg\"/>\n</P>"
+	base, _ := url.Parse("https://example.com/")
+	v, err := validate(&parseResult{Body: []byte(src), BaseURL: base})
+	if err != nil {
+		t.Fatalf("validate returned error: %v", err)
+	}
+
+	want := `&lt;!doctype html&gt;
+<span id="src-1" class="mf mf-root" title="h-card">&lt;P CLASS=&#39;h-card&#39;&gt;<span class="badge badge-info">h-card</span></span>
+  <span id="src-2" class="mf mf-property" title="u-url">&lt;a class=&#34;u-url&#34; href=&#34;/me&#34;&gt;</span>Alice&lt;/a&gt; <span class="src-comment">&lt;!-- note --&gt;</span>
+  &lt;br&gt;<span id="src-3" class="mf mf-property" title="u-photo">&lt;img class=&#34;u-photo&#34; src=&#34;a.jpg&#34;/&gt;</span>
+&lt;/P&gt;`
+	if diff := cmp.Diff(want, string(v.Source)); diff != "" {
+		t.Errorf("validate source differs:\n%s", diff)
+	}
+
+	wantItems := []validatedItem{{Path: "items[0]", Type: []string{"h-card"}, Anchor: "src-1"}}
+	if diff := cmp.Diff(wantItems, v.Items); diff != "" {
+		t.Errorf("validate items differ:\n%s", diff)
+	}
+}
+
+func TestValidate_ImpliedElements(t *testing.T) {
+	// the parser inserts tbody, and ignores the second body tag
+	src := `<body><table><tr class="h-card"><td class="p-name">Alice</td></tr></table><body class="x"><div class="h-entry"></div>`
+	v, err := validate(&parseResult{Body: []byte(src)})
+	if err != nil {
+		t.Fatalf("validate returned error: %v", err)
+	}
+	for _, want := range []string{
+		`<span id="src-1" class="mf mf-root" title="h-card">&lt;tr class=&#34;h-card&#34;&gt;`,
+		`<span id="src-2" class="mf mf-root" title="h-entry">&lt;div class=&#34;h-entry&#34;&gt;`,
+		`&lt;body class=&#34;x&#34;&gt;`,
+	} {
+		if !strings.Contains(string(v.Source), want) {
+			t.Errorf("validate source does not contain %q:\n%s", want, v.Source)
+		}
+	}
+}
+
+func TestValidate_Deep(t *testing.T) {
+	n := 10000
+	src := strings.Repeat("<span>", n) + `<b class="h-card">Alice</b>` + strings.Repeat("</span>", n)
+	v, err := validate(&parseResult{Body: []byte(src)})
+	if err != nil {
+		t.Fatalf("validate returned error: %v", err)
+	}
+	if !strings.HasPrefix(string(v.Source), "&lt;span&gt;&lt;span&gt;") {
+		t.Errorf("validate source does not include deeply nested elements")
+	}
+}
+
+func TestParseRequest_BaseURL(t *testing.T) {
+	f := &stubFetcher{
+		body:   `<a class="h-card" href="me">Alice</a>`,
+		header: http.Header{"Content-Location": {"/people/alice"}},
+	}
+	useFetcher(t, f)
+
+	r := httptest.NewRequest("GET", "/?url=https://example.com/a/b", nil)
+	result, err := parseRequest(r)
+	if err != nil {
+		t.Fatalf("parseRequest returned error: %v", err)
+	}
+	if got, want := result.URL.String(), "https://example.com/a/b"; got != want {
+		t.Errorf("parseRequest URL is %q, want %q", got, want)
+	}
+	if got, want := result.BaseURL.String(), "https://example.com/people/alice"; got != want {
+		t.Errorf("parseRequest BaseURL is %q, want %q", got, want)
+	}
+}
@@ -22,6 +22,10 @@ type datetime struct {
 	// track whether date, time (with or without seconds), and timezone values have been set
 	hasDate, hasTime, hasTZ bool
 	hasSeconds              bool
+
+	// tzSpecified is whether the timezone was specified in the parsed value,
+	// rather than implied for a combined date and time value
+	tzSpecified bool
 }
 
 // Set the date for d.  Has no effect if date has already been set.
@@ -42,13 +46,15 @@ func (d *datetime) setTime(hour, min, sec int) {
 	d.hasTime = true
 }
 
-// Set the timezone for d.  Has no effect if timezone has already been set.
-func (d *datetime) setTZ(loc *time.Location) {
+// Set the timezone for d.  specified is whether the timezone was included in
+// the parsed value.  Has no effect if timezone has already been set.
+func (d *datetime) setTZ(loc *time.Location, specified bool) {
 	if d.hasTZ {
 		return
 	}
 	d.t = time.Date(d.t.Year(), d.t.Month(), d.t.Day(), d.t.Hour(), d.t.Minute(), d.t.Second(), 0, loc)
 	d.hasTZ = true
+	d.tzSpecified = specified
 }
 
 const (
@@ -112,19 +118,19 @@ var (
 // various date time format strings
 var (
 	datetimeFormats = []struct {
-		format     string
-		hasSeconds bool
+		format            string
+		hasSeconds, hasTZ bool
 	}{
-		{time.RFC3339, true},
-		{"2006-01-02T15:04:05-07:00", true},
-		{"2006-01-02T15:04:05-0700", true},
-		{"2006-01-02T15:04:05-07", true},
-		{"2006-01-02T15:04:05", true},
-		{"2006-01-02T15:04Z07:00", false},
-		{"2006-01-02T15:04-07:00", false},
-		{"2006-01-02T15:04-0700", false},
-		{"2006-01-02T15:04-07", false},
-		{"2006-01-02T15:04", false},
+		{time.RFC3339, true, true},
+		{"2006-01-02T15:04:05-07:00", true, true},
+		{"2006-01-02T15:04:05-0700", true, true},
+		{"2006-01-02T15:04:05-07", true, true},
+		{"2006-01-02T15:04:05", true, false},
+		{"2006-01-02T15:04Z07:00", false, true},
+		{"2006-01-02T15:04-07:00", false, true},
+		{"2006-01-02T15:04-0700", false, true},
+		{"2006-01-02T15:04-07", false, true},
+		{"2006-01-02T15:04", false, false},
 	}
 
 	timeFormats = []struct {
@@ -164,7 +170,7 @@ func (d *datetime) Parse(s string) {
 		if t, err := time.Parse(f.format, s); err == nil {
 			d.setDate(t.Year(), t.Month(), t.Day())
 			d.setTime(t.Hour(), t.Minute(), t.Second())
-			d.setTZ(t.Location())
+			d.setTZ(t.Location(), f.hasTZ)
 			d.hasSeconds = f.hasSeconds
 			return
 		}
@@ -190,7 +196,7 @@ func (d *datetime) Parse(s string) {
 			d.setTime(t.Hour(), t.Minute(), t.Second())
 			d.hasSeconds = f.hasSeconds
 			if f.hasTZ {
-				d.setTZ(t.Location())
+				d.setTZ(t.Location(), true)
 			}
 			return
 		}
@@ -199,14 +205,14 @@ func (d *datetime) Parse(s string) {
 	// timezone only formats
 	for _, format := range tzFormats {
 		if t, err := time.Parse(format, s); err == nil {
-			d.setTZ(t.Location())
+			d.setTZ(t.Location(), true)
 			return
 		}
 	}
 }
 
-func getDateTimeValue(node *html.Node) *string {
-	values := parseValueClassPattern(node, true)
+func (p *parser) getDateTimeValue(node *html.Node) *string {
+	values := p.parseValueClassPattern(node, true)
 	var d datetime
 	for _, v := range values {
 		d.Parse(strings.TrimSpace(v))
@@ -247,3 +253,65 @@ func implyEndDate(item *Microformat) {
 		}
 	}
 }
+
+// DateTime is a date and time value parsed using the microformats date and
+// time parsing rules.  The date, time, and timezone components of a value may
+// each be present or absent independently.  Components which were not
+// specified have their zero value in Time, and a value without a timezone is
+// represented in UTC.
+type DateTime struct {
+	Time time.Time
+
+	// whether date, time (with or without seconds), and timezone values were specified
+	HasDate, HasTime, HasTZ bool
+	HasSeconds              bool
+}
+
+// ParseDateTime parses s as a microformats datetime value.  The returned bool
+// is false if s does not include any recognizable date, time, or timezone
+// value.
+//
+// Microformat docs: http://microformats.org/wiki/value-class-pattern#Date_and_time_parsing
+func ParseDateTime(s string) (DateTime, bool) {
+	s = strings.TrimSpace(s)
+	var d datetime
+	d.Parse(s)
+	dt := DateTime{
+		Time:       d.t,
+		HasDate:    d.hasDate,
+		HasTime:    d.hasTime,
+		HasTZ:      d.tzSpecified,
+		HasSeconds: d.hasSeconds,
+	}
+	return dt, d.hasDate || d.hasTime || d.hasTZ
+}
+
+// String returns the normalized string representation of d, using the same
+// format as datetime values in parsed microformats.  Values with a time but
+// no date are formatted as "HH:MM:SS+XXYY", omitting seconds and timezone if
+// they were not specified.
+func (d DateTime) String() string {
+	if !d.HasDate && d.HasTime {
+		format := "15:04"
+		if d.HasSeconds {
+			format = "15:04:05"
+		}
+		if d.HasTZ {
+			format += "-0700"
+		}
+		value := d.Time.Format(format)
+		if d.HasTZ && strings.HasSuffix(value, "+0000") {
+			value = strings.TrimSuffix(value, "+0000") + "Z"
+		}
+		return value
+	}
+
+	dt := datetime{
+		t:          d.Time,
+		hasDate:    d.HasDate,
+		hasTime:    d.HasTime,
+		hasTZ:      d.HasTZ,
+		hasSeconds: d.HasSeconds,
+	}
+	return dt.String()
+}
@@ -88,7 +88,7 @@ func Test_Datetime_SetTZ(t *testing.T) {
 		time.Date(2000, 1, 3, 4, 5, 6, 0, time.UTC),
 		{},
 	} {
-		d.setTZ(tt.Location())
+		d.setTZ(tt.Location(), true)
 
 		// check that has* flags are set properly
 		if got, want := d.hasDate, false; got != want {
@@ -216,7 +216,7 @@ func Test_GetDateTimeValue(t *testing.T) {
 			t.Fatalf("Error parsing HTML: %v", err)
 		}
 
-		if got, want := getDateTimeValue(n), tt.value; !cmp.Equal(got, want) {
+		if got, want := new(parser).getDateTimeValue(n), tt.value; !cmp.Equal(got, want) {
 			t.Errorf("getDateTimeValue(%q) returned %v, want %v", tt.html, *got, *want)
 		}
 	}
@@ -281,3 +281,39 @@ func Test_ImplyEndDate(t *testing.T) {
 		}
 	}
 }
+
+func Test_ParseDateTime(t *testing.T) {
+	tests := []struct {
+		s      string
+		want   string
+		hasTZ  bool
+		wantOK bool
+	}{
+		{"", "", false, false},
+		{"foo", "", false, false},
+		{"2015-02-03", "2015-02-03", false, true},
+		{"2015-02-03 21:15", "2015-02-03 21:15", false, true},
+		{"2015-02-03T21:15:00", "2015-02-03 21:15:00", false, true},
+		{"2015-02-03T21:15:00Z", "2015-02-03 21:15:00Z", true, true},
+		{"2015-02-03T21:15:00-08:00", "2015-02-03 21:15:00-0800", true, true},
+		{"2015-02-03 21:15-08", "2015-02-03 21:15-0800", true, true},
+		{"2015-02-03 12:00-ish", "", false, false},
+		{"21:15", "21:15", false, true},
+		{"21:15:30", "21:15:30", false, true},
+		{"21:15-0800", "21:15-0800", true, true},
+		{"21:15Z", "21:15Z", true, true},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseDateTime(tt.s)
+		if ok != tt.wantOK {
+			t.Errorf("ParseDateTime(%q) returned ok %t, want %t", tt.s, ok, tt.wantOK)
+		}
+		if got.String() != tt.want {
+			t.Errorf("ParseDateTime(%q) returned %q, want %q", tt.s, got.String(), tt.want)
+		}
+		if got.HasTZ != tt.hasTZ {
+			t.Errorf("ParseDateTime(%q) returned HasTZ %t, want %t", tt.s, got.HasTZ, tt.hasTZ)
+		}
+	}
+}
@@ -0,0 +1,406 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package feedxml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"willnorris.com/go/microformats"
+	"willnorris.com/go/microformats/internal/feed"
+)
+
+// Decode reads an Atom 1.0 or RSS 2.0 feed from r and returns it as
+// microformats data containing a single h-feed, with an h-entry child for
+// each entry or item.  Property names and values have the same shapes as
+// those produced by the microformats parser: content is a map with "html"
+// and "value" keys, authors are nested h-cards, and datetimes use the
+// parser's normalized format.  Relative URLs are resolved using base, if it
+// is not nil.
+//
+// In addition to the core elements of each format, RSS content:encoded and
+// dc:creator elements, Media RSS content and thumbnails, and Atom threading
+// in-reply-to elements are supported.
+func Decode(r io.Reader, base *url.URL) (*microformats.Data, error) {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = charset.NewReaderLabel
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("feedxml: %w", err)
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		var f *feed.Feed
+		switch {
+		case se.Name.Space == atomNS && se.Name.Local == "feed":
+			var doc atomDocument
+			if err := dec.DecodeElement(&doc, &se); err != nil {
+				return nil, fmt.Errorf("feedxml: %w", err)
+			}
+			f = doc.feed()
+		case se.Name.Space == "" && se.Name.Local == "rss":
+			var doc rssDocument
+			if err := dec.DecodeElement(&doc, &se); err != nil {
+				return nil, fmt.Errorf("feedxml: %w", err)
+			}
+			f = doc.Channel.feed()
+		default:
+			return nil, fmt.Errorf("feedxml: unsupported feed element %q", se.Name.Local)
+		}
+		return f.Data(base), nil
+	}
+}
+
+// atomNS is the namespace of Atom elements.
+const atomNS = "http://www.w3.org/2005/Atom"
+
+type atomDocument struct {
+	Title    atomText            `xml:"http://www.w3.org/2005/Atom title"`
+	Subtitle atomText            `xml:"http://www.w3.org/2005/Atom subtitle"`
+	Links    []atomLink          `xml:"http://www.w3.org/2005/Atom link"`
+	Authors  []atomPerson        `xml:"http://www.w3.org/2005/Atom author"`
+	Logo     string              `xml:"http://www.w3.org/2005/Atom logo"`
+	Icon     string              `xml:"http://www.w3.org/2005/Atom icon"`
+	Entries  []atomDocumentEntry `xml:"http://www.w3.org/2005/Atom entry"`
+}
+
+type atomDocumentEntry struct {
+	ID         string          `xml:"http://www.w3.org/2005/Atom id"`
+	Title      atomText        `xml:"http://www.w3.org/2005/Atom title"`
+	Updated    string          `xml:"http://www.w3.org/2005/Atom updated"`
+	Published  string          `xml:"http://www.w3.org/2005/Atom published"`
+	Links      []atomLink      `xml:"http://www.w3.org/2005/Atom link"`
+	Authors    []atomPerson    `xml:"http://www.w3.org/2005/Atom author"`
+	Categories []atomCategory  `xml:"http://www.w3.org/2005/Atom category"`
+	Summary    atomText        `xml:"http://www.w3.org/2005/Atom summary"`
+	Content    atomText        `xml:"http://www.w3.org/2005/Atom content"`
+	InReplyTo  []threadReplyTo `xml:"http://purl.org/syndication/thread/1.0 in-reply-to"`
+	media
+}
+
+// atomText is an Atom text construct or content element, which may contain
+// plain text, escaped HTML, or XHTML.
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Src   string `xml:"src,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// html returns the content of t as HTML.  Content that is not text, such as
+// base64 encoded images, is ignored.
+func (t atomText) html() string {
+	switch t.Type {
+	case "", "text":
+		return html.EscapeString(strings.TrimSpace(t.Text))
+	case "html":
+		return strings.TrimSpace(t.Text)
+	case "xhtml":
+		// remove the wrapping div element
+		inner := strings.TrimSpace(t.Inner)
+		if i := strings.Index(inner, ">"); i >= 0 && strings.HasSuffix(inner, ">") {
+			if j := strings.LastIndex(inner, "</"); j > i {
+				inner = inner[i+1 : j]
+			}
+		}
+		return strings.TrimSpace(inner)
+	}
+	if t.Src == "" && strings.HasPrefix(t.Type, "text/") {
+		return html.EscapeString(strings.TrimSpace(t.Text))
+	}
+	return ""
+}
+
+// plain returns the content of t as plain text.
+func (t atomText) plain() string {
+	if t.Type == "" || t.Type == "text" {
+		return strings.TrimSpace(t.Text)
+	}
+	return feed.TextContent(t.html())
+}
+
+func (doc *atomDocument) feed() *feed.Feed {
+	f := &feed.Feed{
+		Title:   doc.Title.plain(),
+		URL:     alternateLink(doc.Links),
+		Summary: doc.Subtitle.plain(),
+		Photo:   doc.Logo,
+		Author:  atomAuthor(doc.Authors),
+	}
+	if f.Photo == "" {
+		f.Photo = doc.Icon
+	}
+
+	for _, ae := range doc.Entries {
+		e := &feed.Entry{
+			ID:          ae.ID,
+			URL:         alternateLink(ae.Links),
+			Title:       ae.Title.plain(),
+			Summary:     ae.Summary.plain(),
+			ContentHTML: ae.Content.html(),
+			Published:   parseTime(ae.Published),
+			Updated:     parseTime(ae.Updated),
+			Author:      atomAuthor(ae.Authors),
+			Attachments: ae.attachments(),
+		}
+		for _, l := range ae.Links {
+			if l.Rel == "enclosure" && l.Href != "" {
+				e.Attachments = append(e.Attachments, attachment(l.Href, l.Type))
+			}
+		}
+		for _, c := range ae.Categories {
+			if c.Term != "" {
+				e.Categories = append(e.Categories, c.Term)
+			}
+		}
+		e.InReplyTo = inReplyTo(ae.InReplyTo)
+		f.Entries = append(f.Entries, e)
+	}
+	return f
+}
+
+// alternateLink returns the URL of the first alternate link in links.
+func alternateLink(links []atomLink) string {
+	for _, l := range links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+// atomAuthor returns the first author in authors, or nil if there are none.
+func atomAuthor(authors []atomPerson) *feed.Person {
+	for _, a := range authors {
+		if a.Name != "" || a.URI != "" {
+			return &feed.Person{Name: a.Name, URL: a.URI, Email: a.Email}
+		}
+	}
+	return nil
+}
+
+type rssDocument struct {
+	Channel rssDocumentChannel `xml:"channel"`
+}
+
+type rssDocumentChannel struct {
+	Title       []rssElement      `xml:"title"`
+	Link        []rssElement      `xml:"link"`
+	Description []rssElement      `xml:"description"`
+	Image       rssImage          `xml:"image"`
+	Creator     string            `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Items       []rssDocumentItem `xml:"item"`
+}
+
+type rssDocumentItem struct {
+	Title       []rssElement    `xml:"title"`
+	Link        []rssElement    `xml:"link"`
+	Description []rssElement    `xml:"description"`
+	Content     string          `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Author      string          `xml:"author"`
+	Creator     string          `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Date        string          `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Categories  []string        `xml:"category"`
+	Enclosures  []rssEnclosure  `xml:"enclosure"`
+	GUID        rssDocumentGUID `xml:"guid"`
+	PubDate     string          `xml:"pubDate"`
+	InReplyTo   []threadReplyTo `xml:"http://purl.org/syndication/thread/1.0 in-reply-to"`
+	media
+}
+
+// rssElement is an RSS element whose name is also used by common extensions,
+// such as the Atom link element.
+type rssElement struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+// rssValue returns the trimmed value of the first element in elems without a
+// namespace.
+func rssValue(elems []rssElement) string {
+	for _, e := range elems {
+		if e.XMLName.Space == "" {
+			return strings.TrimSpace(e.Value)
+		}
+	}
+	return ""
+}
+
+type rssDocumentGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func (ch *rssDocumentChannel) feed() *feed.Feed {
+	f := &feed.Feed{
+		Title:   rssValue(ch.Title),
+		URL:     rssValue(ch.Link),
+		Summary: rssValue(ch.Description),
+		Photo:   strings.TrimSpace(ch.Image.URL),
+	}
+	if ch.Creator != "" {
+		f.Author = &feed.Person{Name: strings.TrimSpace(ch.Creator)}
+	}
+
+	for _, item := range ch.Items {
+		e := &feed.Entry{
+			URL:         rssValue(item.Link),
+			Title:       rssValue(item.Title),
+			ContentHTML: strings.TrimSpace(item.Content),
+			Published:   parseTime(item.PubDate),
+			Author:      rssAuthor(item.Author, item.Creator),
+			Categories:  item.Categories,
+			Attachments: item.attachments(),
+		}
+		if desc := rssValue(item.Description); e.ContentHTML == "" {
+			e.ContentHTML = desc
+		} else {
+			e.Summary = feed.TextContent(desc)
+		}
+		if e.Published.IsZero() {
+			e.Published = parseTime(item.Date)
+		}
+
+		guid := strings.TrimSpace(item.GUID.Value)
+		e.ID = guid
+		if guid == "" {
+			e.ID = e.URL
+		} else if e.URL == "" && item.GUID.IsPermaLink != "false" {
+			e.URL = guid
+		}
+
+		for _, enc := range item.Enclosures {
+			if enc.URL != "" {
+				e.Attachments = append(e.Attachments, attachment(enc.URL, enc.Type))
+			}
+		}
+		e.InReplyTo = inReplyTo(item.InReplyTo)
+		f.Entries = append(f.Entries, e)
+	}
+	return f
+}
+
+// rssAuthor returns the author of an RSS item from its author element, which
+// is an email address with an optional name, or its dc:creator element.
+func rssAuthor(author, creator string) *feed.Person {
+	var p feed.Person
+	if author = strings.TrimSpace(author); author != "" {
+		if addr, err := mail.ParseAddress(author); err == nil {
+			p.Name, p.Email = addr.Name, addr.Address
+		} else {
+			p.Name = author
+		}
+	}
+	if creator = strings.TrimSpace(creator); creator != "" {
+		p.Name = creator
+	}
+	if p == (feed.Person{}) {
+		return nil
+	}
+	return &p
+}
+
+// media contains the Media RSS elements of an Atom entry or RSS item.
+type media struct {
+	MediaContent   []mediaContent `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaThumbnail []mediaContent `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	MediaGroup     []media        `xml:"http://search.yahoo.com/mrss/ group"`
+}
+
+type mediaContent struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Medium string `xml:"medium,attr"`
+}
+
+// attachments returns the media content and thumbnails in m.
+func (m media) attachments() []feed.Attachment {
+	var attachments []feed.Attachment
+	for _, c := range m.MediaContent {
+		if c.URL == "" {
+			continue
+		}
+		t := c.Type
+		if t == "" && c.Medium != "" {
+			t = c.Medium + "/*"
+		}
+		attachments = append(attachments, attachment(c.URL, t))
+	}
+	for _, c := range m.MediaThumbnail {
+		if c.URL != "" {
+			attachments = append(attachments, attachment(c.URL, "image/*"))
+		}
+	}
+	for _, g := range m.MediaGroup {
+		attachments = append(attachments, g.attachments()...)
+	}
+	return attachments
+}
+
+// attachment returns an attachment for u with the specified MIME type.  If
+// the type is empty or a wildcard such as "image/*", the type implied by the
+// file extension of u is used instead where possible.
+func attachment(u, mimeType string) feed.Attachment {
+	u = strings.TrimSpace(u)
+	if mimeType == "" {
+		mimeType = feed.MediaType(u)
+	} else if major, ok := strings.CutSuffix(mimeType, "/*"); ok {
+		if t := feed.MediaType(u); strings.HasPrefix(t, major+"/") {
+			mimeType = t
+		}
+	}
+	return feed.Attachment{URL: u, MIMEType: mimeType}
+}
+
+// inReplyTo returns the URLs of the posts that replies are in reply to.
+func inReplyTo(replies []threadReplyTo) []string {
+	var urls []string
+	for _, r := range replies {
+		if u := r.Href; u != "" {
+			urls = append(urls, u)
+		} else if r.Ref != "" {
+			urls = append(urls, r.Ref)
+		}
+	}
+	return urls
+}
+
+// timeLayouts are the layouts of dates in RSS and Atom feeds, including
+// common variations of RFC 822 dates.
+var timeLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 2006 15:04 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+}
+
+// parseTime parses a date from a feed, returning the zero time if it cannot
+// be parsed.
+func parseTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
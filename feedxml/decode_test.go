@@ -0,0 +1,247 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package feedxml
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"willnorris.com/go/microformats"
+)
+
+// ignoreUnexported ignores the parser's internal state when comparing
+// microformats.
+var ignoreUnexported = cmpopts.IgnoreUnexported(microformats.Microformat{})
+
+func TestDecode(t *testing.T) {
+	alice := &microformats.Microformat{
+		Type: []string{"h-card"},
+		Properties: map[string][]any{
+			"name":  {"Alice"},
+			"url":   {"https://example.com/"},
+			"email": {"mailto:alice@example.com"},
+		},
+		Value: "Alice",
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  *microformats.Microformat
+	}{
+		{
+			name: "atom",
+			input: `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:thr="http://purl.org/syndication/thread/1.0">
+  <title type="html">Blog &amp;amp; more</title>
+  <subtitle>Posts by Alice</subtitle>
+  <link rel="self" href="/feed.atom"/>
+  <link href="/blog"/>
+  <id>https://example.com/blog</id>
+  <updated>2024-05-02T00:00:00Z</updated>
+  <author><name>Alice</name><uri>/</uri><email>alice@example.com</email></author>
+  <icon>/icon.png</icon>
+  <entry>
+    <id>https://example.com/1</id>
+    <title>Title</title>
+    <link rel="alternate" href="https://example.com/1"/>
+    <link rel="enclosure" type="audio/mpeg" href="/a.mp3"/>
+    <link rel="enclosure" href="/b.pdf"/>
+    <published>2024-05-01T10:00:00-07:00</published>
+    <updated>2024-05-01T10:00:00-07:00</updated>
+    <category term="go"/>
+    <summary type="html">&lt;p&gt;Short&lt;/p&gt;</summary>
+    <content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Some <b>long</b> content</p></div></content>
+  </entry>
+  <entry>
+    <id>tag:example.com,2024:2</id>
+    <title>A note</title>
+    <updated>2024-05-02T00:00:00Z</updated>
+    <author><name>Bob</name></author>
+    <content>A note &lt;3</content>
+    <thr:in-reply-to ref="tag:other.example,2024:1" href="https://other.example/post"/>
+  </entry>
+</feed>`,
+			want: &microformats.Microformat{
+				Type: []string{"h-feed"},
+				Properties: map[string][]any{
+					"name":    {"Blog & more"},
+					"url":     {"https://example.com/blog"},
+					"summary": {"Posts by Alice"},
+					"photo":   {"https://example.com/icon.png"},
+					"author":  {alice},
+				},
+				Children: []*microformats.Microformat{
+					{
+						Type: []string{"h-entry"},
+						Properties: map[string][]any{
+							"name":      {"Title"},
+							"url":       {"https://example.com/1"},
+							"summary":   {"Short"},
+							"content":   {map[string]string{"html": "<p>Some <b>long</b> content</p>", "value": "Some long content"}},
+							"published": {"2024-05-01 10:00:00-0700"},
+							"updated":   {"2024-05-01 10:00:00-0700"},
+							"category":  {"go"},
+							"audio":     {"https://example.com/a.mp3"},
+						},
+					},
+					{
+						Type: []string{"h-entry"},
+						Properties: map[string][]any{
+							"name":        {"A note"},
+							"uid":         {"tag:example.com,2024:2"},
+							"content":     {map[string]string{"html": "A note &lt;3", "value": "A note <3"}},
+							"updated":     {"2024-05-02 00:00:00Z"},
+							"author":      {&microformats.Microformat{Type: []string{"h-card"}, Properties: map[string][]any{"name": {"Bob"}}, Value: "Bob"}},
+							"in-reply-to": {"https://other.example/post"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "rss",
+			input: `<?xml version="1.0" encoding="ISO-8859-1"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:content="http://purl.org/rss/1.0/modules/content/"
+  xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:media="http://search.yahoo.com/mrss/">
+  <channel>
+    <title>Blog</title>
+    <link>https://example.com/blog</link>
+    <atom:link rel="self" href="https://example.com/feed.rss"/>
+    <description>Caf` + "\xe9" + ` posts</description>
+    <image><url>https://example.com/logo.png</url><title>Blog</title><link>https://example.com/blog</link></image>
+    <item>
+      <title>Title</title>
+      <link>https://example.com/1</link>
+      <description>Short &lt;b&gt;summary&lt;/b&gt;</description>
+      <content:encoded><![CDATA[<p>Some <b>long</b> content</p>]]></content:encoded>
+      <author>alice@example.com (Alice)</author>
+      <category>go</category>
+      <category>mf2</category>
+      <guid>https://example.com/1</guid>
+      <pubDate>Wed, 1 May 2024 10:00:00 -0700</pubDate>
+      <enclosure url="https://example.com/v.mp4" length="100" type="video/mp4"/>
+      <media:group>
+        <media:content url="https://example.com/a.jpg" medium="image"/>
+        <media:thumbnail url="https://example.com/thumb"/>
+      </media:group>
+    </item>
+    <item>
+      <description>A note</description>
+      <dc:creator>Bob</dc:creator>
+      <dc:date>2024-05-02T00:00:00Z</dc:date>
+      <guid isPermaLink="false">note-2</guid>
+    </item>
+  </channel>
+</rss>`,
+			want: &microformats.Microformat{
+				Type: []string{"h-feed"},
+				Properties: map[string][]any{
+					"name":    {"Blog"},
+					"url":     {"https://example.com/blog"},
+					"summary": {"Café posts"},
+					"photo":   {"https://example.com/logo.png"},
+				},
+				Children: []*microformats.Microformat{
+					{
+						Type: []string{"h-entry"},
+						Properties: map[string][]any{
+							"name":      {"Title"},
+							"url":       {"https://example.com/1"},
+							"summary":   {"Short summary"},
+							"content":   {map[string]string{"html": "<p>Some <b>long</b> content</p>", "value": "Some long content"}},
+							"published": {"2024-05-01 10:00:00-0700"},
+							"author": {&microformats.Microformat{
+								Type:       []string{"h-card"},
+								Properties: map[string][]any{"name": {"Alice"}, "email": {"mailto:alice@example.com"}},
+								Value:      "Alice",
+							}},
+							"category": {"go", "mf2"},
+							"photo":    {"https://example.com/a.jpg", "https://example.com/thumb"},
+							"video":    {"https://example.com/v.mp4"},
+						},
+					},
+					{
+						Type: []string{"h-entry"},
+						Properties: map[string][]any{
+							"uid":       {"note-2"},
+							"content":   {map[string]string{"html": "A note", "value": "A note"}},
+							"published": {"2024-05-02 00:00:00Z"},
+							"author":    {&microformats.Microformat{Type: []string{"h-card"}, Properties: map[string][]any{"name": {"Bob"}}, Value: "Bob"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	base, _ := url.Parse("https://example.com/feed")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Decode(strings.NewReader(tt.input), base)
+			if err != nil {
+				t.Fatalf("Decode() returned error: %v", err)
+			}
+			if len(data.Items) != 1 {
+				t.Fatalf("Decode() returned %d items, want 1", len(data.Items))
+			}
+			if diff := cmp.Diff(tt.want, data.Items[0], ignoreUnexported); diff != "" {
+				t.Errorf("Decode() differs:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", "feedxml: EOF"},
+		// HTML pages are rejected rather than parsed as malformed feeds
+		{"<html><body>not a feed</body></html>", `unsupported feed element "html"`},
+		// RSS 1.0 (RDF) feeds are not supported
+		{`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"></rdf:RDF>`, `unsupported feed element "RDF"`},
+		{"<rss><channel><item></channel></rss>", "feedxml: XML syntax error"},
+	}
+	for _, tt := range tests {
+		_, err := Decode(strings.NewReader(tt.input), nil)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Decode(%q) returned error %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestDecode_RoundTrip(t *testing.T) {
+	data := parse(t, feedHTML)
+	for name, encode := range map[string]func(io.Writer, *microformats.Data) error{"Atom": Atom, "RSS": RSS} {
+		var b strings.Builder
+		if err := encode(&b, data); err != nil {
+			t.Fatalf("%s() returned error: %v", name, err)
+		}
+		got, err := Decode(strings.NewReader(b.String()), nil)
+		if err != nil {
+			t.Fatalf("Decode(%s()) returned error: %v", name, err)
+		}
+
+		entries := got.Items[0].Children
+		if len(entries) != 2 {
+			t.Fatalf("Decode(%s()) returned %d entries, want 2", name, len(entries))
+		}
+		if diff := cmp.Diff([]any{"Title"}, entries[0].Properties["name"]); diff != "" {
+			t.Errorf("Decode(%s()) entry name differs:\n%s", name, diff)
+		}
+		wantContent := []any{map[string]string{"html": "<p>Some <b>long</b> content</p>", "value": "Some long content"}}
+		if diff := cmp.Diff(wantContent, entries[0].Properties["content"]); diff != "" {
+			t.Errorf("Decode(%s()) entry content differs:\n%s", name, diff)
+		}
+		if diff := cmp.Diff([]any{"https://other.example/post"}, entries[1].Properties["in-reply-to"]); diff != "" {
+			t.Errorf("Decode(%s()) entry in-reply-to differs:\n%s", name, diff)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+// Package feedxml converts an h-feed to Atom 1.0 (RFC 4287) and RSS 2.0.
+//
+// The feed is the first h-feed in the parsed data, or else an implied feed
+// of its top-level h-entry items.  Entries without an author use the author
+// of the feed.  Datetimes are interpreted using the same rules as the
+// microformats parser, with times that lack a timezone treated as UTC.
+// Photos, videos, and audio are included as enclosures, and replies are
+// identified using the Atom threading extensions (RFC 4685).
+package feedxml
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+
+	"willnorris.com/go/microformats"
+	"willnorris.com/go/microformats/internal/feed"
+)
+
+// ErrNoFeed is returned when data contains neither an h-feed nor any
+// top-level h-entry items.
+var ErrNoFeed = feed.ErrNoFeed
+
+// Generator is the name of the generator written to feeds.
+const Generator = "willnorris.com/go/microformats"
+
+// now returns the current time; it can be overridden in tests.
+var now = time.Now
+
+type atomFeed struct {
+	XMLName   xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	ID        string       `xml:"id"`
+	Title     string       `xml:"title"`
+	Subtitle  string       `xml:"subtitle,omitempty"`
+	Updated   string       `xml:"updated"`
+	Links     []atomLink   `xml:"link"`
+	Author    *atomPerson  `xml:"author,omitempty"`
+	Generator string       `xml:"generator"`
+	Logo      string       `xml:"logo,omitempty"`
+	Entries   []*atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID         string          `xml:"id"`
+	Title      string          `xml:"title"`
+	Updated    string          `xml:"updated"`
+	Published  string          `xml:"published,omitempty"`
+	Links      []atomLink      `xml:"link"`
+	Author     *atomPerson     `xml:"author,omitempty"`
+	Categories []atomCategory  `xml:"category"`
+	Summary    string          `xml:"summary,omitempty"`
+	Content    *atomContent    `xml:"content"`
+	InReplyTo  []threadReplyTo `xml:"http://purl.org/syndication/thread/1.0 in-reply-to"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomPerson struct {
+	Name  string `xml:"name"`
+	URI   string `xml:"uri,omitempty"`
+	Email string `xml:"email,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// threadReplyTo is an in-reply-to element from the Atom threading
+// extensions, which is used in both Atom and RSS feeds.
+type threadReplyTo struct {
+	Ref  string `xml:"ref,attr"`
+	Href string `xml:"href,attr,omitempty"`
+}
+
+// Atom writes the feed in data to w as an Atom 1.0 feed.  It returns
+// ErrNoFeed if data does not include a feed.
+func Atom(w io.Writer, data *microformats.Data) error {
+	f, err := feed.Discover(data)
+	if err != nil {
+		return err
+	}
+
+	updated := f.Updated()
+	if updated.IsZero() {
+		updated = now()
+	}
+	af := &atomFeed{
+		ID:        f.URL,
+		Title:     f.Title,
+		Subtitle:  f.Summary,
+		Updated:   atomTime(updated),
+		Author:    newAtomPerson(f.Author),
+		Generator: Generator,
+		Logo:      f.Photo,
+	}
+	if af.ID == "" {
+		af.ID = feedID(f)
+	}
+	if f.URL != "" {
+		af.Links = append(af.Links, atomLink{Rel: "alternate", Type: "text/html", Href: f.URL})
+	}
+
+	for _, e := range f.Entries {
+		ae := &atomEntry{
+			ID:        e.ID,
+			Title:     e.Title,
+			Updated:   atomTime(e.LastModified()),
+			Published: atomTime(e.Published),
+			Author:    newAtomPerson(e.Author),
+			Summary:   e.Summary,
+		}
+		if ae.Updated == "" {
+			ae.Updated = af.Updated
+		}
+		if e.URL != "" {
+			ae.Links = append(ae.Links, atomLink{Rel: "alternate", Type: "text/html", Href: e.URL})
+		}
+		for _, a := range e.Attachments {
+			ae.Links = append(ae.Links, atomLink{Rel: "enclosure", Type: a.MIMEType, Href: a.URL})
+		}
+		for _, c := range e.Categories {
+			ae.Categories = append(ae.Categories, atomCategory{Term: c})
+		}
+		if e.ContentHTML != "" {
+			ae.Content = &atomContent{Type: "html", Body: e.ContentHTML}
+		}
+		for _, u := range e.InReplyTo {
+			ae.InReplyTo = append(ae.InReplyTo, threadReplyTo{Ref: u, Href: u})
+		}
+		af.Entries = append(af.Entries, ae)
+	}
+
+	return encode(w, af)
+}
+
+type rssFeed struct {
+	XMLName xml.Name    `xml:"rss"`
+	Version string      `xml:"version,attr"`
+	Channel *rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string     `xml:"title"`
+	Link          string     `xml:"link"`
+	Description   string     `xml:"description"`
+	LastBuildDate string     `xml:"lastBuildDate,omitempty"`
+	Generator     string     `xml:"generator"`
+	Image         *rssImage  `xml:"image"`
+	Items         []*rssItem `xml:"item"`
+}
+
+type rssImage struct {
+	URL   string `xml:"url"`
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+}
+
+type rssItem struct {
+	Title       string          `xml:"title,omitempty"`
+	Link        string          `xml:"link,omitempty"`
+	Description string          `xml:"description,omitempty"`
+	Creator     string          `xml:"http://purl.org/dc/elements/1.1/ creator,omitempty"`
+	Categories  []string        `xml:"category"`
+	Enclosure   *rssEnclosure   `xml:"enclosure"`
+	GUID        *rssGUID        `xml:"guid"`
+	PubDate     string          `xml:"pubDate,omitempty"`
+	InReplyTo   []threadReplyTo `xml:"http://purl.org/syndication/thread/1.0 in-reply-to"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length string `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// RSS writes the feed in data to w as an RSS 2.0 feed.  It returns ErrNoFeed
+// if data does not include a feed.  Because RSS allows only one enclosure per
+// item, only the first photo, video, or audio file of each entry is
+// included.
+func RSS(w io.Writer, data *microformats.Data) error {
+	f, err := feed.Discover(data)
+	if err != nil {
+		return err
+	}
+
+	ch := &rssChannel{
+		Title:         f.Title,
+		Link:          f.URL,
+		Description:   f.Summary,
+		LastBuildDate: rssTime(f.Updated()),
+		Generator:     Generator,
+	}
+	if ch.Title == "" {
+		ch.Title = f.URL
+	}
+	if ch.Description == "" {
+		ch.Description = ch.Title
+	}
+	if f.Photo != "" && f.URL != "" {
+		ch.Image = &rssImage{URL: f.Photo, Title: ch.Title, Link: f.URL}
+	}
+
+	for _, e := range f.Entries {
+		item := &rssItem{
+			Title:       e.Title,
+			Link:        e.URL,
+			Description: e.ContentHTML,
+			Categories:  e.Categories,
+			PubDate:     rssTime(e.Published),
+			GUID:        &rssGUID{IsPermaLink: e.ID == e.URL, Value: e.ID},
+		}
+		if item.Description == "" {
+			item.Description = e.Summary
+		}
+		if e.Author != nil {
+			item.Creator = e.Author.Name
+			if item.Creator == "" {
+				item.Creator = e.Author.URL
+			}
+		}
+		if len(e.Attachments) > 0 {
+			a := e.Attachments[0]
+			// the length is required, but is not known without fetching the
+			// file, so 0 is used as recommended by the RSS Advisory Board
+			item.Enclosure = &rssEnclosure{URL: a.URL, Length: "0", Type: a.MIMEType}
+		}
+		for _, u := range e.InReplyTo {
+			item.InReplyTo = append(item.InReplyTo, threadReplyTo{Ref: u, Href: u})
+		}
+		ch.Items = append(ch.Items, item)
+	}
+
+	return encode(w, &rssFeed{Version: "2.0", Channel: ch})
+}
+
+// encode writes v to w as an indented XML document.
+func encode(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// feedID returns an identifier for a feed without a URL, derived from the
+// identifiers of its entries.
+func feedID(f *feed.Feed) string {
+	ids := make([]string, len(f.Entries))
+	for i, e := range f.Entries {
+		ids[i] = e.ID
+	}
+	return feed.HashID(ids...)
+}
+
+// newAtomPerson returns the Atom person construct for p, or nil if p is nil.
+func newAtomPerson(p *feed.Person) *atomPerson {
+	if p == nil {
+		return nil
+	}
+	ap := &atomPerson{Name: p.Name, URI: p.URL, Email: p.Email}
+	if ap.Name == "" {
+		// name is required in Atom
+		ap.Name = p.URL
+	}
+	return ap
+}
+
+// atomTime formats t as an RFC 3339 timestamp, or returns an empty string if
+// t is the zero time.
+func atomTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// rssTime formats t as an RFC 822 timestamp, or returns an empty string if t
+// is the zero time.
+func rssTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC1123Z)
+}
@@ -0,0 +1,150 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package feedxml
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"willnorris.com/go/microformats"
+)
+
+const feedHTML = `<main class="h-feed"><h1 class="p-name">Blog &amp; more</h1><a class="u-url" href="/blog">x</a>
+  <a class="p-author h-card" href="/">Alice</a>
+  <article class="h-entry"><h2 class="p-name">Title</h2><a class="u-url" href="/1">x</a>
+    <time class="dt-published" datetime="2024-05-01 10:00:00-07:00">May 1</time>
+    <div class="e-content"><p>Some <b>long</b> content</p></div>
+    <span class="p-category">go</span>
+    <img class="u-photo" src="/a.jpg" alt="A"><audio class="u-audio" src="/a.mp3"></audio></article>
+  <article class="h-entry"><p class="p-name e-content">A note</p><span class="p-uid">note-2</span>
+    <a class="u-in-reply-to" href="https://other.example/post">re</a>
+    <time class="dt-published" datetime="2024-05-02">May 2</time></article>
+</main>`
+
+func parse(t *testing.T, html string) *microformats.Data {
+	t.Helper()
+	base, _ := url.Parse("https://example.com/")
+	return microformats.Parse(strings.NewReader(html), base)
+}
+
+func TestAtom(t *testing.T) {
+	var b strings.Builder
+	if err := Atom(&b, parse(t, feedHTML)); err != nil {
+		t.Fatalf("Atom() returned error: %v", err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <id>https://example.com/blog</id>
+  <title>Blog &amp; more</title>
+  <updated>2024-05-02T00:00:00Z</updated>
+  <link rel="alternate" type="text/html" href="https://example.com/blog"></link>
+  <author>
+    <name>Alice</name>
+    <uri>https://example.com/</uri>
+  </author>
+  <generator>willnorris.com/go/microformats</generator>
+  <entry>
+    <id>https://example.com/1</id>
+    <title>Title</title>
+    <updated>2024-05-01T10:00:00-07:00</updated>
+    <published>2024-05-01T10:00:00-07:00</published>
+    <link rel="alternate" type="text/html" href="https://example.com/1"></link>
+    <link rel="enclosure" type="image/jpeg" href="https://example.com/a.jpg"></link>
+    <link rel="enclosure" type="audio/mpeg" href="https://example.com/a.mp3"></link>
+    <author>
+      <name>Alice</name>
+      <uri>https://example.com/</uri>
+    </author>
+    <category term="go"></category>
+    <content type="html">&lt;p&gt;Some &lt;b&gt;long&lt;/b&gt; content&lt;/p&gt;</content>
+  </entry>
+  <entry>
+    <id>note-2</id>
+    <title></title>
+    <updated>2024-05-02T00:00:00Z</updated>
+    <published>2024-05-02T00:00:00Z</published>
+    <author>
+      <name>Alice</name>
+      <uri>https://example.com/</uri>
+    </author>
+    <content type="html">A note</content>
+    <in-reply-to xmlns="http://purl.org/syndication/thread/1.0" ref="https://other.example/post" href="https://other.example/post"></in-reply-to>
+  </entry>
+</feed>
+`
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("Atom() differs:\n%s", diff)
+	}
+}
+
+func TestAtom_Implied(t *testing.T) {
+	now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	var b strings.Builder
+	if err := Atom(&b, parse(t, `<p class="h-entry"><span class="p-author">Bob</span> <span class="e-content">hi</span></p>`)); err != nil {
+		t.Fatalf("Atom() returned error: %v", err)
+	}
+	for _, want := range []string{
+		"<updated>2024-01-02T03:04:05Z</updated>",
+		"<name>Bob</name>",
+		"<id>urn:sha256:",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("Atom() output does not contain %q:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestRSS(t *testing.T) {
+	var b strings.Builder
+	if err := RSS(&b, parse(t, feedHTML)); err != nil {
+		t.Fatalf("RSS() returned error: %v", err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+  <channel>
+    <title>Blog &amp; more</title>
+    <link>https://example.com/blog</link>
+    <description>Blog &amp; more</description>
+    <lastBuildDate>Thu, 02 May 2024 00:00:00 +0000</lastBuildDate>
+    <generator>willnorris.com/go/microformats</generator>
+    <item>
+      <title>Title</title>
+      <link>https://example.com/1</link>
+      <description>&lt;p&gt;Some &lt;b&gt;long&lt;/b&gt; content&lt;/p&gt;</description>
+      <creator xmlns="http://purl.org/dc/elements/1.1/">Alice</creator>
+      <category>go</category>
+      <enclosure url="https://example.com/a.jpg" length="0" type="image/jpeg"></enclosure>
+      <guid isPermaLink="true">https://example.com/1</guid>
+      <pubDate>Wed, 01 May 2024 10:00:00 -0700</pubDate>
+    </item>
+    <item>
+      <description>A note</description>
+      <creator xmlns="http://purl.org/dc/elements/1.1/">Alice</creator>
+      <guid isPermaLink="false">note-2</guid>
+      <pubDate>Thu, 02 May 2024 00:00:00 +0000</pubDate>
+      <in-reply-to xmlns="http://purl.org/syndication/thread/1.0" ref="https://other.example/post" href="https://other.example/post"></in-reply-to>
+    </item>
+  </channel>
+</rss>
+`
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("RSS() differs:\n%s", diff)
+	}
+}
+
+func TestNoFeed(t *testing.T) {
+	data := parse(t, `<div class="h-card">Alice</div>`)
+	for name, encode := range map[string]func(io.Writer, *microformats.Data) error{"Atom": Atom, "RSS": RSS} {
+		if err := encode(new(strings.Builder), data); !errors.Is(err, ErrNoFeed) {
+			t.Errorf("%s() returned error %v, want %v", name, err, ErrNoFeed)
+		}
+	}
+}
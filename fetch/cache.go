@@ -0,0 +1,153 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package fetch
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Cache stores fetched responses.  Implementations must be safe for
+// concurrent use.
+type Cache interface {
+	// Get returns the value stored for key, and whether it was present.
+	Get(key string) ([]byte, bool)
+
+	// Set stores value for key.
+	Set(key string, value []byte)
+
+	// Delete removes the value for key.
+	Delete(key string)
+}
+
+// MemoryCache is an in-memory Cache which holds a limited number of entries,
+// discarding the least recently used entry when full.
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+// memoryEntry is an element in MemoryCache's list.
+type memoryEntry struct {
+	key   string
+	value []byte
+}
+
+// NewMemoryCache returns a MemoryCache that holds up to maxEntries entries.
+// If maxEntries is zero or less, the number of entries is unlimited.
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// Get implements Cache.
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		return el.Value.(*memoryEntry).value, true
+	}
+	return nil, false
+}
+
+// Set implements Cache.
+func (c *MemoryCache) Set(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		el.Value.(*memoryEntry).value = value
+		return
+	}
+	c.items[key] = c.ll.PushFront(&memoryEntry{key, value})
+	if c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+		if el := c.ll.Back(); el != nil {
+			c.ll.Remove(el)
+			delete(c.items, el.Value.(*memoryEntry).key)
+		}
+	}
+}
+
+// Delete implements Cache.
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.ll.Remove(el)
+		delete(c.items, key)
+	}
+}
+
+// Len returns the number of entries in the cache.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// DiskCache is a Cache which stores entries as files in a directory.
+type DiskCache struct {
+	// Dir is the directory entries are stored in.  It is created if it
+	// does not exist.
+	Dir string
+}
+
+// NewDiskCache returns a DiskCache that stores entries in dir.
+func NewDiskCache(dir string) *DiskCache {
+	return &DiskCache{Dir: dir}
+}
+
+// path returns the file path entries for key are stored at.
+func (c *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:]))
+}
+
+// Get implements Cache.
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	b, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// Set implements Cache.  Errors writing to disk are ignored, which results
+// in the entry not being cached.
+func (c *DiskCache) Set(key string, value []byte) {
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return
+	}
+
+	// write to a temporary file and rename to avoid concurrent readers
+	// seeing a partially written entry
+	f, err := os.CreateTemp(c.Dir, "tmp-")
+	if err != nil {
+		return
+	}
+	_, werr := f.Write(value)
+	cerr := f.Close()
+	if werr != nil || cerr != nil {
+		_ = os.Remove(f.Name())
+		return
+	}
+	if err := os.Rename(f.Name(), c.path(key)); err != nil {
+		_ = os.Remove(f.Name())
+	}
+}
+
+// Delete implements Cache.
+func (c *DiskCache) Delete(key string) {
+	_ = os.Remove(c.path(key))
+}
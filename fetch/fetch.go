@@ -0,0 +1,353 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+// Package fetch retrieves web pages and parses their microformats.
+//
+// Client is the default Fetcher implementation.  It follows redirects, limits
+// the size of response bodies, and honors HTTP caching headers (ETag,
+// Last-Modified, Cache-Control, and Expires) when configured with a Cache.
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"willnorris.com/go/microformats"
+)
+
+// DefaultMaxBodySize is the maximum size of response bodies used by Client if
+// MaxBodySize is not set.
+const DefaultMaxBodySize = 10 << 20
+
+// ErrBodyTooLarge is returned if a response body exceeds the maximum size.
+var ErrBodyTooLarge = errors.New("fetch: response body too large")
+
+// Fetcher retrieves the page at a URL and parses its microformats.
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) (*Response, error)
+}
+
+// Response is a fetched page.
+type Response struct {
+	// URL is the final URL of the page, after following any redirects.
+	URL *url.URL
+
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+
+	// Data is the microformats parsed from Body.  Relative URLs are
+	// resolved against BaseURL.  It is nil if the Client has SkipParse
+	// set.
+	Data *microformats.Data
+
+	// FromCache is whether the response was served from the cache,
+	// including responses which were successfully revalidated.
+	FromCache bool
+}
+
+// Client is a Fetcher that retrieves pages over HTTP.  The zero value is
+// usable, and does not cache responses.
+type Client struct {
+	// HTTPClient is the client used to make requests.  If nil,
+	// http.DefaultClient is used.  Configure its CheckRedirect and Jar
+	// fields to control redirects and cookies.
+	HTTPClient *http.Client
+
+	// Cache stores responses for reuse.  If nil, responses are not cached.
+	Cache Cache
+
+	// Header includes additional headers to send with each request, such
+	// as User-Agent or Accept.
+	Header http.Header
+
+	// MaxBodySize is the maximum size of response bodies in bytes.  If
+	// zero, DefaultMaxBodySize is used.
+	MaxBodySize int64
+
+	// Timeout limits the time taken to fetch each page, including
+	// redirects and reading the response body.  If zero, no timeout is
+	// applied beyond any set on the context or HTTPClient.
+	Timeout time.Duration
+
+	// ParseOptions are the options used to parse the microformats of
+	// fetched pages.
+	ParseOptions microformats.ParseOptions
+
+	// SkipParse disables parsing the microformats of fetched pages, for
+	// callers which parse the body themselves or which fetch documents
+	// other than HTML.
+	SkipParse bool
+
+	// now returns the current time; it can be overridden in tests.
+	now func() time.Time
+}
+
+// entry is a response stored in a Cache.
+type entry struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	Stored     time.Time   `json:"stored"`
+
+	// RequestHeader holds the values of the request headers named by the
+	// Vary header of the response, which must match for the entry to be
+	// used.
+	RequestHeader http.Header `json:"request_header,omitempty"`
+}
+
+// Fetch retrieves the page at rawURL and parses its microformats, unless
+// SkipParse is set.  Responses with a status other than 200 OK are returned
+// along with an error.
+func (c *Client) Fetch(ctx context.Context, rawURL string) (*Response, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+
+	cached := c.cacheGet(rawURL)
+	if cached != nil && !cached.matches(req.Header) {
+		cached = nil
+	}
+	if cached != nil && c.fresh(cached) {
+		return c.response(cached, true)
+	}
+	if cached != nil {
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lm := cached.Header.Get("Last-Modified"); lm != "" {
+			req.Header.Set("If-Modified-Since", lm)
+		}
+	}
+
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		// update the stored headers with any provided in the 304 response
+		for k, v := range resp.Header {
+			cached.Header[k] = v
+		}
+		cached.Stored = c.timeNow()
+		c.cacheSet(rawURL, cached)
+		return c.response(cached, true)
+	}
+
+	maxSize := c.MaxBodySize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxBodySize
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxSize {
+		return nil, ErrBodyTooLarge
+	}
+
+	e := &entry{
+		URL:        resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+		Stored:     c.timeNow(),
+	}
+	for _, name := range varyHeaders(e.Header) {
+		if e.RequestHeader == nil {
+			e.RequestHeader = make(http.Header)
+		}
+		e.RequestHeader[name] = req.Header.Values(name)
+	}
+	if e.StatusCode == http.StatusOK && c.cacheable(e.Header) {
+		c.cacheSet(rawURL, e)
+	}
+
+	r, err := c.response(e, false)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK {
+		return r, fmt.Errorf("fetch: unexpected response status: %s", resp.Status)
+	}
+	return r, nil
+}
+
+// BaseURL returns the URL that relative URLs in r are resolved against.  This
+// is the Content-Location of the response if present, or else its URL.
+func (r *Response) BaseURL() *url.URL {
+	if loc := r.Header.Get("Content-Location"); loc != "" {
+		if l, err := r.URL.Parse(loc); err == nil {
+			return l
+		}
+	}
+	return r.URL
+}
+
+// response builds a Response from e, parsing its microformats unless
+// SkipParse is set.
+func (c *Client) response(e *entry, fromCache bool) (*Response, error) {
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return nil, err
+	}
+	r := &Response{
+		URL:        u,
+		StatusCode: e.StatusCode,
+		Header:     e.Header,
+		Body:       e.Body,
+		FromCache:  fromCache,
+	}
+	if !c.SkipParse {
+		r.Data = c.ParseOptions.ParseWithContentType(bytes.NewReader(e.Body), e.Header.Get("Content-Type"), r.BaseURL())
+	}
+	return r, nil
+}
+
+func (c *Client) timeNow() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+// cacheGet returns the cached entry for key, or nil if none is present.
+func (c *Client) cacheGet(key string) *entry {
+	if c.Cache == nil {
+		return nil
+	}
+	b, ok := c.Cache.Get(key)
+	if !ok {
+		return nil
+	}
+	e := new(entry)
+	if err := json.Unmarshal(b, e); err != nil {
+		c.Cache.Delete(key)
+		return nil
+	}
+	return e
+}
+
+// cacheSet stores e in the cache with the specified key.
+func (c *Client) cacheSet(key string, e *entry) {
+	if c.Cache == nil {
+		return
+	}
+	if b, err := json.Marshal(e); err == nil {
+		c.Cache.Set(key, b)
+	}
+}
+
+// fresh returns whether e can be used without revalidation.
+func (c *Client) fresh(e *entry) bool {
+	cc := cacheControl(e.Header)
+	if _, ok := cc["no-cache"]; ok {
+		return false
+	}
+	return c.timeNow().Sub(e.Stored) < c.lifetime(e.Header, cc)
+}
+
+// matches returns whether e can be used for a request with header h, which
+// requires the request headers named by the Vary header of e to match.
+func (e *entry) matches(h http.Header) bool {
+	for _, name := range varyHeaders(e.Header) {
+		if !slices.Equal(e.RequestHeader.Values(name), h.Values(name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// varyHeaders returns the canonical names of the request headers listed in
+// the Vary header of h.
+func varyHeaders(h http.Header) []string {
+	var names []string
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// cacheable returns whether a response with header h may be stored.
+// Responses are only stored if they can be reused or revalidated.
+func (c *Client) cacheable(h http.Header) bool {
+	cc := cacheControl(h)
+	if _, ok := cc["no-store"]; ok {
+		return false
+	}
+	if slices.Contains(varyHeaders(h), "*") {
+		// the response varies on something other than request headers
+		return false
+	}
+	return h.Get("ETag") != "" || h.Get("Last-Modified") != "" || c.lifetime(h, cc) > 0
+}
+
+// lifetime returns the freshness lifetime of a response with header h and
+// parsed Cache-Control directives cc.
+func (c *Client) lifetime(h http.Header, cc map[string]string) time.Duration {
+	if v, ok := cc["max-age"]; ok {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second
+		}
+		return 0
+	}
+	if v := h.Get("Expires"); v != "" {
+		expires, err := http.ParseTime(v)
+		if err != nil {
+			return 0
+		}
+		date, err := http.ParseTime(h.Get("Date"))
+		if err != nil {
+			date = c.timeNow()
+		}
+		return expires.Sub(date)
+	}
+	return 0
+}
+
+// cacheControl parses the Cache-Control directives in h.
+func cacheControl(h http.Header) map[string]string {
+	cc := make(map[string]string)
+	for _, v := range h.Values("Cache-Control") {
+		for _, directive := range strings.Split(v, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name != "" {
+				cc[strings.ToLower(name)] = strings.Trim(value, `"`)
+			}
+		}
+	}
+	return cc
+}
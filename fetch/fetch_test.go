@@ -0,0 +1,264 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testServer returns a server that serves an h-card page at /card, and
+// counts requests made to it.
+func testServer(t *testing.T, header http.Header) (*httptest.Server, *int) {
+	t.Helper()
+	var count int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/card", func(w http.ResponseWriter, r *http.Request) {
+		count++
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		if etag := header.Get("ETag"); etag != "" && r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<a class="h-card" href="/me">Alice</a>`)
+	})
+	mux.Handle("/redirect", http.RedirectHandler("/card", http.StatusFound))
+	mux.HandleFunc("/missing", http.NotFound)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, &count
+}
+
+func TestClient_Fetch(t *testing.T) {
+	srv, _ := testServer(t, nil)
+	c := &Client{}
+
+	resp, err := c.Fetch(context.Background(), srv.URL+"/redirect")
+	if err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+	if got, want := resp.URL.String(), srv.URL+"/card"; got != want {
+		t.Errorf("Fetch returned URL %q, want %q", got, want)
+	}
+	if len(resp.Data.Items) != 1 {
+		t.Fatalf("Fetch returned %d items, want 1", len(resp.Data.Items))
+	}
+	if got, want := resp.Data.Items[0].Properties["url"][0], srv.URL+"/me"; got != want {
+		t.Errorf("Fetch returned h-card url %q, want %q", got, want)
+	}
+	if resp.FromCache {
+		t.Errorf("Fetch returned FromCache true, want false")
+	}
+
+	resp, err = c.Fetch(context.Background(), srv.URL+"/missing")
+	if err == nil {
+		t.Errorf("Fetch of missing page did not return error")
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Fetch of missing page returned %v, want 404 response", resp)
+	}
+}
+
+func TestClient_Fetch_MaxBodySize(t *testing.T) {
+	srv, _ := testServer(t, nil)
+	c := &Client{MaxBodySize: 10}
+	if _, err := c.Fetch(context.Background(), srv.URL+"/card"); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("Fetch returned error %v, want %v", err, ErrBodyTooLarge)
+	}
+}
+
+func TestClient_Fetch_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	c := &Client{Timeout: 10 * time.Millisecond}
+	if _, err := c.Fetch(context.Background(), srv.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Fetch returned error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestClient_Fetch_Cache(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		// whether the second fetch is served from cache, and the
+		// expected number of requests made to the server
+		fromCache bool
+		requests  int
+	}{
+		{"no cache headers", http.Header{}, false, 2},
+		{"max-age", http.Header{"Cache-Control": {"max-age=60"}}, true, 1},
+		{"expired max-age", http.Header{"Cache-Control": {"max-age=0"}}, false, 2},
+		{"no-store", http.Header{"Cache-Control": {"no-store, max-age=60"}}, false, 2},
+		{"expires", http.Header{
+			"Date":    {"Mon, 01 Jan 2024 00:00:00 GMT"},
+			"Expires": {"Mon, 01 Jan 2024 01:00:00 GMT"},
+		}, true, 1},
+		{"etag", http.Header{"Etag": {`"v1"`}}, true, 2},
+		{"no-cache etag", http.Header{"Etag": {`"v1"`}, "Cache-Control": {"no-cache, max-age=60"}}, true, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, cache := range []Cache{NewMemoryCache(10), NewDiskCache(t.TempDir())} {
+				srv, count := testServer(t, tt.header)
+				c := &Client{Cache: cache}
+
+				if _, err := c.Fetch(context.Background(), srv.URL+"/card"); err != nil {
+					t.Fatalf("Fetch returned error: %v", err)
+				}
+				resp, err := c.Fetch(context.Background(), srv.URL+"/card")
+				if err != nil {
+					t.Fatalf("Fetch returned error: %v", err)
+				}
+				if resp.FromCache != tt.fromCache {
+					t.Errorf("%T: Fetch returned FromCache %t, want %t", cache, resp.FromCache, tt.fromCache)
+				}
+				if *count != tt.requests {
+					t.Errorf("%T: server received %d requests, want %d", cache, *count, tt.requests)
+				}
+				if len(resp.Data.Items) != 1 {
+					t.Errorf("%T: Fetch returned %d items, want 1", cache, len(resp.Data.Items))
+				}
+			}
+		})
+	}
+}
+
+func TestClient_Fetch_CacheExpiry(t *testing.T) {
+	srv, count := testServer(t, http.Header{"Cache-Control": {"max-age=60"}})
+	now := time.Now()
+	c := &Client{Cache: NewMemoryCache(0), now: func() time.Time { return now }}
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Fetch(context.Background(), srv.URL+"/card"); err != nil {
+			t.Fatalf("Fetch returned error: %v", err)
+		}
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := c.Fetch(context.Background(), srv.URL+"/card"); err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+	if *count != 2 {
+		t.Errorf("server received %d requests, want 2", *count)
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	c := NewMemoryCache(2)
+	c.Set("a", []byte("1"))
+	c.Set("b", []byte("2"))
+	c.Get("a") // mark a as recently used
+	c.Set("c", []byte("3"))
+
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("least recently used entry was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("Get(%q) returned no value", key)
+		}
+	}
+	if got, want := c.Len(), 2; got != want {
+		t.Errorf("Len() returned %d, want %d", got, want)
+	}
+
+	c.Delete("a")
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("Get(%q) returned value after delete", "a")
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	c := NewDiskCache(t.TempDir() + "/cache")
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("Get on empty cache returned value")
+	}
+	c.Set("a", []byte("1"))
+	if got, ok := c.Get("a"); !ok || string(got) != "1" {
+		t.Errorf("Get(%q) returned %q, %t, want %q", "a", got, ok, "1")
+	}
+	c.Delete("a")
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("Get(%q) returned value after delete", "a")
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	h := http.Header{"Cache-Control": {`max-age="60", No-Cache`, "private"}}
+	cc := cacheControl(h)
+	for k, want := range map[string]string{"max-age": "60", "no-cache": "", "private": ""} {
+		if got, ok := cc[k]; !ok || got != want {
+			t.Errorf("cacheControl(%v)[%q] = %q, %t, want %q", h, k, got, ok, want)
+		}
+	}
+	if got, want := new(Client).lifetime(h, cc), time.Minute; got != want {
+		t.Errorf("lifetime(%v) = %v, want %v", h, got, want)
+	}
+}
+
+func TestClient_Lifetime_Expires(t *testing.T) {
+	// without a Date header, the lifetime is relative to the current time
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := &Client{now: func() time.Time { return now }}
+	h := http.Header{"Expires": {"Mon, 01 Jan 2024 01:00:00 GMT"}}
+	if got, want := c.lifetime(h, cacheControl(h)), time.Hour; got != want {
+		t.Errorf("lifetime(%v) = %v, want %v", h, got, want)
+	}
+}
+
+func TestClient_Fetch_SkipParse(t *testing.T) {
+	srv, _ := testServer(t, nil)
+	c := &Client{SkipParse: true}
+	resp, err := c.Fetch(context.Background(), srv.URL+"/card")
+	if err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+	if resp.Data != nil {
+		t.Errorf("Fetch returned Data %v, want nil", resp.Data)
+	}
+	if len(resp.Body) == 0 {
+		t.Errorf("Fetch returned empty body")
+	}
+}
+
+func TestClient_Fetch_Vary(t *testing.T) {
+	tests := []struct {
+		name     string
+		vary     string
+		lang     []string // Accept-Language of each request
+		requests int
+	}{
+		{"same header", "Accept-Language", []string{"en", "en"}, 1},
+		{"different header", "accept-language", []string{"en", "fr"}, 2},
+		{"other header", "Accept-Encoding, Cookie", []string{"en", "fr"}, 1},
+		{"vary star", "*", []string{"en", "en"}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, count := testServer(t, http.Header{"Cache-Control": {"max-age=60"}, "Vary": {tt.vary}})
+			c := &Client{Cache: NewMemoryCache(0)}
+			for _, lang := range tt.lang {
+				c.Header = http.Header{"Accept-Language": {lang}}
+				if _, err := c.Fetch(context.Background(), srv.URL+"/card"); err != nil {
+					t.Fatalf("Fetch returned error: %v", err)
+				}
+			}
+			if *count != tt.requests {
+				t.Errorf("server received %d requests, want %d", *count, tt.requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultMaxRedirects is the number of redirects followed by a restricted
+// client if Restrictions.MaxRedirects is not set.
+const DefaultMaxRedirects = 10
+
+var (
+	// ErrDisallowedScheme is returned when requesting a URL whose scheme
+	// is not allowed.
+	ErrDisallowedScheme = errors.New("fetch: URL scheme not allowed")
+
+	// ErrDisallowedAddress is returned when connecting to a network
+	// address that is not allowed.
+	ErrDisallowedAddress = errors.New("fetch: network address not allowed")
+)
+
+// Restrictions limit the requests made by an http.Client, to protect against
+// server-side request forgery when fetching untrusted URLs.
+type Restrictions struct {
+	// Schemes lists the allowed URL schemes.  If empty, only http and
+	// https are allowed.
+	Schemes []string
+
+	// MaxRedirects is the maximum number of redirects to follow.  If
+	// zero, DefaultMaxRedirects is used.  If negative, redirects are not
+	// followed.
+	MaxRedirects int
+
+	// AllowAddr reports whether connections to addr are allowed.  It is
+	// checked after DNS resolution for every connection, including those
+	// made for redirects.  If nil, PublicAddr is used.
+	AllowAddr func(addr netip.Addr) bool
+}
+
+// NewRestrictedClient returns an http.Client that enforces r.  The client
+// does not use any proxy configured in the environment, since that would
+// prevent checking the address being connected to.
+func NewRestrictedClient(r Restrictions) *http.Client {
+	allow := r.AllowAddr
+	if allow == nil {
+		allow = PublicAddr
+	}
+	schemes := r.Schemes
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https"}
+	}
+	maxRedirects := r.MaxRedirects
+	if maxRedirects == 0 {
+		maxRedirects = DefaultMaxRedirects
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrDisallowedAddress, address)
+			}
+			if !allow(ap.Addr().Unmap()) {
+				return fmt.Errorf("%w: %s", ErrDisallowedAddress, ap.Addr())
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	return &http.Client{
+		Transport: &restrictedTransport{transport, schemes},
+		CheckRedirect: func(_ *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return fmt.Errorf("fetch: stopped after %d redirects", max(maxRedirects, 0))
+			}
+			return nil
+		},
+	}
+}
+
+// restrictedTransport is an http.RoundTripper that only allows requests for
+// URLs with certain schemes.
+type restrictedTransport struct {
+	base    http.RoundTripper
+	schemes []string
+}
+
+func (t *restrictedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !slices.Contains(t.schemes, strings.ToLower(req.URL.Scheme)) {
+		return nil, fmt.Errorf("%w: %q", ErrDisallowedScheme, req.URL.Scheme)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// specialPrefixes are the special-purpose address blocks which are not
+// globally reachable, from the IANA IPv4 and IPv6 Special-Purpose Address
+// Registries, along with reserved and documentation blocks.  Blocks which
+// are covered by the netip.Addr methods used by PublicAddr are omitted.
+var specialPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"),   // shared address space (carrier-grade NAT)
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation (TEST-NET-1)
+	netip.MustParsePrefix("192.88.99.0/24"),  // deprecated 6to4 relay anycast
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation (TEST-NET-2)
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation (TEST-NET-3)
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, including limited broadcast
+
+	netip.MustParsePrefix("64:ff9b:1::/48"), // local-use IPv4/IPv6 translation
+	netip.MustParsePrefix("100::/64"),       // discard-only
+	netip.MustParsePrefix("2001:2::/48"),    // benchmarking
+	netip.MustParsePrefix("2001:10::/28"),   // deprecated ORCHID
+	netip.MustParsePrefix("2001:20::/28"),   // ORCHIDv2
+	netip.MustParsePrefix("2001:db8::/32"),  // documentation
+	netip.MustParsePrefix("3fff::/20"),      // documentation
+	netip.MustParsePrefix("5f00::/16"),      // segment routing SIDs
+}
+
+// Prefixes of IPv6 addresses which embed an IPv4 address.
+var (
+	nat64Prefix  = netip.MustParsePrefix("64:ff9b::/96") // RFC 6052
+	sixToFour    = netip.MustParsePrefix("2002::/16")    // RFC 3056
+	teredoPrefix = netip.MustParsePrefix("2001::/32")    // RFC 4380
+)
+
+// PublicAddr reports whether addr is a publicly routable unicast address.  It
+// returns false for loopback, private, link-local, multicast, unspecified,
+// and other special-purpose addresses that are not globally reachable.  IPv6
+// addresses which embed an IPv4 address, such as NAT64 and 6to4 addresses,
+// are only public if the embedded address is.
+func PublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, p := range specialPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+
+	b := addr.As16()
+	switch {
+	case nat64Prefix.Contains(addr):
+		return PublicAddr(netip.AddrFrom4([4]byte(b[12:16])))
+	case sixToFour.Contains(addr):
+		return PublicAddr(netip.AddrFrom4([4]byte(b[2:6])))
+	case teredoPrefix.Contains(addr):
+		// the server address follows the prefix, and the client address
+		// is stored with its bits inverted in the last 32 bits
+		client := [4]byte{^b[12], ^b[13], ^b[14], ^b[15]}
+		return PublicAddr(netip.AddrFrom4([4]byte(b[4:8]))) && PublicAddr(netip.AddrFrom4(client))
+	}
+	return true
+}
@@ -0,0 +1,130 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestNewRestrictedClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/loopback":
+			// redirect to a different loopback address
+			_, port, _ := net.SplitHostPort(r.Host)
+			http.Redirect(w, r, "http://127.0.0.2:"+port+"/", http.StatusFound)
+		case "/file":
+			http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+		case "/redirect":
+			http.Redirect(w, r, "/", http.StatusFound)
+		default:
+			_, _ = w.Write([]byte(`<p class="h-card">Alice</p>`))
+		}
+	}))
+	defer srv.Close()
+
+	// allow connections to only the test server address
+	srvAddr := netip.MustParseAddrPort(srv.Listener.Addr().String()).Addr()
+	allowTestServer := func(addr netip.Addr) bool { return addr == srvAddr }
+
+	tests := []struct {
+		name         string
+		url          string
+		restrictions Restrictions
+		wantErr      error
+	}{
+		{"public only", srv.URL, Restrictions{}, ErrDisallowedAddress},
+		{"allowed", srv.URL, Restrictions{AllowAddr: allowTestServer}, nil},
+		{"redirect", srv.URL + "/redirect", Restrictions{AllowAddr: allowTestServer}, nil},
+		{"redirect to disallowed address", srv.URL + "/loopback", Restrictions{AllowAddr: allowTestServer}, ErrDisallowedAddress},
+		{"redirect to disallowed scheme", srv.URL + "/file", Restrictions{AllowAddr: allowTestServer}, ErrDisallowedScheme},
+		{"disallowed scheme", srv.URL, Restrictions{AllowAddr: allowTestServer, Schemes: []string{"https"}}, ErrDisallowedScheme},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{HTTPClient: NewRestrictedClient(tt.restrictions)}
+			resp, err := c.Fetch(context.Background(), tt.url)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Fetch(%q) returned error %v, want %v", tt.url, err, tt.wantErr)
+			}
+			if err == nil && len(resp.Data.Items) != 1 {
+				t.Errorf("Fetch(%q) returned %d items, want 1", tt.url, len(resp.Data.Items))
+			}
+		})
+	}
+
+	// redirects not followed
+	c := &Client{HTTPClient: NewRestrictedClient(Restrictions{AllowAddr: allowTestServer, MaxRedirects: -1})}
+	if _, err := c.Fetch(context.Background(), srv.URL+"/redirect"); err == nil {
+		t.Errorf("Fetch with redirects disabled did not return error")
+	}
+}
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"2001:4860:4860::8888", true},
+		{"::ffff:8.8.8.8", true},
+
+		{"0.0.0.0", false},
+		{"0.1.2.3", false},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"::", false},
+		{"::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"fc00::1", false},
+		{"fe80::1", false},
+		{"ff02::1", false},
+
+		// other special-purpose IPv4 blocks
+		{"192.0.0.8", false},
+		{"192.0.2.1", false},
+		{"198.18.0.1", false},
+		{"198.19.255.255", false},
+		{"198.20.0.1", true},
+		{"203.0.113.5", false},
+		{"240.0.0.1", false},
+		{"255.255.255.255", false},
+
+		// IPv6 special-purpose blocks
+		{"2001:db8::1", false},
+		{"100::1", false},
+		{"64:ff9b:1::a00:1", false},
+
+		// NAT64 and 6to4 addresses embedding an IPv4 address
+		{"64:ff9b::808:808", true},
+		{"64:ff9b::7f00:1", false},
+		{"64:ff9b::a9fe:a9fe", false},
+		{"64:ff9b::10.1.2.3", false},
+		{"2002:808:808::1", true},
+		{"2002:7f00:1::1", false},
+		{"2002:c0a8:101::1", false},
+
+		// Teredo, with server 65.54.227.120 and client 8.8.8.8 or
+		// 127.0.0.1 (with inverted bits)
+		{"2001:0:4136:e378:8000:63bf:f7f7:f7f7", true},
+		{"2001:0:4136:e378:8000:63bf:80ff:fffe", false},
+	}
+	for _, tt := range tests {
+		if got := PublicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("PublicAddr(%q) = %t, want %t", tt.addr, got, tt.want)
+		}
+	}
+}
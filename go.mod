@@ -10,4 +10,7 @@ require (
 	golang.org/x/net v0.40.0
 )
 
-require github.com/andybalholm/cascadia v1.3.3 // indirect
+require (
+	github.com/andybalholm/cascadia v1.3.3 // indirect
+	golang.org/x/text v0.25.0 // indirect
+)
@@ -0,0 +1,156 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package ical
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"willnorris.com/go/microformats"
+	"willnorris.com/go/microformats/internal/contentline"
+)
+
+// textProperties maps iCalendar properties with a single text value to
+// h-event property names.
+var textProperties = map[string]string{
+	"SUMMARY":     "name",
+	"DESCRIPTION": "description",
+	"DURATION":    "duration",
+	"UID":         "uid",
+	"URL":         "url",
+}
+
+// dateProperties maps iCalendar date-time properties to h-event property
+// names.
+var dateProperties = map[string]string{
+	"DTSTART":       "start",
+	"DTEND":         "end",
+	"CREATED":       "published",
+	"LAST-MODIFIED": "updated",
+}
+
+// Decode reads the iCalendar data in r and returns each VEVENT component as
+// an h-event.  Properties and their values have the same names and shapes as
+// those produced by the microformats parser; for example, start and end
+// times use the parser's datetime format, and a location with coordinates is
+// a nested h-card.  Other components, such as VTODO, and components nested
+// within events, such as VALARM, are ignored.
+func Decode(r io.Reader) ([]*microformats.Microformat, error) {
+	lines, err := contentline.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*microformats.Microformat
+	var event *microformats.Microformat
+	var props []contentline.Line
+	depth := 0 // depth of components nested within the current event
+	for _, l := range lines {
+		switch {
+		case l.Name == "BEGIN" && event == nil && strings.EqualFold(l.Value, "VEVENT"):
+			event = &microformats.Microformat{
+				Type:       []string{"h-event"},
+				Properties: make(map[string][]any),
+			}
+			props = nil
+		case event == nil:
+			continue
+		case l.Name == "BEGIN":
+			depth++
+		case l.Name == "END" && depth > 0:
+			depth--
+		case l.Name == "END" && strings.EqualFold(l.Value, "VEVENT"):
+			decodeEvent(event, props)
+			events = append(events, event)
+			event = nil
+		case depth == 0:
+			props = append(props, l)
+		}
+	}
+	if event != nil {
+		return events, errors.New("ical: missing END:VEVENT")
+	}
+	return events, nil
+}
+
+// decodeEvent adds the h-event properties for the VEVENT properties props to
+// event.
+func decodeEvent(event *microformats.Microformat, props []contentline.Line) {
+	add := func(name string, v any) {
+		if s, ok := v.(string); ok && s == "" {
+			return
+		}
+		event.Properties[name] = append(event.Properties[name], v)
+	}
+
+	var loc, lat, long string
+	for _, l := range props {
+		if name, ok := textProperties[l.Name]; ok {
+			add(name, contentline.Unescape(l.Value))
+			continue
+		}
+		if name, ok := dateProperties[l.Name]; ok {
+			add(name, decodeTime(l))
+			continue
+		}
+
+		switch l.Name {
+		case "CATEGORIES":
+			for _, v := range contentline.Split(l.Value, ',') {
+				add("category", contentline.Unescape(v))
+			}
+		case "LOCATION":
+			loc = contentline.Unescape(l.Value)
+		case "GEO":
+			lat, long, _ = strings.Cut(l.Value, ";")
+			lat, long = strings.TrimSpace(lat), strings.TrimSpace(long)
+		}
+	}
+
+	switch {
+	case lat == "" || long == "":
+		add("location", loc)
+	case loc != "":
+		add("location", &microformats.Microformat{
+			Type: []string{"h-card"},
+			Properties: map[string][]any{
+				"name":      {loc},
+				"latitude":  {lat},
+				"longitude": {long},
+			},
+			Value: loc,
+		})
+	default:
+		add("location", &microformats.Microformat{
+			Type: []string{"h-geo"},
+			Properties: map[string][]any{
+				"latitude":  {lat},
+				"longitude": {long},
+			},
+			Value: lat + ";" + long,
+		})
+	}
+}
+
+// decodeTime returns the value of the date or date-time property l in the
+// microformats datetime format.  Times with a TZID parameter are converted to
+// a UTC offset if the timezone is known, and are otherwise left floating.
+// Values that cannot be parsed are returned unchanged.
+func decodeTime(l contentline.Line) string {
+	var tz *time.Location
+	if tzid := l.Param("TZID"); tzid != "" {
+		tz, _ = time.LoadLocation(strings.TrimPrefix(tzid, "/"))
+	}
+	dt, ok := contentline.ParseDateTime(l.Value, tz)
+	if !ok {
+		return l.Value
+	}
+	if l.Name == "DTEND" && !dt.HasTime {
+		// DTEND dates are exclusive, but microformats end dates are not
+		dt.Time = dt.Time.AddDate(0, 0, -1)
+	}
+	return dt.String()
+}
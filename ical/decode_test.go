@@ -0,0 +1,158 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package ical
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"willnorris.com/go/microformats"
+)
+
+// ignoreUnexported ignores the parser's internal state when comparing
+// microformats.
+var ignoreUnexported = cmpopts.IgnoreUnexported(microformats.Microformat{})
+
+func TestDecode(t *testing.T) {
+	if _, err := time.LoadLocation("America/New_York"); err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	input := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" +
+		"BEGIN:VTIMEZONE\r\nTZID:America/New_York\r\nEND:VTIMEZONE\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:call-1\r\n" +
+		"DTSTAMP:20240401T120000Z\r\n" +
+		"DTSTART;TZID=America/New_York:20240501T190000\r\n" +
+		"DTEND:20240501T233000Z\r\n" +
+		"SUMMARY:Call\\; weekly\r\n" +
+		"DESCRIPTION:Bring notes\\, ideas\r\n" +
+		"CATEGORIES:work,calls\r\n" +
+		"LOCATION:Hall\r\n" +
+		"GEO:45.5;-122.6\r\n" +
+		"CREATED:20240401T120000Z\r\n" +
+		"BEGIN:VALARM\r\nACTION:DISPLAY\r\nDESCRIPTION:Reminder\r\nEND:VALARM\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"SUMMARY:Conf\r\n" +
+		"DTSTART;VALUE=DATE:20240501\r\n" +
+		"DTEND;VALUE=DATE:20240504\r\n" +
+		"LOCATION:Paris\r\n" +
+		"URL:https://example.com/conf\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"SUMMARY:Run\r\n" +
+		"DTSTART:20240501T070000\r\n" +
+		"DURATION:PT1H\r\n" +
+		"GEO:45.5;-122.6\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VTODO\r\nSUMMARY:ignored\r\nEND:VTODO\r\n" +
+		"END:VCALENDAR\r\n"
+
+	got, err := Decode(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Decode() returned error: %v", err)
+	}
+	want := []*microformats.Microformat{
+		{
+			Type: []string{"h-event"},
+			Properties: map[string][]any{
+				"uid":         {"call-1"},
+				"start":       {"2024-05-01 19:00:00-0400"},
+				"end":         {"2024-05-01 23:30:00Z"},
+				"name":        {"Call; weekly"},
+				"description": {"Bring notes, ideas"},
+				"category":    {"work", "calls"},
+				"published":   {"2024-04-01 12:00:00Z"},
+				"location": {&microformats.Microformat{
+					Type: []string{"h-card"},
+					Properties: map[string][]any{
+						"name":      {"Hall"},
+						"latitude":  {"45.5"},
+						"longitude": {"-122.6"},
+					},
+					Value: "Hall",
+				}},
+			},
+		},
+		{
+			Type: []string{"h-event"},
+			Properties: map[string][]any{
+				"name":     {"Conf"},
+				"start":    {"2024-05-01"},
+				"end":      {"2024-05-03"},
+				"location": {"Paris"},
+				"url":      {"https://example.com/conf"},
+			},
+		},
+		{
+			Type: []string{"h-event"},
+			Properties: map[string][]any{
+				"name":     {"Run"},
+				"start":    {"2024-05-01 07:00:00"},
+				"duration": {"PT1H"},
+				"location": {&microformats.Microformat{
+					Type:       []string{"h-geo"},
+					Properties: map[string][]any{"latitude": {"45.5"}, "longitude": {"-122.6"}},
+					Value:      "45.5;-122.6",
+				}},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got, ignoreUnexported); diff != "" {
+		t.Errorf("Decode() differs:\n%s", diff)
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		// an event that is never closed is reported, along with the line
+		// number of malformed content lines
+		{"BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:Call\r\n", "ical: missing END:VEVENT"},
+		{"BEGIN:VEVENT\r\nSUMMARY Call\r\nEND:VEVENT\r\n", "line 2: missing ':'"},
+		{"BEGIN:VEVENT\r\nDTSTART;TZID=\"America/New_York:20240501T190000\r\nEND:VEVENT\r\n", "unterminated quoted value in property DTSTART"},
+	}
+	for _, tt := range tests {
+		_, err := Decode(strings.NewReader(tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Decode(%q) returned error %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestDecode_RoundTrip(t *testing.T) {
+	html := `<div class="h-event"><span class="p-name">Party</span><a class="u-url" href="/p">x</a>
+	  <time class="dt-start" datetime="2024-05-01 19:00:00Z">7pm</time>
+	  <time class="dt-end" datetime="2024-05-03 21:00:00Z">3</time>
+	  <span class="p-category">social</span><span class="p-category">food</span>
+	  <span class="p-location">Hall</span></div>`
+	base, _ := url.Parse("https://example.com/")
+	event := microformats.Parse(strings.NewReader(html), base).Items[0]
+
+	var b strings.Builder
+	if err := Encode(&b, event); err != nil {
+		t.Fatalf("Encode() returned error: %v", err)
+	}
+	events, err := Decode(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("Decode() returned error: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Decode() returned %d events, want 1", len(events))
+	}
+
+	// UID is always included in the calendar
+	got := events[0]
+	delete(got.Properties, "uid")
+	if diff := cmp.Diff(event, got, ignoreUnexported); diff != "" {
+		t.Errorf("Decode(Encode()) differs:\n%s", diff)
+	}
+}
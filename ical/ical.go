@@ -0,0 +1,241 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+// Package ical converts h-event microformats to iCalendar (RFC 5545), and
+// reads iCalendar events as h-events.
+//
+// Start and end values are interpreted using the same datetime rules as the
+// microformats parser.  Values with a timezone offset are converted to UTC,
+// values without a timezone are written as floating times, and values
+// without a time are written as dates.  An end value is only written if it
+// has the same value type as the start, as required by RFC 5545.
+//
+// The required DTSTAMP of each event is its updated or published time, or
+// else its start, so that converting the same h-event always produces the
+// same output.  Only events with none of these use the current time.
+package ical
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"willnorris.com/go/microformats"
+	"willnorris.com/go/microformats/internal/contentline"
+	"willnorris.com/go/microformats/internal/mftext"
+)
+
+// ProdID is the product identifier written to generated calendars.
+const ProdID = "-//willnorris.com//go microformats//EN"
+
+// ErrNotEvent is returned when converting a microformat that is not an
+// h-event.
+var ErrNotEvent = errors.New("ical: microformat is not an h-event")
+
+// now returns the current time, used as the DTSTAMP of events without any
+// other time; it can be overridden in tests.
+var now = time.Now
+
+// durationPattern matches the durations supported by iCalendar, which do not
+// include years or months.
+var durationPattern = regexp.MustCompile(`^[+-]?P(\d+W|(\d+D)?(T(\d+H)?(\d+M)?(\d+S)?)?)$`)
+
+// Encode writes a calendar containing events to w.  It returns ErrNotEvent if
+// any of events is not an h-event.
+func Encode(w io.Writer, events ...*microformats.Microformat) error {
+	var b strings.Builder
+	contentline.WriteLine(&b, "BEGIN:VCALENDAR")
+	contentline.WriteLine(&b, "VERSION:2.0")
+	contentline.WriteLine(&b, "PRODID:"+escape(ProdID))
+	for _, event := range events {
+		if err := writeEvent(&b, event); err != nil {
+			return err
+		}
+	}
+	contentline.WriteLine(&b, "END:VCALENDAR")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeEvent writes a VEVENT component for event to b.
+func writeEvent(b *strings.Builder, event *microformats.Microformat) error {
+	if event == nil || !slices.Contains(event.Type, "h-event") {
+		return ErrNotEvent
+	}
+
+	contentline.WriteLine(b, "BEGIN:VEVENT")
+	contentline.WriteLine(b, "UID:"+escape(uid(event)))
+
+	contentline.WriteLine(b, "DTSTAMP:"+stamp(event).Format("20060102T150405Z"))
+
+	start, hasStart := parseTime(mftext.First(event, "start"))
+	if hasStart {
+		contentline.WriteLine(b, "DTSTART"+start.format())
+	}
+	end, hasEnd := parseTime(mftext.First(event, "end"))
+	if hasEnd && start.dateOnly && !end.dateOnly {
+		// DTEND must have the same value type as DTSTART, so use the
+		// date of an end time for all-day events
+		end = eventTime{t: time.Date(end.t.Year(), end.t.Month(), end.t.Day(), 0, 0, 0, 0, time.UTC), dateOnly: true}
+	}
+	if hasStart && hasEnd && start.dateOnly == end.dateOnly {
+		if end.dateOnly {
+			// microformats end dates are inclusive, but DTEND is not
+			end.t = end.t.AddDate(0, 0, 1)
+		}
+		contentline.WriteLine(b, "DTEND"+end.format())
+	} else if d := mftext.First(event, "duration"); d != "" && durationPattern.MatchString(d) && hasStart {
+		contentline.WriteLine(b, "DURATION:"+d)
+	}
+
+	if name := mftext.First(event, "name"); name != "" {
+		contentline.WriteLine(b, "SUMMARY:"+escape(name))
+	}
+	for _, name := range []string{"description", "summary", "content"} {
+		if desc := mftext.First(event, name); desc != "" {
+			contentline.WriteLine(b, "DESCRIPTION:"+escape(desc))
+			break
+		}
+	}
+	if loc := location(event); loc != "" {
+		contentline.WriteLine(b, "LOCATION:"+escape(loc))
+	}
+	if lat, long := geo(event); lat != "" && long != "" {
+		contentline.WriteLine(b, "GEO:"+lat+";"+long)
+	}
+	if categories := mftext.Values(event, "category"); len(categories) > 0 {
+		for i, c := range categories {
+			categories[i] = escape(c)
+		}
+		contentline.WriteLine(b, "CATEGORIES:"+strings.Join(categories, ","))
+	}
+	if u := mftext.First(event, "url"); contentline.IsURI(u) {
+		contentline.WriteLine(b, "URL:"+u)
+	}
+
+	contentline.WriteLine(b, "END:VEVENT")
+	return nil
+}
+
+// uid returns the unique identifier of event.  This is its uid or url
+// property if present, or else a hash of its name and start, so that the
+// identifier is stable across conversions.
+func uid(event *microformats.Microformat) string {
+	if u := mftext.First(event, "uid"); u != "" {
+		return u
+	}
+	if u := mftext.First(event, "url"); u != "" {
+		return u
+	}
+	h := sha256.Sum256([]byte(mftext.First(event, "name") + "\x00" + mftext.First(event, "start")))
+	return hex.EncodeToString(h[:16]) + "@microformats"
+}
+
+// stamp returns the DTSTAMP of event.  This is its updated, published, or
+// start time, with values without a timezone treated as UTC, or else the
+// current time.
+func stamp(event *microformats.Microformat) time.Time {
+	for _, name := range []string{"updated", "published", "start"} {
+		if dt, ok := microformats.ParseDateTime(mftext.First(event, name)); ok && dt.HasDate {
+			return dt.Time.UTC()
+		}
+	}
+	return now().UTC()
+}
+
+// eventTime is a DTSTART or DTEND value.
+type eventTime struct {
+	t        time.Time
+	dateOnly bool
+	utc      bool
+}
+
+// parseTime parses the microformats datetime value s.
+func parseTime(s string) (eventTime, bool) {
+	dt, ok := microformats.ParseDateTime(s)
+	if !ok || !dt.HasDate {
+		return eventTime{}, false
+	}
+	return eventTime{t: dt.Time, dateOnly: !dt.HasTime, utc: dt.HasTZ}, true
+}
+
+// format returns the parameters and value of t, for use after the property
+// name.
+func (t eventTime) format() string {
+	switch {
+	case t.dateOnly:
+		return ";VALUE=DATE:" + t.t.Format("20060102")
+	case t.utc:
+		return ":" + t.t.UTC().Format("20060102T150405Z")
+	}
+	// floating time, without a timezone
+	return ":" + t.t.Format("20060102T150405")
+}
+
+// location returns a description of the location of event.  Locations may be
+// plain text, or a nested h-card or h-adr.
+func location(event *microformats.Microformat) string {
+	for _, v := range event.Properties["location"] {
+		mf, ok := v.(*microformats.Microformat)
+		if !ok {
+			if s := mftext.Value(v); s != "" {
+				return s
+			}
+			continue
+		}
+
+		var parts []string
+		if name := mftext.First(mf, "name"); name != "" && !slices.Contains(mf.Type, "h-adr") {
+			parts = append(parts, name)
+		}
+		addr := mf
+		for _, a := range mf.Properties["adr"] {
+			if a, ok := a.(*microformats.Microformat); ok {
+				addr = a
+				break
+			}
+		}
+		for _, name := range []string{"street-address", "extended-address", "locality", "region", "postal-code", "country-name"} {
+			parts = append(parts, mftext.Values(addr, name)...)
+		}
+		if len(parts) == 0 {
+			parts = append(parts, mftext.Value(mf))
+		}
+		return strings.Join(parts, ", ")
+	}
+	return ""
+}
+
+// geo returns the latitude and longitude of event, from its own properties or
+// those of its location.
+func geo(event *microformats.Microformat) (lat, long string) {
+	items := []*microformats.Microformat{event}
+	for _, v := range event.Properties["location"] {
+		if mf, ok := v.(*microformats.Microformat); ok {
+			items = append(items, mf)
+		}
+	}
+	for _, item := range items {
+		if lat, long := mftext.First(item, "latitude"), mftext.First(item, "longitude"); lat != "" && long != "" {
+			return lat, long
+		}
+		for _, v := range item.Properties["geo"] {
+			if g, ok := v.(*microformats.Microformat); ok {
+				if lat, long := mftext.First(g, "latitude"), mftext.First(g, "longitude"); lat != "" && long != "" {
+					return lat, long
+				}
+			}
+		}
+	}
+	return "", ""
+}
+
+// escape returns s escaped for use as an iCalendar text value.
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,144 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package ical
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"willnorris.com/go/microformats"
+)
+
+func TestEncode(t *testing.T) {
+	now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	tests := []struct {
+		name string
+		html string
+		want []string // lines between BEGIN:VEVENT and END:VEVENT
+	}{
+		{
+			name: "date only",
+			html: `<div class="h-event"><a class="p-name u-url" href="/conf">Conf</a>
+			  <time class="dt-start" datetime="2024-05-01">May 1</time>–<time class="dt-end" datetime="2024-05-03">3</time></div>`,
+			want: []string{
+				"UID:https://example.com/conf",
+				"DTSTAMP:20240501T000000Z",
+				"DTSTART;VALUE=DATE:20240501",
+				"DTEND;VALUE=DATE:20240504",
+				"SUMMARY:Conf",
+				"URL:https://example.com/conf",
+			},
+		},
+		{
+			name: "times with zone",
+			html: `<div class="h-event"><span class="p-name">Call</span><span class="p-uid">call-1</span>
+			  <time class="dt-start" datetime="2024-05-01T19:00-07:00">7pm</time>
+			  <time class="dt-end" datetime="2024-05-01T20:30-07:00">8:30pm</time>
+			  <time class="dt-published" datetime="2024-04-01T12:00:00Z">Apr 1</time></div>`,
+			want: []string{
+				"UID:call-1",
+				"DTSTAMP:20240401T120000Z",
+				"DTSTART:20240502T020000Z",
+				"DTEND:20240502T033000Z",
+				"SUMMARY:Call",
+			},
+		},
+		{
+			name: "floating time and duration",
+			html: `<div class="h-event"><span class="p-name">Run</span>
+			  <time class="dt-start" datetime="2024-05-01 07:00">7am</time><data class="dt-duration" value="PT1H30M">90m</data></div>`,
+			want: []string{
+				"UID:" + uid(&microformats.Microformat{Properties: map[string][]any{"name": {"Run"}, "start": {"2024-05-01 07:00"}}}),
+				"DTSTAMP:20240501T070000Z",
+				"DTSTART:20240501T070000",
+				"DURATION:PT1H30M",
+				"SUMMARY:Run",
+			},
+		},
+		{
+			name: "date start and time end",
+			html: `<div class="h-event"><span class="p-name">Fair</span><span class="p-uid">fair</span>
+			  <time class="dt-start" datetime="2024-05-01">May 1</time>–<time class="dt-end" datetime="2024-05-03T17:00-07:00">3</time></div>`,
+			want: []string{
+				"UID:fair",
+				"DTSTAMP:20240501T000000Z",
+				"DTSTART;VALUE=DATE:20240501",
+				"DTEND;VALUE=DATE:20240504",
+				"SUMMARY:Fair",
+			},
+		},
+		{
+			name: "time start and date end",
+			html: `<div class="h-event"><span class="p-name">Fair</span><span class="p-uid">fair</span>
+			  <time class="dt-start" datetime="2024-05-01T09:00Z">May 1</time>–<time class="dt-end" datetime="2024-05-03">3</time>
+			  <time class="dt-updated" datetime="2024-04-01">Apr 1</time></div>`,
+			want: []string{
+				"UID:fair",
+				"DTSTAMP:20240401T000000Z",
+				"DTSTART:20240501T090000Z",
+				"SUMMARY:Fair",
+			},
+		},
+		{
+			name: "location card",
+			html: `<div class="h-event"><span class="p-name">Party; fun</span><a class="u-url" href="/p">x</a>
+			  <p class="p-summary">Bring snacks, drinks</p>
+			  <div class="p-location h-card"><span class="p-name">Hall</span>
+			    <span class="p-street-address">1 Main St</span><span class="p-locality">Portland</span>
+			    <span class="p-geo h-geo"><data class="p-latitude" value="45.5">N</data><data class="p-longitude" value="-122.6">W</data></span></div>
+			  <span class="p-category">social</span><span class="p-category">food</span></div>`,
+			want: []string{
+				"UID:https://example.com/p",
+				"DTSTAMP:20240102T030405Z",
+				`SUMMARY:Party\; fun`,
+				`DESCRIPTION:Bring snacks\, drinks`,
+				"LOCATION:Hall\\, 1 Main St\\, Portland",
+				"GEO:45.5;-122.6",
+				"CATEGORIES:social,food",
+				"URL:https://example.com/p",
+			},
+		},
+		{
+			name: "location adr",
+			html: `<div class="h-event"><span class="p-name">Meetup</span><a class="u-url" href="/m">x</a>
+			  <div class="p-location h-adr"><span class="p-locality">Paris</span><span class="p-country-name">France</span></div></div>`,
+			want: []string{
+				"UID:https://example.com/m",
+				"DTSTAMP:20240102T030405Z",
+				"SUMMARY:Meetup",
+				`LOCATION:Paris\, France`,
+				"URL:https://example.com/m",
+			},
+		},
+	}
+
+	base, _ := url.Parse("https://example.com/")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := microformats.Parse(strings.NewReader(tt.html), base)
+			var b strings.Builder
+			if err := Encode(&b, data.Items[0]); err != nil {
+				t.Fatalf("Encode() returned error: %v", err)
+			}
+			want := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:" + escape(ProdID) + "\r\nBEGIN:VEVENT\r\n" +
+				strings.Join(tt.want, "\r\n") + "\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+			if diff := cmp.Diff(want, b.String()); diff != "" {
+				t.Errorf("Encode() differs:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEncode_Errors(t *testing.T) {
+	card := &microformats.Microformat{Type: []string{"h-card"}}
+	if err := Encode(new(strings.Builder), card); !errors.Is(err, ErrNotEvent) {
+		t.Errorf("Encode(h-card) returned error %v, want %v", err, ErrNotEvent)
+	}
+}
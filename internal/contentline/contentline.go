@@ -0,0 +1,300 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+// Package contentline parses and writes the content lines and values used by
+// vCard (RFC 6350 and RFC 2426) and iCalendar (RFC 5545).
+package contentline
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"willnorris.com/go/microformats"
+)
+
+// Line is a single unfolded content line.
+type Line struct {
+	// Group is the optional group name of the line, such as "item1" in
+	// "item1.TEL:555-0100".
+	Group string
+
+	// Name is the uppercase property name.
+	Name string
+
+	// Params maps uppercase parameter names to their values.  Parameter
+	// values are decoded as described in RFC 6868.
+	Params map[string][]string
+
+	// Value is the raw property value, which may include escaped
+	// characters.
+	Value string
+}
+
+// Param returns the first value of the named parameter, or an empty string.
+func (l Line) Param(name string) string {
+	if v := l.Params[strings.ToUpper(name)]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// HasParam returns whether the named parameter includes value, ignoring case.
+func (l Line) HasParam(name, value string) bool {
+	for _, v := range l.Params[strings.ToUpper(name)] {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse reads and unfolds the content lines in r.  Blank lines are ignored.
+func Parse(r io.Reader) ([]Line, error) {
+	var raw []string
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 10<<20)
+	for s.Scan() {
+		text := strings.TrimSuffix(s.Text(), "\r")
+		if len(raw) > 0 && (strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")) {
+			// continuation of a folded line
+			raw[len(raw)-1] += text[1:]
+			continue
+		}
+		raw = append(raw, text)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	var lines []Line
+	for i, text := range raw {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		l, err := parseLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("contentline: line %d: %w", i+1, err)
+		}
+		lines = append(lines, l)
+	}
+	return lines, nil
+}
+
+// parseLine parses a single unfolded content line.
+func parseLine(text string) (Line, error) {
+	l := Line{Params: make(map[string][]string)}
+
+	i := strings.IndexAny(text, ";:")
+	if i < 0 {
+		return l, fmt.Errorf("missing ':' in %q", text)
+	}
+	name := text[:i]
+	if g, n, ok := strings.Cut(name, "."); ok {
+		l.Group, name = g, n
+	}
+	l.Name = strings.ToUpper(name)
+
+	for text[i] == ';' {
+		// parse parameter name and values, which may be quoted
+		text = text[i+1:]
+		end := strings.IndexAny(text, "=;:")
+		if end < 0 {
+			return l, fmt.Errorf("missing ':' in property %s", l.Name)
+		}
+		pname := strings.ToUpper(text[:end])
+		if text[end] != '=' {
+			// vCard 2.1 and 3.0 allow bare TYPE values, such as "TEL;HOME:"
+			l.Params["TYPE"] = append(l.Params["TYPE"], pname)
+			i = end
+			continue
+		}
+
+		text = text[end+1:]
+		i = 0
+		for {
+			var v string
+			if strings.HasPrefix(text, `"`) {
+				q := strings.Index(text[1:], `"`)
+				if q < 0 {
+					return l, fmt.Errorf("unterminated quoted value in property %s", l.Name)
+				}
+				v, text = text[1:q+1], text[q+2:]
+			} else {
+				end := strings.IndexAny(text, ",;:")
+				if end < 0 {
+					return l, fmt.Errorf("missing ':' in property %s", l.Name)
+				}
+				v, text = text[:end], text[end:]
+			}
+			l.Params[pname] = append(l.Params[pname], decodeParam(v))
+			if text == "" {
+				return l, fmt.Errorf("missing ':' in property %s", l.Name)
+			}
+			if text[0] != ',' {
+				break
+			}
+			text = text[1:]
+		}
+	}
+
+	l.Value = text[i+1:]
+	return l, nil
+}
+
+// decodeParam decodes a parameter value using the caret encoding defined in
+// RFC 6868.
+func decodeParam(v string) string {
+	if !strings.Contains(v, "^") {
+		return v
+	}
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] == '^' && i+1 < len(v) {
+			switch v[i+1] {
+			case '^':
+				b.WriteByte('^')
+				i++
+				continue
+			case 'n':
+				b.WriteByte('\n')
+				i++
+				continue
+			case '\'':
+				b.WriteByte('"')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(v[i])
+	}
+	return b.String()
+}
+
+// WriteLine writes a content line to b, folding it after 75 octets as
+// required by RFC 5545 and RFC 6350.  Lines are only folded between
+// characters, so that multi-octet UTF-8 sequences are not split.
+func WriteLine(b *strings.Builder, line string) {
+	const maxLen = 75
+	n := 0
+	for _, r := range line {
+		size := utf8.RuneLen(r)
+		if n+size > maxLen {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	b.WriteString("\r\n")
+}
+
+// IsURI returns whether s is an absolute URI, which is written with the URI
+// value type rather than as text.
+func IsURI(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != ""
+}
+
+// Split splits the raw value s on each unescaped occurrence of sep, without
+// unescaping the parts.
+func Split(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// Unescape returns the text value s with backslash escapes removed.
+func Unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// ParseDateTime parses a vCard or iCalendar date, time, or date-time value in
+// either basic ("20240102T030405Z") or extended ("2024-01-02T03:04:05Z")
+// format.  Values without a UTC offset are interpreted in loc if it is not
+// nil, or else as floating times without a timezone.  Dates without a year,
+// and times without a date, are not supported.
+func ParseDateTime(s string, loc *time.Location) (microformats.DateTime, bool) {
+	date, clock, hasTime := strings.Cut(strings.TrimSpace(s), "T")
+	date = strings.ReplaceAll(date, "-", "")
+	if len(date) != 8 {
+		return microformats.DateTime{}, false
+	}
+	t, err := time.Parse("20060102", date)
+	if err != nil {
+		return microformats.DateTime{}, false
+	}
+	dt := microformats.DateTime{Time: t, HasDate: true}
+	if !hasTime {
+		return dt, true
+	}
+
+	zone := ""
+	if i := strings.IndexAny(clock, "Z+-"); i >= 0 {
+		clock, zone = clock[:i], strings.ReplaceAll(clock[i:], ":", "")
+	}
+	clock = strings.ReplaceAll(clock, ":", "")
+	var layout string
+	switch len(clock) {
+	case 4:
+		layout = "1504"
+	case 6:
+		layout = "150405"
+		dt.HasSeconds = true
+	default:
+		return microformats.DateTime{}, false
+	}
+	c, err := time.Parse(layout, clock)
+	if err != nil {
+		return microformats.DateTime{}, false
+	}
+
+	tz := time.UTC
+	switch {
+	case zone == "Z":
+		dt.HasTZ = true
+	case zone != "":
+		offset, err := time.Parse("-0700", zone)
+		if err != nil {
+			return microformats.DateTime{}, false
+		}
+		tz = offset.Location()
+		dt.HasTZ = true
+	case loc != nil:
+		tz = loc
+		dt.HasTZ = true
+	}
+	dt.HasTime = true
+	dt.Time = time.Date(t.Year(), t.Month(), t.Day(), c.Hour(), c.Minute(), c.Second(), 0, tz)
+	return dt, true
+}
@@ -0,0 +1,167 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package contentline
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	input := "BEGIN:VCARD\r\n" +
+		"FN:Alice\r\n" +
+		"NOTE:folded\r\n  line\r\n" +
+		"item1.TEL;TYPE=home,voice;PREF=1:+1 555\n" +
+		"PHOTO;X-ALT=\"Alice; ^'smiling^' ^^\":https://example.com/a.jpg\r\n" +
+		"TEL;HOME;CELL:555\r\n" +
+		"\r\n" +
+		"END:VCARD\r\n"
+
+	got, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+	want := []Line{
+		{Name: "BEGIN", Params: map[string][]string{}, Value: "VCARD"},
+		{Name: "FN", Params: map[string][]string{}, Value: "Alice"},
+		{Name: "NOTE", Params: map[string][]string{}, Value: "folded line"},
+		{Group: "item1", Name: "TEL", Params: map[string][]string{"TYPE": {"home", "voice"}, "PREF": {"1"}}, Value: "+1 555"},
+		{Name: "PHOTO", Params: map[string][]string{"X-ALT": {`Alice; "smiling" ^`}}, Value: "https://example.com/a.jpg"},
+		{Name: "TEL", Params: map[string][]string{"TYPE": {"HOME", "CELL"}}, Value: "555"},
+		{Name: "END", Params: map[string][]string{}, Value: "VCARD"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Parse() differs:\n%s", diff)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []string{
+		"FN Alice",
+		"PHOTO;X-ALT=\"unterminated:foo",
+		"FN;TYPE=x",
+	}
+	for _, tt := range tests {
+		if _, err := Parse(strings.NewReader(tt)); err == nil {
+			t.Errorf("Parse(%q) did not return an error", tt)
+		}
+	}
+}
+
+func TestWriteLine(t *testing.T) {
+	tests := []struct {
+		line, want string
+	}{
+		{"", "\r\n"},
+		{"SUMMARY:short", "SUMMARY:short\r\n"},
+		{strings.Repeat("a", 75), strings.Repeat("a", 75) + "\r\n"},
+		{strings.Repeat("a", 80), strings.Repeat("a", 75) + "\r\n " + "aaaaa\r\n"},
+		{strings.Repeat("a", 160), strings.Repeat("a", 75) + "\r\n " + strings.Repeat("a", 74) + "\r\n " + strings.Repeat("a", 11) + "\r\n"},
+		// multi-octet characters are not split
+		{strings.Repeat("a", 74) + "é", strings.Repeat("a", 74) + "\r\n é\r\n"},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		WriteLine(&b, tt.line)
+		if got := b.String(); got != tt.want {
+			t.Errorf("WriteLine(%q) wrote %q, want %q", tt.line, got, tt.want)
+		}
+
+		// folded lines are unfolded when parsed
+		if tt.line != "" {
+			lines, err := Parse(strings.NewReader("X:" + b.String()))
+			if err != nil || len(lines) != 1 || lines[0].Value != tt.line {
+				t.Errorf("WriteLine(%q) did not round trip: %v, %v", tt.line, lines, err)
+			}
+		}
+	}
+}
+
+func TestIsURI(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"https://example.com/", true},
+		{"mailto:alice@example.com", true},
+		{"tel:+1-555-0100", true},
+		{"/relative", false},
+		{"plain text", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsURI(tt.s); got != tt.want {
+			t.Errorf("IsURI(%q) returned %t, want %t", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		s    string
+		sep  byte
+		want []string
+	}{
+		{"", ';', []string{""}},
+		{"a;b;;c", ';', []string{"a", "b", "", "c"}},
+		{`a\;b;c`, ';', []string{`a\;b`, "c"}},
+		{`a\\;b`, ';', []string{`a\\`, "b"}},
+		{`a,b\,c`, ',', []string{"a", `b\,c`}},
+	}
+	for _, tt := range tests {
+		if got := Split(tt.s, tt.sep); !cmp.Equal(got, tt.want) {
+			t.Errorf("Split(%q, %q) returned %q, want %q", tt.s, tt.sep, got, tt.want)
+		}
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{"plain", "plain"},
+		{`a\,b\;c\\d`, `a,b;c\d`},
+		{`line\nnext\Nlast`, "line\nnext\nlast"},
+	}
+	for _, tt := range tests {
+		if got := Unescape(tt.s); got != tt.want {
+			t.Errorf("Unescape(%q) returned %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestParseDateTime(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	tests := []struct {
+		s    string
+		loc  *time.Location
+		want string
+	}{
+		{"20240102", nil, "2024-01-02"},
+		{"2024-01-02", nil, "2024-01-02"},
+		{"20240102T0304", nil, "2024-01-02 03:04"},
+		{"20240102T030405", nil, "2024-01-02 03:04:05"},
+		{"20240102T030405Z", nil, "2024-01-02 03:04:05Z"},
+		{"2024-01-02T03:04:05-07:00", nil, "2024-01-02 03:04:05-0700"},
+		{"20240102T030405+0530", nil, "2024-01-02 03:04:05+0530"},
+		{"20240102T030405", ny, "2024-01-02 03:04:05-0500"},
+		{"20240102T030405Z", ny, "2024-01-02 03:04:05Z"},
+		{"--0102", nil, ""},
+		{"T0304", nil, ""},
+		{"20240102T3", nil, ""},
+		{"2024", nil, ""},
+	}
+	for _, tt := range tests {
+		dt, ok := ParseDateTime(tt.s, tt.loc)
+		if got := dt.String(); got != tt.want || ok != (tt.want != "") {
+			t.Errorf("ParseDateTime(%q) returned %q, %t, want %q", tt.s, got, ok, tt.want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package feed
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"willnorris.com/go/microformats"
+)
+
+// Data returns f as microformats data containing a single h-feed, with an
+// h-entry child for each entry.  Property names and values have the same
+// shapes as those produced by the microformats parser: content is a map with
+// "html" and "value" keys, authors are nested h-cards, and datetimes use the
+// parser's normalized format.  Relative URLs are resolved using base, if it
+// is not nil.  Because entries have a single ExternalURL, it is included as
+// bookmark-of.
+func (f *Feed) Data(base *url.URL) *microformats.Data {
+	hfeed := &microformats.Microformat{
+		Type:       []string{"h-feed"},
+		Properties: make(map[string][]any),
+	}
+	props := hfeed.Properties
+	add(props, "name", f.Title)
+	add(props, "url", resolve(base, f.URL))
+	add(props, "summary", f.Summary)
+	add(props, "photo", resolve(base, f.Photo))
+	add(props, "author", card(f.Author, base))
+
+	for _, e := range f.Entries {
+		hfeed.Children = append(hfeed.Children, entryItem(e, f.Author, base))
+	}
+
+	return &microformats.Data{
+		Items:   []*microformats.Microformat{hfeed},
+		Rels:    make(map[string][]string),
+		RelURLs: make(map[string]*microformats.RelURL),
+	}
+}
+
+// entryItem returns the h-entry for e.  The author of the entry is omitted if
+// it is the same as the feed author.
+func entryItem(e *Entry, feedAuthor *Person, base *url.URL) *microformats.Microformat {
+	item := &microformats.Microformat{
+		Type:       []string{"h-entry"},
+		Properties: make(map[string][]any),
+	}
+	props := item.Properties
+	add(props, "name", e.Title)
+	add(props, "url", resolve(base, e.URL))
+	if e.ID != e.URL {
+		// IDs are not necessarily URLs, so are not resolved
+		add(props, "uid", e.ID)
+	}
+	add(props, "summary", e.Summary)
+
+	contentHTML, contentText := e.ContentHTML, e.ContentText
+	if contentHTML == "" {
+		contentHTML = html.EscapeString(contentText)
+	} else if contentText == "" {
+		contentText = TextContent(contentHTML)
+	}
+	if contentHTML != "" {
+		add(props, "content", map[string]string{"html": contentHTML, "value": contentText})
+	}
+
+	add(props, "published", formatTime(e.Published))
+	add(props, "updated", formatTime(e.Updated))
+	if e.Author != nil && (feedAuthor == nil || *e.Author != *feedAuthor) {
+		add(props, "author", card(e.Author, base))
+	}
+	for _, c := range e.Categories {
+		add(props, "category", c)
+	}
+	for _, a := range e.Attachments {
+		name, _, _ := strings.Cut(a.MIMEType, "/")
+		switch name {
+		case "image":
+			name = "photo"
+		case "video", "audio":
+		default:
+			continue
+		}
+		add(props, name, resolve(base, a.URL))
+	}
+	for _, u := range e.InReplyTo {
+		add(props, "in-reply-to", resolve(base, u))
+	}
+	add(props, "bookmark-of", resolve(base, e.ExternalURL))
+	return item
+}
+
+// card returns the h-card for p, or nil if p is nil.
+func card(p *Person, base *url.URL) *microformats.Microformat {
+	if p == nil {
+		return nil
+	}
+	c := &microformats.Microformat{
+		Type:       []string{"h-card"},
+		Properties: make(map[string][]any),
+		Value:      p.Name,
+	}
+	props := c.Properties
+	add(props, "name", p.Name)
+	add(props, "url", resolve(base, p.URL))
+	add(props, "photo", resolve(base, p.Photo))
+	if p.Email != "" {
+		add(props, "email", "mailto:"+p.Email)
+	}
+	if c.Value == "" {
+		c.Value = resolve(base, p.URL)
+	}
+	return c
+}
+
+// add adds v to the values of the named property in props, unless v is
+// empty.
+func add(props map[string][]any, name string, v any) {
+	switch v := v.(type) {
+	case string:
+		if v == "" {
+			return
+		}
+	case *microformats.Microformat:
+		if v == nil {
+			return
+		}
+	}
+	props[name] = append(props[name], v)
+}
+
+// resolve returns the URL u resolved relative to base.
+func resolve(base *url.URL, u string) string {
+	if base == nil || u == "" {
+		return u
+	}
+	ref, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// formatTime returns t in the normalized microformats datetime format, or an
+// empty string if t is the zero time.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return microformats.DateTime{
+		Time:       t,
+		HasDate:    true,
+		HasTime:    true,
+		HasTZ:      true,
+		HasSeconds: true,
+	}.String()
+}
+
+// TextContent returns the plain text of the HTML fragment s.  Script and
+// style elements are ignored, and images are replaced with their alt text.
+func TextContent(s string) string {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(s), body)
+	if err != nil {
+		return ""
+	}
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+		case n.DataAtom == atom.Script || n.DataAtom == atom.Style || n.DataAtom == atom.Template:
+		case n.DataAtom == atom.Img:
+			for _, a := range n.Attr {
+				if a.Key == "alt" {
+					b.WriteString(a.Val)
+				}
+			}
+		default:
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				walk(c)
+			}
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+	return strings.TrimSpace(b.String())
+}
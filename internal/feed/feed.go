@@ -0,0 +1,329 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+// Package feed discovers the feed of posts in parsed microformats, and
+// describes it in a form that is convenient for encoding as a syndication
+// format such as Atom, RSS, or JSON Feed.
+package feed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"html"
+	"mime"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"willnorris.com/go/microformats"
+	"willnorris.com/go/microformats/internal/mftext"
+)
+
+// ErrNoFeed is returned when data contains neither an h-feed nor any
+// top-level h-entry items.
+var ErrNoFeed = errors.New("feed: no h-feed or h-entry found")
+
+// Feed is a feed of posts.
+type Feed struct {
+	Title   string
+	URL     string
+	Summary string
+	Photo   string
+	Author  *Person
+	Entries []*Entry
+}
+
+// Person is the author of a feed or entry.
+type Person struct {
+	Name  string
+	URL   string
+	Email string
+	Photo string
+}
+
+// Entry is a single post in a feed.
+type Entry struct {
+	// ID is the uid or url of the entry, or else a stable identifier
+	// derived from its content.
+	ID string
+
+	URL     string
+	Title   string // empty for notes
+	Summary string
+
+	// ExternalURL is the URL of the post that the entry is a repost or
+	// bookmark of.
+	ExternalURL string
+
+	ContentHTML string
+	ContentText string
+
+	// Published and Updated are the zero time if not known.  Times
+	// without a timezone are interpreted as UTC.
+	Published time.Time
+	Updated   time.Time
+
+	// Author is the author of the entry, or else of the feed.
+	Author *Person
+
+	Categories  []string
+	Attachments []Attachment
+	InReplyTo   []string
+}
+
+// Attachment is a photo, video, or audio file attached to an entry.
+type Attachment struct {
+	URL      string
+	MIMEType string
+}
+
+// mediaTypes maps common media file extensions to their MIME type, since
+// the types known to the mime package vary by system.
+var mediaTypes = map[string]string{
+	".avif": "image/avif",
+	".gif":  "image/gif",
+	".jpeg": "image/jpeg",
+	".jpg":  "image/jpeg",
+	".m4a":  "audio/mp4",
+	".mov":  "video/quicktime",
+	".mp3":  "audio/mpeg",
+	".mp4":  "video/mp4",
+	".oga":  "audio/ogg",
+	".ogg":  "audio/ogg",
+	".ogv":  "video/ogg",
+	".opus": "audio/opus",
+	".png":  "image/png",
+	".svg":  "image/svg+xml",
+	".wav":  "audio/wav",
+	".webm": "video/webm",
+	".webp": "image/webp",
+}
+
+// Discover returns the feed in data.  This is the first h-feed in data, with
+// its h-entry children as entries, or else an implied feed of the top-level
+// h-entry items in data.
+func Discover(data *microformats.Data) (*Feed, error) {
+	if data == nil {
+		return nil, ErrNoFeed
+	}
+
+	f := new(Feed)
+	var entries []*microformats.Microformat
+	if hfeed := findFeed(data.Items); hfeed != nil {
+		f.Title = mftext.First(hfeed, "name")
+		f.URL = mftext.First(hfeed, "url")
+		f.Summary = mftext.First(hfeed, "summary")
+		f.Photo = mftext.First(hfeed, "photo")
+		f.Author = person(hfeed.Properties["author"])
+		for _, child := range hfeed.Children {
+			if slices.Contains(child.Type, "h-entry") {
+				entries = append(entries, child)
+			}
+		}
+	} else {
+		for _, item := range data.Items {
+			if slices.Contains(item.Type, "h-entry") {
+				entries = append(entries, item)
+			}
+		}
+		if len(entries) == 0 {
+			return nil, ErrNoFeed
+		}
+	}
+
+	if f.Author == nil {
+		if authors := data.Rels["author"]; len(authors) > 0 {
+			f.Author = &Person{URL: authors[0]}
+		}
+	}
+	for _, item := range entries {
+		e := entry(item)
+		if e.Author == nil {
+			e.Author = f.Author
+		}
+		f.Entries = append(f.Entries, e)
+	}
+	return f, nil
+}
+
+// Updated returns the time that f was last updated, which is the latest
+// updated or published time of its entries.  It returns the zero time if no
+// entries have a known time.
+func (f *Feed) Updated() time.Time {
+	var t time.Time
+	for _, e := range f.Entries {
+		if u := e.LastModified(); u.After(t) {
+			t = u
+		}
+	}
+	return t
+}
+
+// LastModified returns the updated time of e, or else its published time.
+func (e *Entry) LastModified() time.Time {
+	if !e.Updated.IsZero() {
+		return e.Updated
+	}
+	return e.Published
+}
+
+// HashID returns a stable identifier derived from values, for feeds and
+// entries without a URL.
+func HashID(values ...string) string {
+	h := sha256.Sum256([]byte(strings.Join(values, "\x00")))
+	return "urn:sha256:" + hex.EncodeToString(h[:16])
+}
+
+// findFeed returns the first h-feed in items or their children.
+func findFeed(items []*microformats.Microformat) *microformats.Microformat {
+	for _, item := range items {
+		if slices.Contains(item.Type, "h-feed") {
+			return item
+		}
+		if f := findFeed(item.Children); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// entry returns the Entry for an h-entry item.
+func entry(item *microformats.Microformat) *Entry {
+	e := &Entry{
+		URL:       mftext.First(item, "url"),
+		Summary:   mftext.First(item, "summary"),
+		Published: parseTime(mftext.First(item, "published")),
+		Updated:   parseTime(mftext.First(item, "updated")),
+		Author:    person(item.Properties["author"]),
+	}
+	for _, v := range item.Properties["content"] {
+		switch v := v.(type) {
+		case map[string]string:
+			e.ContentHTML, e.ContentText = v["html"], v["value"]
+		case string:
+			e.ContentHTML, e.ContentText = html.EscapeString(v), v
+		default:
+			continue
+		}
+		break
+	}
+
+	if name := mftext.First(item, "name"); !isPrefix(name, e.ContentText) && !isPrefix(name, e.Summary) {
+		// names that are a prefix of the content are implied from the
+		// content, and are not a title
+		e.Title = name
+	}
+
+	e.ID = mftext.First(item, "uid")
+	if e.ID == "" {
+		e.ID = e.URL
+	}
+	if e.ID == "" {
+		e.ID = HashID(mftext.First(item, "published"), e.Title, e.ContentText)
+	}
+
+	for _, v := range item.Properties["category"] {
+		if s := mftext.Value(v); s != "" {
+			e.Categories = append(e.Categories, s)
+		}
+	}
+	for _, name := range []string{"photo", "video", "audio"} {
+		for _, v := range item.Properties[name] {
+			if u := mftext.Value(v); u != "" {
+				e.Attachments = append(e.Attachments, Attachment{URL: u, MIMEType: MediaType(u)})
+			}
+		}
+	}
+	for _, v := range item.Properties["in-reply-to"] {
+		if u := citeURL(v); u != "" {
+			e.InReplyTo = append(e.InReplyTo, u)
+		}
+	}
+	for _, name := range []string{"repost-of", "bookmark-of"} {
+		for _, v := range item.Properties[name] {
+			if u := citeURL(v); u != "" && e.ExternalURL == "" {
+				e.ExternalURL = u
+			}
+		}
+	}
+	return e
+}
+
+// citeURL returns the URL of a property value that refers to another post,
+// which may be a URL or an h-cite.
+func citeURL(v any) string {
+	if mf, ok := v.(*microformats.Microformat); ok {
+		if u := mftext.First(mf, "url"); u != "" {
+			return u
+		}
+	}
+	return mftext.Value(v)
+}
+
+// isPrefix returns whether name is a prefix of content, ignoring differences
+// in whitespace.  This is the test used by post type discovery to identify
+// notes, whose name is implied from their content.
+func isPrefix(name, content string) bool {
+	name = strings.Join(strings.Fields(name), " ")
+	content = strings.Join(strings.Fields(content), " ")
+	return strings.HasPrefix(content, name)
+}
+
+// person returns the Person for the first value of an author property, which
+// may be an h-card, a URL, or a name.
+func person(values []any) *Person {
+	for _, v := range values {
+		switch v := v.(type) {
+		case *microformats.Microformat:
+			p := &Person{
+				Name:  mftext.First(v, "name"),
+				URL:   mftext.First(v, "url"),
+				Email: strings.TrimPrefix(mftext.First(v, "email"), "mailto:"),
+				Photo: mftext.First(v, "photo"),
+			}
+			if p.Name == "" && p.URL == "" {
+				p.Name = v.Value
+			}
+			return p
+		default:
+			s := mftext.Value(v)
+			if s == "" {
+				continue
+			}
+			if u, err := url.Parse(s); err == nil && u.Scheme != "" && u.Host != "" {
+				return &Person{URL: s}
+			}
+			return &Person{Name: s}
+		}
+	}
+	return nil
+}
+
+// MediaType returns the MIME type of the media file at u, based on its file
+// extension.
+func MediaType(u string) string {
+	ext := ""
+	if pu, err := url.Parse(u); err == nil {
+		ext = strings.ToLower(path.Ext(pu.Path))
+	}
+	if t, ok := mediaTypes[ext]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// parseTime parses a microformats datetime value, returning the zero time if
+// it does not include a date.
+func parseTime(s string) time.Time {
+	dt, ok := microformats.ParseDateTime(s)
+	if !ok || !dt.HasDate {
+		return time.Time{}
+	}
+	return dt.Time
+}
@@ -0,0 +1,143 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package feed
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"willnorris.com/go/microformats"
+)
+
+func parse(t *testing.T, html string) *microformats.Data {
+	t.Helper()
+	base, _ := url.Parse("https://example.com/")
+	return microformats.Parse(strings.NewReader(html), base)
+}
+
+func TestDiscover(t *testing.T) {
+	data := parse(t, `<main class="h-feed"><h1 class="p-name">Blog</h1><a class="u-url" href="/blog">x</a>
+	  <a class="p-author h-card" href="/">Alice</a>
+	  <article class="h-entry"><h2 class="p-name">Title</h2><a class="u-url" href="/1">x</a>
+	    <time class="dt-published" datetime="2024-05-01 10:00:00-07:00">May 1</time>
+	    <div class="e-content"><p>Some <b>long</b> content</p></div>
+	    <span class="p-category">go</span>
+	    <img class="u-photo" src="/a.jpg" alt="A"><audio class="u-audio" src="/a.mp3"></audio></article>
+	  <article class="h-entry"><p class="p-name e-content">A note</p>
+	    <a class="p-author h-card" href="https://bob.example/">Bob</a>
+	    <a class="u-in-reply-to" href="https://other.example/post">re</a>
+	    <div class="u-bookmark-of h-cite"><a class="u-url p-name" href="https://other.example/saved">saved</a></div>
+	    <time class="dt-published" datetime="2024-05-02">May 2</time>
+	    <time class="dt-updated" datetime="2024-05-03 12:00">May 3</time></article>
+	  <div class="h-card">not an entry</div>
+	</main>`)
+
+	got, err := Discover(data)
+	if err != nil {
+		t.Fatalf("Discover() returned error: %v", err)
+	}
+	alice := &Person{Name: "Alice", URL: "https://example.com/"}
+	want := &Feed{
+		Title:  "Blog",
+		URL:    "https://example.com/blog",
+		Author: alice,
+		Entries: []*Entry{
+			{
+				ID:          "https://example.com/1",
+				URL:         "https://example.com/1",
+				Title:       "Title",
+				ContentHTML: "<p>Some <b>long</b> content</p>",
+				ContentText: "Some long content",
+				Published:   time.Date(2024, 5, 1, 17, 0, 0, 0, time.UTC),
+				Author:      alice,
+				Categories:  []string{"go"},
+				Attachments: []Attachment{
+					{URL: "https://example.com/a.jpg", MIMEType: "image/jpeg"},
+					{URL: "https://example.com/a.mp3", MIMEType: "audio/mpeg"},
+				},
+			},
+			{
+				ID:          HashID("2024-05-02", "", "A note"),
+				ContentHTML: "A note",
+				ContentText: "A note",
+				Published:   time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+				Updated:     time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC),
+				Author:      &Person{Name: "Bob", URL: "https://bob.example/"},
+				InReplyTo:   []string{"https://other.example/post"},
+				ExternalURL: "https://other.example/saved",
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got, cmp.Comparer(time.Time.Equal)); diff != "" {
+		t.Errorf("Discover() differs:\n%s", diff)
+	}
+	if got, want := got.Updated(), time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Updated() returned %v, want %v", got, want)
+	}
+}
+
+func TestDiscover_Implied(t *testing.T) {
+	data := parse(t, `<link rel="author" href="/me">
+	  <div class="h-entry"><p class="p-content">one</p></div>
+	  <div class="h-card"><div class="h-entry">nested entries are not included</div></div>
+	  <div class="h-entry"><p class="p-content">two</p></div>`)
+
+	got, err := Discover(data)
+	if err != nil {
+		t.Fatalf("Discover() returned error: %v", err)
+	}
+	var contents []string
+	for _, e := range got.Entries {
+		contents = append(contents, e.ContentHTML)
+		if e.Author == nil || e.Author.URL != "https://example.com/me" {
+			t.Errorf("entry %q has author %v, want rel=author", e.ContentText, e.Author)
+		}
+	}
+	if want := []string{"one", "two"}; !cmp.Equal(contents, want) {
+		t.Errorf("Discover() returned entries %q, want %q", contents, want)
+	}
+}
+
+func TestDiscover_NoFeed(t *testing.T) {
+	for _, data := range []*microformats.Data{nil, parse(t, `<div class="h-card">Alice</div>`)} {
+		if _, err := Discover(data); !errors.Is(err, ErrNoFeed) {
+			t.Errorf("Discover() returned error %v, want %v", err, ErrNoFeed)
+		}
+	}
+}
+
+func TestMediaType(t *testing.T) {
+	tests := []struct {
+		url, want string
+	}{
+		{"https://example.com/a.JPG", "image/jpeg"},
+		{"https://example.com/v.mp4?x=1", "video/mp4"},
+		{"https://example.com/file", "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		if got := MediaType(tt.url); got != tt.want {
+			t.Errorf("MediaType(%q) returned %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestTextContent(t *testing.T) {
+	tests := []struct {
+		html, want string
+	}{
+		{"", ""},
+		{"plain &amp; simple", "plain & simple"},
+		{" <p>Some <b>bold</b> text</p> ", "Some bold text"},
+		{`<p>a<script>alert(1)</script><style>p{}</style><img src="x.png" alt="b"></p>`, "ab"},
+	}
+	for _, tt := range tests {
+		if got := TextContent(tt.html); got != tt.want {
+			t.Errorf("TextContent(%q) returned %q, want %q", tt.html, got, tt.want)
+		}
+	}
+}
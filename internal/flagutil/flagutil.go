@@ -0,0 +1,21 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+// Package flagutil parses command line flag values shared by the gomf and
+// gomfweb commands.
+package flagutil
+
+import "strings"
+
+// Schemes parses a comma separated list of URL schemes, such as
+// "http, https".  Schemes are trimmed of whitespace and lowercased, and
+// empty entries are omitted.
+func Schemes(s string) []string {
+	var schemes []string
+	for _, scheme := range strings.Split(s, ",") {
+		if scheme = strings.ToLower(strings.TrimSpace(scheme)); scheme != "" {
+			schemes = append(schemes, scheme)
+		}
+	}
+	return schemes
+}
@@ -0,0 +1,27 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package flagutil
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSchemes(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []string
+	}{
+		{"", nil},
+		{"http,https", []string{"http", "https"}},
+		{"http, https", []string{"http", "https"}},
+		{" HTTPS ,,Mailto,", []string{"https", "mailto"}},
+	}
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, Schemes(tt.s)); diff != "" {
+			t.Errorf("Schemes(%q) differs:\n%s", tt.s, diff)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+// Package mftext returns the plain text values of parsed microformats
+// properties, for formats which have no use for their structure.
+package mftext
+
+import "willnorris.com/go/microformats"
+
+// Values returns the non-empty plain text values of the named property of
+// item.
+func Values(item *microformats.Microformat, name string) []string {
+	var values []string
+	for _, v := range item.Properties[name] {
+		if s := Value(v); s != "" {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+// First returns the first non-empty plain text value of the named property
+// of item.
+func First(item *microformats.Microformat, name string) string {
+	for _, v := range item.Properties[name] {
+		if s := Value(v); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// Value returns the plain text value of a property value.  The value of an
+// embedded microformat is its Value, or else its name.
+func Value(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case map[string]string:
+		return v["value"]
+	case *microformats.Microformat:
+		if v.Value != "" {
+			return v.Value
+		}
+		return First(v, "name")
+	}
+	return ""
+}
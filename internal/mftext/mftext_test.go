@@ -0,0 +1,49 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package mftext
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"willnorris.com/go/microformats"
+)
+
+func TestValues(t *testing.T) {
+	item := &microformats.Microformat{
+		Properties: map[string][]any{
+			"name": {"", "Alice"},
+			"photo": {
+				map[string]string{"value": "https://example.com/a.jpg", "alt": "A"},
+				map[string]string{"alt": "no value"},
+			},
+			"author": {
+				&microformats.Microformat{Value: "https://example.com/"},
+				&microformats.Microformat{Properties: map[string][]any{"name": {"Bob"}}},
+				&microformats.Microformat{},
+			},
+			"rsvp": {42},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		want      []string
+		wantFirst string
+	}{
+		{"name", []string{"Alice"}, "Alice"},
+		{"photo", []string{"https://example.com/a.jpg"}, "https://example.com/a.jpg"},
+		{"author", []string{"https://example.com/", "Bob"}, "https://example.com/"},
+		{"rsvp", nil, ""},
+		{"missing", nil, ""},
+	}
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, Values(item, tt.name)); diff != "" {
+			t.Errorf("Values(%q) differs:\n%s", tt.name, diff)
+		}
+		if got := First(item, tt.name); got != tt.wantFirst {
+			t.Errorf("First(%q) returned %q, want %q", tt.name, got, tt.wantFirst)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package jsonfeed
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"willnorris.com/go/microformats"
+	"willnorris.com/go/microformats/internal/feed"
+)
+
+// Decode reads a JSON Feed 1.0 or 1.1 document from r and returns it as
+// microformats data containing a single h-feed, with an h-entry child for
+// each item.  Property names and values have the same shapes as those
+// produced by the microformats parser: content is a map with "html" and
+// "value" keys, authors are nested h-cards, and datetimes use the parser's
+// normalized format.  Relative URLs are resolved using base, if it is not
+// nil.  Because h-entry has no equivalent of external_url, it is included as
+// bookmark-of.
+func Decode(r io.Reader, base *url.URL) (*microformats.Data, error) {
+	var jf Feed
+	if err := json.NewDecoder(r).Decode(&jf); err != nil {
+		return nil, fmt.Errorf("jsonfeed: %w", err)
+	}
+	if !strings.HasPrefix(jf.Version, "https://jsonfeed.org/version/") {
+		return nil, fmt.Errorf("jsonfeed: unsupported version %q", jf.Version)
+	}
+
+	f := &feed.Feed{
+		Title:   jf.Title,
+		URL:     jf.HomePageURL,
+		Summary: jf.Description,
+		Photo:   jf.Icon,
+		Author:  person(jf.Authors, jf.Author),
+	}
+	for _, item := range jf.Items {
+		e := &feed.Entry{
+			ID:          item.ID,
+			URL:         item.URL,
+			ExternalURL: item.ExternalURL,
+			Title:       item.Title,
+			Summary:     item.Summary,
+			ContentHTML: item.ContentHTML,
+			ContentText: item.ContentText,
+			Published:   parseTime(item.DatePublished),
+			Updated:     parseTime(item.DateModified),
+			Author:      person(item.Authors, item.Author),
+			Categories:  item.Tags,
+		}
+		if item.Image != "" {
+			e.Attachments = append(e.Attachments, feed.Attachment{URL: item.Image, MIMEType: feed.MediaType(item.Image)})
+		}
+		for _, a := range item.Attachments {
+			if a == nil || a.URL == "" || slices.ContainsFunc(e.Attachments, func(ea feed.Attachment) bool { return ea.URL == a.URL }) {
+				continue
+			}
+			e.Attachments = append(e.Attachments, feed.Attachment{URL: a.URL, MIMEType: a.MIMEType})
+		}
+		f.Entries = append(f.Entries, e)
+	}
+	return f.Data(base), nil
+}
+
+// person returns the first of authors, or else the JSON Feed 1.0 author.
+func person(authors []*Author, author *Author) *feed.Person {
+	for _, a := range authors {
+		if a != nil && (a.Name != "" || a.URL != "") {
+			return &feed.Person{Name: a.Name, URL: a.URL, Photo: a.Avatar}
+		}
+	}
+	if author != nil && (author.Name != "" || author.URL != "") {
+		return &feed.Person{Name: author.Name, URL: author.URL, Photo: author.Avatar}
+	}
+	return nil
+}
+
+// parseTime parses an RFC 3339 timestamp, returning the zero time if it
+// cannot be parsed.
+func parseTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339, s)
+	return t
+}
@@ -0,0 +1,143 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package jsonfeed
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"willnorris.com/go/microformats"
+)
+
+// ignoreUnexported ignores the parser's internal state when comparing
+// microformats.
+var ignoreUnexported = cmpopts.IgnoreUnexported(microformats.Microformat{})
+
+func TestDecode(t *testing.T) {
+	input := `{
+	  "version": "https://jsonfeed.org/version/1.1",
+	  "title": "Blog",
+	  "home_page_url": "/blog",
+	  "description": "Posts by Alice",
+	  "authors": [{"name": "Alice", "url": "/", "avatar": "/alice.jpg"}],
+	  "items": [
+	    {
+	      "id": "1",
+	      "url": "/1",
+	      "title": "Title",
+	      "content_html": "<p>Some <b>long</b> content</p>",
+	      "date_published": "2024-05-01T10:00:00-07:00",
+	      "date_modified": "2024-05-02T10:00:00Z",
+	      "tags": ["go", "mf2"],
+	      "image": "/a.jpg",
+	      "attachments": [
+	        {"url": "/a.jpg", "mime_type": "image/jpeg"},
+	        {"url": "/a.mp3", "mime_type": "audio/mpeg"}
+	      ]
+	    },
+	    {
+	      "id": "https://example.com/2",
+	      "url": "https://example.com/2",
+	      "external_url": "https://other.example/post",
+	      "content_text": "Worth reading <3",
+	      "author": {"name": "Bob"}
+	    }
+	  ]
+	}`
+
+	base, _ := url.Parse("https://example.com/feed.json")
+	data, err := Decode(strings.NewReader(input), base)
+	if err != nil {
+		t.Fatalf("Decode() returned error: %v", err)
+	}
+	want := []*microformats.Microformat{{
+		Type: []string{"h-feed"},
+		Properties: map[string][]any{
+			"name":    {"Blog"},
+			"url":     {"https://example.com/blog"},
+			"summary": {"Posts by Alice"},
+			"author": {&microformats.Microformat{
+				Type: []string{"h-card"},
+				Properties: map[string][]any{
+					"name":  {"Alice"},
+					"url":   {"https://example.com/"},
+					"photo": {"https://example.com/alice.jpg"},
+				},
+				Value: "Alice",
+			}},
+		},
+		Children: []*microformats.Microformat{
+			{
+				Type: []string{"h-entry"},
+				Properties: map[string][]any{
+					"name":      {"Title"},
+					"url":       {"https://example.com/1"},
+					"uid":       {"1"},
+					"content":   {map[string]string{"html": "<p>Some <b>long</b> content</p>", "value": "Some long content"}},
+					"published": {"2024-05-01 10:00:00-0700"},
+					"updated":   {"2024-05-02 10:00:00Z"},
+					"category":  {"go", "mf2"},
+					"photo":     {"https://example.com/a.jpg"},
+					"audio":     {"https://example.com/a.mp3"},
+				},
+			},
+			{
+				Type: []string{"h-entry"},
+				Properties: map[string][]any{
+					"url":         {"https://example.com/2"},
+					"content":     {map[string]string{"html": "Worth reading &lt;3", "value": "Worth reading <3"}},
+					"author":      {&microformats.Microformat{Type: []string{"h-card"}, Properties: map[string][]any{"name": {"Bob"}}, Value: "Bob"}},
+					"bookmark-of": {"https://other.example/post"},
+				},
+			},
+		},
+	}}
+	if diff := cmp.Diff(want, data.Items, ignoreUnexported); diff != "" {
+		t.Errorf("Decode() differs:\n%s", diff)
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", "jsonfeed: EOF"},
+		{"[]", "jsonfeed: json: cannot unmarshal array"},
+		// the version is required, and must be a JSON Feed version URL
+		{`{"items": []}`, `unsupported version ""`},
+		{`{"version": "1", "items": []}`, `unsupported version "1"`},
+	}
+	for _, tt := range tests {
+		_, err := Decode(strings.NewReader(tt.input), nil)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Decode(%q) returned error %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestDecode_RoundTrip(t *testing.T) {
+	data := parse(t, `<main class="h-feed"><h1 class="p-name">Blog</h1>
+	  <article class="h-entry"><h2 class="p-name">Title</h2><a class="u-url" href="/1">x</a>
+	    <time class="dt-published" datetime="2024-05-01 10:00:00-0700">May 1</time>
+	    <div class="e-content"><p>Some content</p></div>
+	    <span class="p-category">go</span></article>
+	</main>`)
+
+	var b strings.Builder
+	if err := Encode(&b, data); err != nil {
+		t.Fatalf("Encode() returned error: %v", err)
+	}
+	got, err := Decode(strings.NewReader(b.String()), nil)
+	if err != nil {
+		t.Fatalf("Decode() returned error: %v", err)
+	}
+
+	if diff := cmp.Diff(data.Items[0].Children[0], got.Items[0].Children[0], ignoreUnexported); diff != "" {
+		t.Errorf("Decode(Encode()) differs:\n%s", diff)
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+// Package jsonfeed converts an h-feed to JSON Feed 1.1.
+//
+// The feed is discovered in the same way as by the feedxml package: it is
+// the first h-feed in the parsed data, or else an implied feed of its
+// top-level h-entry items.  Entries without an author use the author of the
+// feed.
+//
+// See also https://www.jsonfeed.org/version/1.1/
+package jsonfeed
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"willnorris.com/go/microformats"
+	"willnorris.com/go/microformats/internal/feed"
+)
+
+// Version is the JSON Feed version URL of converted feeds.
+const Version = "https://jsonfeed.org/version/1.1"
+
+// ErrNoFeed is returned when data contains neither an h-feed nor any
+// top-level h-entry items.
+var ErrNoFeed = feed.ErrNoFeed
+
+// Feed is a JSON Feed.
+type Feed struct {
+	Version     string    `json:"version"`
+	Title       string    `json:"title"`
+	HomePageURL string    `json:"home_page_url,omitempty"`
+	FeedURL     string    `json:"feed_url,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Icon        string    `json:"icon,omitempty"`
+	Authors     []*Author `json:"authors,omitempty"`
+	Items       []*Item   `json:"items"`
+
+	// Author is the author of a JSON Feed 1.0 feed.  It is read by Decode,
+	// but is not set by Convert.
+	Author *Author `json:"author,omitempty"`
+}
+
+// Item is an item in a JSON Feed.
+type Item struct {
+	ID            string        `json:"id"`
+	URL           string        `json:"url,omitempty"`
+	ExternalURL   string        `json:"external_url,omitempty"`
+	Title         string        `json:"title,omitempty"`
+	ContentHTML   string        `json:"content_html,omitempty"`
+	ContentText   string        `json:"content_text,omitempty"`
+	Summary       string        `json:"summary,omitempty"`
+	Image         string        `json:"image,omitempty"`
+	DatePublished string        `json:"date_published,omitempty"`
+	DateModified  string        `json:"date_modified,omitempty"`
+	Authors       []*Author     `json:"authors,omitempty"`
+	Tags          []string      `json:"tags,omitempty"`
+	Attachments   []*Attachment `json:"attachments,omitempty"`
+
+	// Author is the author of a JSON Feed 1.0 item.  It is read by Decode,
+	// but is not set by Convert.
+	Author *Author `json:"author,omitempty"`
+}
+
+// Author is the author of a JSON Feed or item.
+type Author struct {
+	Name   string `json:"name,omitempty"`
+	URL    string `json:"url,omitempty"`
+	Avatar string `json:"avatar,omitempty"`
+}
+
+// Attachment is a file attached to a JSON Feed item.
+type Attachment struct {
+	URL      string `json:"url"`
+	MIMEType string `json:"mime_type"`
+	Title    string `json:"title,omitempty"`
+}
+
+// Convert returns the feed in data as a JSON Feed.  It returns ErrNoFeed if
+// data does not include a feed.
+func Convert(data *microformats.Data) (*Feed, error) {
+	f, err := feed.Discover(data)
+	if err != nil {
+		return nil, err
+	}
+
+	jf := &Feed{
+		Version:     Version,
+		Title:       f.Title,
+		HomePageURL: f.URL,
+		Description: f.Summary,
+		Icon:        f.Photo,
+		Authors:     authors(f.Author),
+		Items:       []*Item{},
+	}
+	for _, e := range f.Entries {
+		item := &Item{
+			ID:            e.ID,
+			URL:           e.URL,
+			ExternalURL:   e.ExternalURL,
+			Title:         e.Title,
+			ContentHTML:   e.ContentHTML,
+			ContentText:   e.ContentText,
+			Summary:       e.Summary,
+			DatePublished: formatTime(e.Published),
+			DateModified:  formatTime(e.Updated),
+			Authors:       authors(e.Author),
+			Tags:          e.Categories,
+		}
+		if item.ContentHTML == "" && item.ContentText == "" {
+			// items must have content, so use the summary if there is
+			// no content
+			item.ContentText = e.Summary
+		}
+		for _, a := range e.Attachments {
+			if item.Image == "" && strings.HasPrefix(a.MIMEType, "image/") {
+				item.Image = a.URL
+			}
+			item.Attachments = append(item.Attachments, &Attachment{URL: a.URL, MIMEType: a.MIMEType})
+		}
+		jf.Items = append(jf.Items, item)
+	}
+	return jf, nil
+}
+
+// Encode writes the feed in data to w as a JSON Feed.  It returns ErrNoFeed
+// if data does not include a feed.
+func Encode(w io.Writer, data *microformats.Data) error {
+	f, err := Convert(data)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(f)
+}
+
+// authors returns the JSON Feed authors for p, or nil if p is nil.
+func authors(p *feed.Person) []*Author {
+	if p == nil {
+		return nil
+	}
+	return []*Author{{Name: p.Name, URL: p.URL, Avatar: p.Photo}}
+}
+
+// formatTime formats t as an RFC 3339 timestamp, or returns an empty string
+// if t is the zero time.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,112 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package jsonfeed
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"willnorris.com/go/microformats"
+)
+
+func parse(t *testing.T, html string) *microformats.Data {
+	t.Helper()
+	base, _ := url.Parse("https://example.com/")
+	return microformats.Parse(strings.NewReader(html), base)
+}
+
+func TestConvert(t *testing.T) {
+	data := parse(t, `<main class="h-feed"><h1 class="p-name">Blog</h1><a class="u-url" href="/blog">x</a>
+	  <p class="p-summary">Posts by Alice</p>
+	  <div class="p-author h-card"><a class="p-name u-url" href="/">Alice</a><img class="u-photo" src="/alice.jpg"></div>
+	  <article class="h-entry"><h2 class="p-name">Title</h2><a class="u-url" href="/1">x</a>
+	    <time class="dt-published" datetime="2024-05-01 10:00:00-07:00">May 1</time>
+	    <time class="dt-updated" datetime="2024-05-02 10:00:00Z">May 2</time>
+	    <div class="e-content"><p>Some <b>long</b> content</p></div>
+	    <span class="p-category">go</span><span class="p-category">mf2</span>
+	    <img class="u-photo" src="/a.jpg"><video class="u-video" src="/v.mp4"></video></article>
+	  <article class="h-entry"><a class="u-url" href="/2">x</a>
+	    <a class="p-author h-card" href="https://bob.example/">Bob</a>
+	    <a class="u-repost-of" href="https://other.example/post">reposted</a></article>
+	</main>`)
+
+	got, err := Convert(data)
+	if err != nil {
+		t.Fatalf("Convert() returned error: %v", err)
+	}
+	alice := []*Author{{Name: "Alice", URL: "https://example.com/", Avatar: "https://example.com/alice.jpg"}}
+	want := &Feed{
+		Version:     Version,
+		Title:       "Blog",
+		HomePageURL: "https://example.com/blog",
+		Description: "Posts by Alice",
+		Authors:     alice,
+		Items: []*Item{
+			{
+				ID:            "https://example.com/1",
+				URL:           "https://example.com/1",
+				Title:         "Title",
+				ContentHTML:   "<p>Some <b>long</b> content</p>",
+				ContentText:   "Some long content",
+				Image:         "https://example.com/a.jpg",
+				DatePublished: "2024-05-01T10:00:00-07:00",
+				DateModified:  "2024-05-02T10:00:00Z",
+				Authors:       alice,
+				Tags:          []string{"go", "mf2"},
+				Attachments: []*Attachment{
+					{URL: "https://example.com/a.jpg", MIMEType: "image/jpeg"},
+					{URL: "https://example.com/v.mp4", MIMEType: "video/mp4"},
+				},
+			},
+			{
+				ID:          "https://example.com/2",
+				URL:         "https://example.com/2",
+				ExternalURL: "https://other.example/post",
+				Authors:     []*Author{{Name: "Bob", URL: "https://bob.example/"}},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Convert() differs:\n%s", diff)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	data := parse(t, `<p class="h-entry"><data class="p-uid" value="1"></data><span class="p-name e-content">a & b</span></p>`)
+	var b strings.Builder
+	if err := Encode(&b, data); err != nil {
+		t.Fatalf("Encode() returned error: %v", err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+		t.Fatalf("Encode() wrote invalid JSON: %v", err)
+	}
+	want := map[string]any{
+		"version": Version,
+		"title":   "",
+		"items": []any{map[string]any{
+			"id":           "1",
+			"content_html": "a &amp; b",
+			"content_text": "a & b",
+		}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Encode() differs:\n%s", diff)
+	}
+	if !strings.Contains(b.String(), `"a & b"`) {
+		t.Errorf("Encode() escaped HTML characters:\n%s", b.String())
+	}
+}
+
+func TestConvert_NoFeed(t *testing.T) {
+	data := parse(t, `<div class="h-card">Alice</div>`)
+	if _, err := Convert(data); !errors.Is(err, ErrNoFeed) {
+		t.Errorf("Convert() returned error %v, want %v", err, ErrNoFeed)
+	}
+}
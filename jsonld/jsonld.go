@@ -0,0 +1,503 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+// Package jsonld converts microformats to schema.org JSON-LD.
+//
+// Each microformats vocabulary (such as h-card or h-entry) is converted
+// according to a Mapping, which identifies the schema.org type and how
+// properties are renamed.  Mappings for common vocabularies are registered
+// by default, and additional mappings can be registered with Register.
+// Properties without a mapping are omitted from the output.
+//
+// Nested microformats are converted to nested JSON-LD objects.  The HTML of
+// e-* properties such as e-content is preserved.
+package jsonld
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"willnorris.com/go/microformats"
+	"willnorris.com/go/microformats/ptd"
+)
+
+// Context is the JSON-LD context used for converted data.
+const Context = "https://schema.org"
+
+// Mapping describes how a microformats vocabulary is converted to a
+// schema.org type.
+type Mapping struct {
+	// Type is the schema.org type, such as "Person".
+	Type string
+
+	// TypeFunc, if not nil, returns the schema.org type for a particular
+	// item, for vocabularies that map to more than one type.  It takes
+	// precedence over Type.
+	TypeFunc func(item *microformats.Microformat) string
+
+	// Properties maps microformats property names to schema.org property
+	// names.  Multiple microformats properties may map to the same
+	// schema.org property, in which case their values are combined.
+	Properties map[string]string
+
+	// Convert, if not nil, is called with each converted object to add
+	// any values that cannot be expressed by Properties.
+	Convert func(r *Registry, item *microformats.Microformat, obj map[string]any)
+}
+
+// Registry is a set of mappings used to convert microformats.  It is safe for
+// concurrent use.
+type Registry struct {
+	mu       sync.RWMutex
+	mappings map[string]Mapping
+}
+
+// NewRegistry returns a Registry with the default mappings.
+func NewRegistry() *Registry {
+	r := &Registry{mappings: make(map[string]Mapping)}
+	for t, m := range defaultMappings {
+		r.mappings[t] = m
+	}
+	return r
+}
+
+// DefaultRegistry is the Registry used by the package-level functions.
+var DefaultRegistry = NewRegistry()
+
+// Register sets the mapping for the microformats root class mfType, such as
+// "h-entry", replacing any existing mapping.
+func (r *Registry) Register(mfType string, m Mapping) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mappings[mfType] = m
+}
+
+// Lookup returns the mapping for mfType, and whether one was registered.
+func (r *Registry) Lookup(mfType string) (Mapping, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	m, ok := r.mappings[mfType]
+	return m, ok
+}
+
+// Register sets the mapping for mfType in DefaultRegistry.
+func Register(mfType string, m Mapping) {
+	DefaultRegistry.Register(mfType, m)
+}
+
+// Convert converts the microformats in data using DefaultRegistry.
+func Convert(data *microformats.Data) map[string]any {
+	return DefaultRegistry.Convert(data)
+}
+
+// ConvertItem converts item using DefaultRegistry.
+func ConvertItem(item *microformats.Microformat) map[string]any {
+	return DefaultRegistry.ConvertItem(item)
+}
+
+// Convert converts the top-level microformats in data to a JSON-LD document,
+// with the converted items in its "@graph".  Top-level items whose type has
+// no mapping are replaced by their converted children.
+func (r *Registry) Convert(data *microformats.Data) map[string]any {
+	graph := []any{}
+	if data != nil {
+		graph = r.appendGraph(graph, data.Items)
+	}
+	return map[string]any{
+		"@context": Context,
+		"@graph":   graph,
+	}
+}
+
+func (r *Registry) appendGraph(graph []any, items []*microformats.Microformat) []any {
+	for _, item := range items {
+		if _, ok := r.mapping(item); ok {
+			graph = append(graph, r.object(item))
+		} else {
+			graph = r.appendGraph(graph, item.Children)
+		}
+	}
+	return graph
+}
+
+// ConvertItem converts item to a JSON-LD object with the schema.org context.
+// Items whose type has no mapping are converted to a schema.org Thing.  It
+// returns nil if item is nil.
+func (r *Registry) ConvertItem(item *microformats.Microformat) map[string]any {
+	if item == nil {
+		return nil
+	}
+	obj := r.object(item)
+	obj["@context"] = Context
+	return obj
+}
+
+// mapping returns the mapping for the first type of item that has one.
+func (r *Registry) mapping(item *microformats.Microformat) (Mapping, bool) {
+	for _, t := range item.Type {
+		if m, ok := r.Lookup(t); ok {
+			return m, true
+		}
+	}
+	return Mapping{}, false
+}
+
+// object converts item to a JSON-LD object without a context.
+func (r *Registry) object(item *microformats.Microformat) map[string]any {
+	m, ok := r.mapping(item)
+	if !ok {
+		m = thingMapping
+	}
+	typ := m.Type
+	if m.TypeFunc != nil {
+		typ = m.TypeFunc(item)
+	}
+
+	obj := map[string]any{"@type": typ}
+	if item.ID != "" {
+		obj["@id"] = "#" + item.ID
+	}
+
+	values := make(map[string][]any)
+	for _, name := range slices.Sorted(maps.Keys(item.Properties)) {
+		prop, ok := m.Properties[name]
+		if !ok {
+			continue
+		}
+		for _, v := range item.Properties[name] {
+			if v := r.Value(v); v != nil {
+				values[prop] = append(values[prop], v)
+			}
+		}
+	}
+	for prop, v := range values {
+		obj[prop] = unwrap(v)
+	}
+
+	if m.Convert != nil {
+		m.Convert(r, item, obj)
+	}
+	return obj
+}
+
+// Value converts a single microformats property value to JSON-LD.  Nested
+// microformats are converted to objects, e-* property values are converted
+// to their HTML, and images with alt text are converted to an ImageObject.
+func (r *Registry) Value(v any) any {
+	switch v := v.(type) {
+	case string:
+		return v
+	case map[string]string:
+		if html, ok := v["html"]; ok {
+			return html
+		}
+		if alt, ok := v["alt"]; ok {
+			return map[string]any{
+				"@type":   "ImageObject",
+				"url":     v["value"],
+				"caption": alt,
+			}
+		}
+		return v["value"]
+	case *microformats.Microformat:
+		if v == nil {
+			return nil
+		}
+		return r.object(v)
+	}
+	return nil
+}
+
+// unwrap returns the only value in values, or else values itself.
+func unwrap(values []any) any {
+	if len(values) == 1 {
+		return values[0]
+	}
+	return values
+}
+
+// firstString returns the first plain string value of the named property.
+func firstString(item *microformats.Microformat, name string) string {
+	for _, v := range item.Properties[name] {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// addValue adds v to the values of prop in obj.
+func addValue(obj map[string]any, prop string, v any) {
+	switch existing := obj[prop].(type) {
+	case nil:
+		obj[prop] = v
+	case []any:
+		obj[prop] = append(existing, v)
+	default:
+		obj[prop] = []any{existing, v}
+	}
+}
+
+// thingMapping is used for microformats whose type has no mapping.
+var thingMapping = Mapping{
+	Type: "Thing",
+	Properties: map[string]string{
+		"name":    "name",
+		"photo":   "image",
+		"summary": "description",
+		"url":     "url",
+	},
+}
+
+// defaultMappings are the mappings registered in a new Registry.
+var defaultMappings = map[string]Mapping{
+	"h-adr": {
+		Type: "PostalAddress",
+		Properties: map[string]string{
+			"country-name":    "addressCountry",
+			"locality":        "addressLocality",
+			"post-office-box": "postOfficeBoxNumber",
+			"postal-code":     "postalCode",
+			"region":          "addressRegion",
+			"street-address":  "streetAddress",
+		},
+	},
+	"h-card": {
+		TypeFunc: cardType,
+		Properties: map[string]string{
+			"additional-name":  "additionalName",
+			"adr":              "address",
+			"bday":             "birthDate",
+			"email":            "email",
+			"family-name":      "familyName",
+			"geo":              "geo",
+			"given-name":       "givenName",
+			"honorific-prefix": "honorificPrefix",
+			"honorific-suffix": "honorificSuffix",
+			"job-title":        "jobTitle",
+			"logo":             "logo",
+			"name":             "name",
+			"nickname":         "alternateName",
+			"note":             "description",
+			"photo":            "image",
+			"tel":              "telephone",
+			"uid":              "identifier",
+			"url":              "url",
+		},
+		Convert: convertCard,
+	},
+	"h-entry": {
+		TypeFunc: entryType,
+		Properties: map[string]string{
+			"audio":       "audio",
+			"author":      "author",
+			"category":    "keywords",
+			"comment":     "comment",
+			"content":     "articleBody",
+			"name":        "headline",
+			"photo":       "image",
+			"published":   "datePublished",
+			"summary":     "description",
+			"syndication": "sameAs",
+			"uid":         "identifier",
+			"updated":     "dateModified",
+			"url":         "url",
+			"video":       "video",
+		},
+		Convert: convertEntry,
+	},
+	"h-event": {
+		Type: "Event",
+		Properties: map[string]string{
+			"category":    "keywords",
+			"description": "description",
+			"duration":    "duration",
+			"end":         "endDate",
+			"name":        "name",
+			"photo":       "image",
+			"start":       "startDate",
+			"summary":     "description",
+			"url":         "url",
+		},
+		Convert: convertEvent,
+	},
+	"h-feed": {
+		Type: "Blog",
+		Properties: map[string]string{
+			"author":  "author",
+			"name":    "name",
+			"photo":   "image",
+			"summary": "description",
+			"url":     "url",
+		},
+		Convert: convertFeed,
+	},
+	"h-geo": {
+		Type: "GeoCoordinates",
+		Properties: map[string]string{
+			"altitude":  "elevation",
+			"latitude":  "latitude",
+			"longitude": "longitude",
+		},
+	},
+	"h-product": {
+		Type: "Product",
+		Properties: map[string]string{
+			"brand":       "brand",
+			"category":    "category",
+			"description": "description",
+			"identifier":  "identifier",
+			"name":        "name",
+			"photo":       "image",
+			"review":      "review",
+			"url":         "url",
+		},
+		Convert: convertProduct,
+	},
+	"h-recipe": {
+		Type: "Recipe",
+		Properties: map[string]string{
+			"author":       "author",
+			"category":     "recipeCategory",
+			"duration":     "totalTime",
+			"ingredient":   "recipeIngredient",
+			"instructions": "recipeInstructions",
+			"name":         "name",
+			"nutrition":    "nutrition",
+			"photo":        "image",
+			"published":    "datePublished",
+			"summary":      "description",
+			"url":          "url",
+			"yield":        "recipeYield",
+		},
+	},
+	"h-review": {
+		Type: "Review",
+		Properties: map[string]string{
+			"author":    "author",
+			"category":  "keywords",
+			"content":   "reviewBody",
+			"item":      "itemReviewed",
+			"name":      "name",
+			"published": "datePublished",
+			"summary":   "description",
+			"url":       "url",
+		},
+		Convert: convertReview,
+	},
+}
+
+// cardType returns Organization for h-card items whose name and org are the
+// same, following the microformats convention, and Person otherwise.
+func cardType(item *microformats.Microformat) string {
+	if name := firstString(item, "name"); name != "" && name == firstString(item, "org") {
+		return "Organization"
+	}
+	return "Person"
+}
+
+// convertCard sets the employer of a Person from its org property.
+func convertCard(r *Registry, item *microformats.Microformat, obj map[string]any) {
+	if obj["@type"] != "Person" {
+		return
+	}
+	for _, v := range item.Properties["org"] {
+		switch v := v.(type) {
+		case *microformats.Microformat:
+			org := r.object(v)
+			org["@type"] = "Organization"
+			addValue(obj, "worksFor", org)
+		default:
+			if s, ok := r.Value(v).(string); ok && s != "" {
+				addValue(obj, "worksFor", map[string]any{"@type": "Organization", "name": s})
+			}
+		}
+	}
+}
+
+// entryType returns BlogPosting for article posts, and SocialMediaPosting for
+// all other types of posts.
+func entryType(item *microformats.Microformat) string {
+	if ptd.PostType(item) == "article" {
+		return "BlogPosting"
+	}
+	return "SocialMediaPosting"
+}
+
+// convertEntry adds the plain text of an entry's content as its text, since
+// articleBody includes the content HTML, and adds its location as a Place.
+func convertEntry(r *Registry, item *microformats.Microformat, obj map[string]any) {
+	addPlaces(r, item, obj, "contentLocation")
+	for _, v := range item.Properties["content"] {
+		if m, ok := v.(map[string]string); ok && strings.TrimSpace(m["value"]) != "" {
+			obj["text"] = m["value"]
+			return
+		}
+	}
+}
+
+// convertEvent adds the location of an event as a Place.
+func convertEvent(r *Registry, item *microformats.Microformat, obj map[string]any) {
+	addPlaces(r, item, obj, "location")
+}
+
+// addPlaces adds the location values of item to prop in obj as Places.  An
+// h-adr or h-geo location becomes the address or geo of a Place, and any
+// other microformat, such as an h-card, is converted with the Place type.
+func addPlaces(r *Registry, item *microformats.Microformat, obj map[string]any, prop string) {
+	for _, v := range item.Properties["location"] {
+		switch v := v.(type) {
+		case *microformats.Microformat:
+			if v == nil {
+				continue
+			}
+			place := r.object(v)
+			switch place["@type"] {
+			case "PostalAddress":
+				place = map[string]any{"@type": "Place", "address": place}
+			case "GeoCoordinates":
+				place = map[string]any{"@type": "Place", "geo": place}
+			default:
+				place["@type"] = "Place"
+				delete(place, "worksFor")
+			}
+			addValue(obj, prop, place)
+		default:
+			if s, ok := r.Value(v).(string); ok && s != "" {
+				addValue(obj, prop, map[string]any{"@type": "Place", "name": s})
+			}
+		}
+	}
+}
+
+// convertFeed adds the children of a feed as its posts.
+func convertFeed(r *Registry, item *microformats.Microformat, obj map[string]any) {
+	for _, child := range item.Children {
+		addValue(obj, "blogPost", r.object(child))
+	}
+}
+
+// convertProduct adds an offer for the price of a product.
+func convertProduct(_ *Registry, item *microformats.Microformat, obj map[string]any) {
+	if price := firstString(item, "price"); price != "" {
+		obj["offers"] = map[string]any{"@type": "Offer", "price": price}
+	}
+}
+
+// convertReview adds the rating of a review.
+func convertReview(_ *Registry, item *microformats.Microformat, obj map[string]any) {
+	rating := firstString(item, "rating")
+	if rating == "" {
+		return
+	}
+	r := map[string]any{"@type": "Rating", "ratingValue": rating}
+	if best := firstString(item, "best"); best != "" {
+		r["bestRating"] = best
+	}
+	if worst := firstString(item, "worst"); worst != "" {
+		r["worstRating"] = worst
+	}
+	obj["reviewRating"] = r
+}
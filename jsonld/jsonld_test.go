@@ -0,0 +1,203 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package jsonld
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"willnorris.com/go/microformats"
+)
+
+// normalize round-trips v through JSON, so that it can be compared with
+// values unmarshaled from JSON.
+func normalize(t *testing.T, v any) any {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("error marshaling json: %v", err)
+	}
+	var out any
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("error unmarshaling json: %v", err)
+	}
+	return out
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string // JSON of the @graph
+	}{
+		{
+			name: "person",
+			html: `<div class="h-card"><a class="p-name u-url" href="/">Alice</a>
+			  <img class="u-photo" src="/alice.jpg" alt="Alice smiling">
+			  <span class="p-org">Acme</span>
+			  <div class="p-adr h-adr"><span class="p-locality">Portland</span> <span class="p-country-name">US</span></div></div>`,
+			want: `[{
+			  "@type": "Person",
+			  "name": "Alice",
+			  "url": "https://example.com/",
+			  "image": {"@type": "ImageObject", "url": "https://example.com/alice.jpg", "caption": "Alice smiling"},
+			  "worksFor": {"@type": "Organization", "name": "Acme"},
+			  "address": {"@type": "PostalAddress", "addressLocality": "Portland", "addressCountry": "US"}
+			}]`,
+		},
+		{
+			name: "organization",
+			html: `<div class="h-card"><span class="p-name p-org">Acme</span></div>`,
+			want: `[{"@type": "Organization", "name": "Acme"}]`,
+		},
+		{
+			name: "note",
+			html: `<div class="h-entry" id="post"><div class="e-content">Hello <b>world</b></div>
+			  <a class="u-url" href="/post"><time class="dt-published" datetime="2024-01-02T03:04:05Z">Jan 2</time></a>
+			  <a class="p-category">go</a><a class="p-category">mf2</a>
+			  <a class="p-author h-card" href="/">Alice</a></div>`,
+			want: `[{
+			  "@type": "SocialMediaPosting",
+			  "@id": "#post",
+			  "articleBody": "Hello <b>world</b>",
+			  "text": "Hello world",
+			  "url": "https://example.com/post",
+			  "datePublished": "2024-01-02T03:04:05Z",
+			  "keywords": ["go", "mf2"],
+			  "author": {"@type": "Person", "name": "Alice", "url": "https://example.com/"}
+			}]`,
+		},
+		{
+			name: "article",
+			html: `<div class="h-entry"><h1 class="p-name">Title</h1><div class="e-content">Long body text</div></div>`,
+			want: `[{"@type": "BlogPosting", "headline": "Title", "articleBody": "Long body text", "text": "Long body text"}]`,
+		},
+		{
+			name: "event",
+			html: `<div class="h-event"><span class="p-name">Party</span>
+			  <time class="dt-start" datetime="2024-05-01T19:00">May 1</time>
+			  <span class="p-location h-card"><span class="p-name">Hall</span>
+			    <span class="p-geo h-geo"><data class="p-latitude" value="45.5">N</data><data class="p-longitude" value="-122.6">W</data></span></span></div>`,
+			want: `[{
+			  "@type": "Event",
+			  "name": "Party",
+			  "startDate": "2024-05-01T19:00",
+			  "location": {"@type": "Place", "name": "Hall", "geo": {"@type": "GeoCoordinates", "latitude": "45.5", "longitude": "-122.6"}}
+			}]`,
+		},
+		{
+			name: "review",
+			html: `<div class="h-review"><span class="p-name">Great</span>
+			  <div class="p-item h-product"><span class="p-name">Widget</span><data class="p-price" value="9.99">$9.99</data></div>
+			  <data class="p-rating" value="4">4</data>/<data class="p-best" value="5">5</data></div>`,
+			want: `[{
+			  "@type": "Review",
+			  "name": "Great",
+			  "itemReviewed": {"@type": "Product", "name": "Widget", "offers": {"@type": "Offer", "price": "9.99"}},
+			  "reviewRating": {"@type": "Rating", "ratingValue": "4", "bestRating": "5"}
+			}]`,
+		},
+		{
+			name: "recipe",
+			html: `<div class="h-recipe"><span class="p-name">Toast</span>
+			  <span class="p-ingredient">bread</span><span class="p-ingredient">butter</span>
+			  <span class="p-yield">1</span><div class="e-instructions"><ol><li>Toast</li></ol></div></div>`,
+			want: `[{
+			  "@type": "Recipe",
+			  "name": "Toast",
+			  "recipeIngredient": ["bread", "butter"],
+			  "recipeYield": "1",
+			  "recipeInstructions": "<ol><li>Toast</li></ol>"
+			}]`,
+		},
+		{
+			name: "feed",
+			html: `<div class="h-feed"><span class="p-name">Blog</span>
+			  <div class="h-entry"><span class="p-name">Hi</span></div></div>`,
+			want: `[{"@type": "Blog", "name": "Blog", "blogPost": {"@type": "SocialMediaPosting", "headline": "Hi"}}]`,
+		},
+		{
+			name: "unmapped root",
+			html: `<div class="h-x-unknown"><div class="h-card">Bob</div></div>`,
+			want: `[{"@type": "Person", "name": "Bob"}]`,
+		},
+		{
+			name: "unmapped nested",
+			html: `<div class="h-entry"><span class="p-name">Hi</span><div class="p-author h-x-person"><span class="p-name">Alice</span></div></div>`,
+			want: `[{"@type": "SocialMediaPosting", "headline": "Hi", "author": {"@type": "Thing", "name": "Alice"}}]`,
+		},
+		{
+			name: "event locations",
+			html: `<div class="h-event"><span class="p-name">Party</span>
+			  <span class="p-location">Town Hall</span>
+			  <div class="p-location h-adr"><span class="p-locality">Portland</span></div>
+			  <div class="p-location h-card"><span class="p-name">Acme</span><span class="p-org">Acme Corp</span></div></div>`,
+			want: `[{
+			  "@type": "Event",
+			  "name": "Party",
+			  "location": [
+			    {"@type": "Place", "name": "Town Hall"},
+			    {"@type": "Place", "address": {"@type": "PostalAddress", "addressLocality": "Portland"}},
+			    {"@type": "Place", "name": "Acme"}
+			  ]
+			}]`,
+		},
+		{
+			name: "entry location",
+			html: `<div class="h-entry"><span class="p-name">Hi</span><div class="p-location h-x-place"><span class="p-name">Home</span></div></div>`,
+			want: `[{"@type": "SocialMediaPosting", "headline": "Hi", "contentLocation": {"@type": "Place", "name": "Home"}}]`,
+		},
+	}
+
+	base, _ := url.Parse("https://example.com/")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := microformats.Parse(strings.NewReader(tt.html), base)
+			got := normalize(t, Convert(data))
+
+			var graph any
+			if err := json.Unmarshal([]byte(tt.want), &graph); err != nil {
+				t.Fatalf("error unmarshaling want: %v", err)
+			}
+			want := map[string]any{"@context": Context, "@graph": graph}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("Convert() differs:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConvert_Nil(t *testing.T) {
+	want := map[string]any{"@context": Context, "@graph": []any{}}
+	if diff := cmp.Diff(want, Convert(nil)); diff != "" {
+		t.Errorf("Convert(nil) differs:\n%s", diff)
+	}
+	if got := ConvertItem(nil); got != nil {
+		t.Errorf("ConvertItem(nil) returned %v, want nil", got)
+	}
+}
+
+func TestRegistry_Register(t *testing.T) {
+	r := NewRegistry()
+	r.Register("h-x-book", Mapping{
+		Type:       "Book",
+		Properties: map[string]string{"name": "name", "isbn": "isbn"},
+	})
+	item := &microformats.Microformat{
+		Type:       []string{"h-x-book"},
+		Properties: map[string][]any{"name": {"Go"}, "isbn": {"123"}, "other": {"x"}},
+	}
+	want := map[string]any{"@context": Context, "@type": "Book", "name": "Go", "isbn": "123"}
+	if diff := cmp.Diff(want, r.ConvertItem(item)); diff != "" {
+		t.Errorf("ConvertItem() differs:\n%s", diff)
+	}
+
+	// registering with one Registry does not affect others
+	if m, ok := DefaultRegistry.Lookup("h-x-book"); ok {
+		t.Errorf("DefaultRegistry.Lookup(%q) returned %v, want none", "h-x-book", m)
+	}
+}
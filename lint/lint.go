@@ -0,0 +1,446 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+// Package lint checks microformats for common publishing mistakes.
+//
+// Check inspects parsed microformats data, and CheckNode inspects the HTML
+// document the data was parsed from.  Both return a list of findings, each
+// identified by a rule ID and severity, suitable for reporting in continuous
+// integration.
+package lint
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"willnorris.com/go/microformats"
+)
+
+// Rule IDs reported in findings.
+const (
+	// An h-entry has no url property.
+	RuleEntryMissingURL = "entry-missing-url"
+
+	// An h-entry has no published property.
+	RuleEntryMissingPublished = "entry-missing-published"
+
+	// An h-card has no name property.
+	RuleCardMissingName = "card-missing-name"
+
+	// A datetime property value cannot be parsed.
+	RuleInvalidDatetime = "invalid-datetime"
+
+	// A URL property value is not an absolute URL.
+	RuleRelativeURL = "relative-url"
+
+	// An e-* property contains links or embeds with relative URLs.
+	RuleRelativeContentLink = "relative-content-link"
+
+	// An element has both microformats v1 and v2 classes.
+	RuleMixedVersions = "mixed-versions"
+
+	// An element has a property class, but is not inside any microformats2 root.
+	RuleOrphanProperty = "orphan-property"
+)
+
+// Severity describes how serious a finding is.
+type Severity int
+
+// Severity levels, in increasing order of seriousness.
+const (
+	Info Severity = iota
+	Warning
+	Error
+)
+
+// String returns the lowercase name of s.
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Finding is a single problem found by a check.
+type Finding struct {
+	// Rule is the ID of the rule that produced this finding.
+	Rule string `json:"rule"`
+
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+
+	// Path identifies where the problem was found.  For findings from
+	// Check, this is the path to the item or property value within the
+	// parsed data, such as "items[0].properties.url[0]".  For findings
+	// from CheckNode, this is the path to the element within the document,
+	// such as "/html/body/div[2]".
+	Path string `json:"path"`
+
+	// Node is the element the problem was found on.  For findings from
+	// Check, this is the root element of the microformat containing the
+	// problem, and is only set if the data was produced by the parser.
+	Node *html.Node `json:"-"`
+}
+
+// String returns a single line description of f.
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s (%s)", f.Path, f.Severity, f.Message, f.Rule)
+}
+
+var (
+	// property names that are conventionally parsed as dt-* properties
+	datetimeProperties = map[string]bool{
+		"accessed":    true,
+		"anniversary": true,
+		"bday":        true,
+		"end":         true,
+		"published":   true,
+		"rev":         true,
+		"reviewed":    true,
+		"start":       true,
+		"updated":     true,
+	}
+
+	// property names that are conventionally parsed as u-* properties
+	urlProperties = map[string]bool{
+		"audio":       true,
+		"bookmark-of": true,
+		"featured":    true,
+		"follow-of":   true,
+		"in-reply-to": true,
+		"like-of":     true,
+		"logo":        true,
+		"photo":       true,
+		"repost-of":   true,
+		"syndication": true,
+		"uid":         true,
+		"url":         true,
+		"video":       true,
+	}
+)
+
+// Check inspects the microformats in data and returns any findings.
+func Check(data *microformats.Data) []Finding {
+	if data == nil {
+		return nil
+	}
+	var findings []Finding
+	for i, item := range data.Items {
+		findings = checkItem(findings, item, fmt.Sprintf("items[%d]", i))
+	}
+	return findings
+}
+
+// checkItem appends findings for item and its nested microformats to
+// findings.  path is the path to item.
+func checkItem(findings []Finding, item *microformats.Microformat, path string) []Finding {
+	if item == nil {
+		return findings
+	}
+
+	for _, t := range item.Type {
+		switch t {
+		case "h-entry":
+			if len(item.Properties["url"]) == 0 {
+				findings = append(findings, Finding{
+					Rule:     RuleEntryMissingURL,
+					Severity: Warning,
+					Message:  "h-entry has no url property",
+					Path:     path,
+					Node:     item.Node(),
+				})
+			}
+			if len(item.Properties["published"]) == 0 {
+				findings = append(findings, Finding{
+					Rule:     RuleEntryMissingPublished,
+					Severity: Warning,
+					Message:  "h-entry has no published property",
+					Path:     path,
+					Node:     item.Node(),
+				})
+			}
+		case "h-card":
+			if len(item.Properties["name"]) == 0 {
+				findings = append(findings, Finding{
+					Rule:     RuleCardMissingName,
+					Severity: Warning,
+					Message:  "h-card has no name property",
+					Path:     path,
+					Node:     item.Node(),
+				})
+			}
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(item.Properties)) {
+		for i, value := range item.Properties[name] {
+			vpath := fmt.Sprintf("%s.properties.%s[%d]", path, name, i)
+			if mf, ok := value.(*microformats.Microformat); ok {
+				findings = checkItem(findings, mf, vpath)
+				continue
+			}
+
+			s, ok := stringValue(value)
+			if !ok {
+				continue
+			}
+			if datetimeProperties[name] {
+				if _, ok := microformats.ParseDateTime(s); !ok {
+					findings = append(findings, Finding{
+						Rule:     RuleInvalidDatetime,
+						Severity: Error,
+						Message:  fmt.Sprintf("%s value %q is not a valid datetime", name, s),
+						Path:     vpath,
+						Node:     item.Node(),
+					})
+				}
+			}
+			if urlProperties[name] && !isAbsoluteURL(s) {
+				findings = append(findings, Finding{
+					Rule:     RuleRelativeURL,
+					Severity: Warning,
+					Message:  fmt.Sprintf("%s value %q is not an absolute URL", name, s),
+					Path:     vpath,
+					Node:     item.Node(),
+				})
+			}
+		}
+	}
+
+	for i, child := range item.Children {
+		findings = checkItem(findings, child, fmt.Sprintf("%s.children[%d]", path, i))
+	}
+
+	return findings
+}
+
+// stringValue returns the plain text value of a property value, which may be
+// a string or a map with a "value" key.
+func stringValue(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case map[string]string:
+		s, ok := v["value"]
+		return s, ok
+	}
+	return "", false
+}
+
+// isAbsoluteURL returns whether s is a valid absolute URL.
+func isAbsoluteURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.IsAbs()
+}
+
+var (
+	rootClassNames     = regexp.MustCompile(`^h-([a-z0-9]+-)?[a-z]+(-[a-z]+)*$`)
+	propertyClassNames = regexp.MustCompile(`^(p|u|dt|e)-([a-z0-9]+-)?[a-z]+(-[a-z]+)*$`)
+
+	// backcompatRootClassNames maps microformats v1 root class names to
+	// the equivalent v2 root class names.
+	backcompatRootClassNames = map[string]string{
+		"adr":               "h-adr",
+		"geo":               "h-geo",
+		"hentry":            "h-entry",
+		"hfeed":             "h-feed",
+		"hnews":             "h-news",
+		"hproduct":          "h-product",
+		"hrecipe":           "h-recipe",
+		"hresume":           "h-resume",
+		"hreview":           "h-review",
+		"hreview-aggregate": "h-review-aggregate",
+		"vcard":             "h-card",
+		"vevent":            "h-event",
+	}
+)
+
+// CheckNode inspects the HTML document rooted at doc and returns any
+// findings.  Unlike Check, CheckNode can identify problems with how
+// microformats are marked up, even if they result in no parsed data.
+func CheckNode(doc *html.Node) []Finding {
+	if doc == nil {
+		return nil
+	}
+	return checkNode(nil, doc, false)
+}
+
+// checkNode appends findings for node and its descendants to findings.
+// inRoot is whether node is inside a microformats v2 root element.
+func checkNode(findings []Finding, node *html.Node, inRoot bool) []Finding {
+	if node.Type == html.ElementNode && node.DataAtom == atom.Template {
+		return findings
+	}
+
+	var hasRoot, hasProperty bool
+	var eProperty bool
+	var roots, v1Roots []string
+	for _, class := range strings.Fields(getAttr(node, "class")) {
+		switch {
+		case rootClassNames.MatchString(class):
+			hasRoot = true
+			roots = append(roots, class)
+		case propertyClassNames.MatchString(class):
+			hasProperty = true
+			if strings.HasPrefix(class, "e-") {
+				eProperty = true
+			}
+		case backcompatRootClassNames[class] != "":
+			v1Roots = append(v1Roots, class)
+		}
+	}
+
+	if msg := mixedVersionsMessage(roots, v1Roots, hasProperty); msg != "" {
+		findings = append(findings, Finding{
+			Rule:     RuleMixedVersions,
+			Severity: Info,
+			Message:  msg,
+			Path:     NodePath(node),
+			Node:     node,
+		})
+	}
+	if hasProperty && !inRoot {
+		findings = append(findings, Finding{
+			Rule:     RuleOrphanProperty,
+			Severity: Warning,
+			Message:  "property class is not inside any microformats2 root",
+			Path:     NodePath(node),
+			Node:     node,
+		})
+	}
+	if eProperty && inRoot {
+		findings = checkRelativeLinks(findings, node)
+	}
+
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		findings = checkNode(findings, c, inRoot || hasRoot)
+	}
+	return findings
+}
+
+// mixedVersionsMessage returns the message describing how the parser handles
+// an element with the v2 root classes roots, the v1 root classes v1Roots,
+// and a v2 property class if hasProperty is true.  It returns an empty string
+// if the classes are not mixed, or if each v1 root is paired with the
+// equivalent v2 root, which is the recommended way to support older parsers.
+func mixedVersionsMessage(roots, v1Roots []string, hasProperty bool) string {
+	if len(roots) > 0 {
+		var ignored []string
+		for _, class := range v1Roots {
+			if !slices.Contains(roots, backcompatRootClassNames[class]) {
+				ignored = append(ignored, class)
+			}
+		}
+		if len(ignored) == 0 {
+			return ""
+		}
+		return fmt.Sprintf("v1 root class %s is ignored because the element has a microformats2 root class; v1 properties of this item will be ignored",
+			strings.Join(ignored, ", "))
+	}
+	if hasProperty && len(v1Roots) > 0 {
+		return fmt.Sprintf("element has a microformats2 property class and v1 root class %s; the property value will be parsed as a v1 microformat, using v1 property classes",
+			strings.Join(v1Roots, ", "))
+	}
+	return ""
+}
+
+// checkRelativeLinks appends findings for elements within node that link to
+// or embed relative URLs.  Nested e-* properties are not descended into,
+// since checkNode checks them separately.
+func checkRelativeLinks(findings []Finding, node *html.Node) []Finding {
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		for _, attr := range []string{"href", "src"} {
+			if v, ok := attrValue(c, attr); ok && !isAbsoluteURL(strings.TrimSpace(v)) && !strings.HasPrefix(v, "#") {
+				findings = append(findings, Finding{
+					Rule:     RuleRelativeContentLink,
+					Severity: Info,
+					Message:  fmt.Sprintf("%s %q in content is relative and may break when syndicated", attr, v),
+					Path:     NodePath(c),
+					Node:     c,
+				})
+			}
+		}
+		if !isEProperty(c) {
+			findings = checkRelativeLinks(findings, c)
+		}
+	}
+	return findings
+}
+
+// isEProperty returns whether node has an e-* property class.
+func isEProperty(node *html.Node) bool {
+	for _, class := range strings.Fields(getAttr(node, "class")) {
+		if strings.HasPrefix(class, "e-") && propertyClassNames.MatchString(class) {
+			return true
+		}
+	}
+	return false
+}
+
+// NodePath returns a path identifying node within its document, such as
+// "/html/body/div[2]".  Element positions are only included when an element
+// has siblings with the same name.
+func NodePath(node *html.Node) string {
+	var parts []string
+	for n := node; n != nil && n.Type == html.ElementNode; n = n.Parent {
+		var pos, count int
+		if n.Parent != nil {
+			for c := n.Parent.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode && c.Data == n.Data {
+					count++
+					if c == n {
+						pos = count
+					}
+				}
+			}
+		}
+		if count > 1 {
+			parts = append(parts, fmt.Sprintf("%s[%d]", n.Data, pos))
+		} else {
+			parts = append(parts, n.Data)
+		}
+	}
+
+	var b strings.Builder
+	for i := len(parts) - 1; i >= 0; i-- {
+		b.WriteString("/")
+		b.WriteString(parts[i])
+	}
+	return b.String()
+}
+
+// getAttr returns the value of the specified attribute on node.
+func getAttr(node *html.Node, name string) string {
+	v, _ := attrValue(node, name)
+	return v
+}
+
+// attrValue returns the value of the specified attribute on node, and whether
+// the attribute was present.
+func attrValue(node *html.Node, name string) (string, bool) {
+	for _, attr := range node.Attr {
+		if strings.EqualFold(attr.Key, name) {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,188 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package lint
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+	"willnorris.com/go/microformats"
+)
+
+// findingIDs returns the rule and path of each finding, for easy comparison.
+func findingIDs(findings []Finding) []string {
+	var ids []string
+	for _, f := range findings {
+		ids = append(ids, f.Rule+" "+f.Path)
+	}
+	return ids
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want []string
+	}{
+		{
+			name: "valid entry",
+			html: `<article class="h-entry">
+			  <a class="u-url" href="/post"><time class="dt-published" datetime="2024-01-02">Jan 2</time></a>
+			  <p class="p-author h-card">Alice</p>
+			</article>`,
+		},
+		{
+			name: "entry missing url and published",
+			html: `<article class="h-entry"><p class="p-name">Hello</p></article>`,
+			want: []string{
+				"entry-missing-url items[0]",
+				"entry-missing-published items[0]",
+			},
+		},
+		{
+			name: "invalid datetime",
+			html: `<div class="h-event"><p class="p-name">Party</p><p class="dt-start">next tuesday</p></div>`,
+			want: []string{"invalid-datetime items[0].properties.start[0]"},
+		},
+		{
+			name: "nested card without name",
+			html: `<div class="h-entry">
+			  <a class="u-url" href="/">x</a><time class="dt-published">2024-01-02</time>
+			  <div class="p-author h-card"><p class="p-org">Acme</p></div>
+			</div>`,
+			want: []string{"card-missing-name items[0].properties.author[0]"},
+		},
+		{
+			name: "child card without name",
+			html: `<div class="h-feed"><p class="p-name">Feed</p><div class="h-card"><p class="p-org">Acme</p></div></div>`,
+			want: []string{"card-missing-name items[0].children[0]"},
+		},
+	}
+
+	base, _ := url.Parse("https://example.com/")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := microformats.Parse(strings.NewReader(tt.html), base)
+			got := findingIDs(Check(data))
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Check() findings differ:\n%s", diff)
+			}
+		})
+	}
+
+	// findings identify the element of the microformat they were found in
+	data := microformats.Parse(strings.NewReader(`<article class="h-entry" id="post"><p class="p-name">Hello</p></article>`), base)
+	for _, f := range Check(data) {
+		if f.Node == nil {
+			t.Errorf("Check() finding %q has no node", f.Rule)
+		} else if got := getAttr(f.Node, "id"); got != "post" {
+			t.Errorf("Check() finding %q has node with id %q, want %q", f.Rule, got, "post")
+		}
+	}
+
+	// relative URLs are only present in parsed data if no base URL is known
+	data = &microformats.Data{Items: []*microformats.Microformat{{
+		Type: []string{"h-card"},
+		Properties: map[string][]any{
+			"name":  {"Bob"},
+			"photo": {map[string]string{"value": "https://example.com/bob.jpg", "alt": "Bob"}},
+			"url":   {"/bob"},
+		},
+	}}}
+	want := []string{"relative-url items[0].properties.url[0]"}
+	if diff := cmp.Diff(want, findingIDs(Check(data))); diff != "" {
+		t.Errorf("Check() findings differ:\n%s", diff)
+	}
+
+	if got := Check(nil); got != nil {
+		t.Errorf("Check(nil) returned %v, want nil", got)
+	}
+}
+
+func TestCheckNode(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want []string
+	}{
+		{
+			name: "valid",
+			html: `<div class="h-entry"><div class="e-content"><a href="https://example.com/">x</a></div></div>`,
+		},
+		{
+			name: "v1 root with equivalent v2 root",
+			html: `<div class="h-card vcard"><p class="p-name fn">Alice</p></div>`,
+		},
+		{
+			name: "v1 root with different v2 root",
+			html: `<div class="h-card vevent"><p class="fn">Alice</p></div>`,
+			want: []string{"mixed-versions /html/body/div"},
+		},
+		{
+			name: "v1 root with v2 property",
+			html: `<div class="h-entry"><div class="p-author vcard"><p class="fn">Alice</p></div></div>`,
+			want: []string{"mixed-versions /html/body/div/div"},
+		},
+		{
+			name: "orphan property",
+			html: `<p class="p-name">Alice</p><div class="h-card"><p class="p-name">Bob</p></div>`,
+			want: []string{"orphan-property /html/body/p"},
+		},
+		{
+			name: "relative content link",
+			html: `<div class="h-entry"><div class="e-content">
+			  <a href="/about">about</a> <a href="#top">top</a>
+			  <p><img src="photo.jpg"></p>
+			</div></div>`,
+			want: []string{
+				"relative-content-link /html/body/div/div/a[1]",
+				"relative-content-link /html/body/div/div/p/img",
+			},
+		},
+		{
+			name: "relative link in nested content",
+			html: `<div class="h-entry"><div class="e-content">
+			  <div class="p-comment h-cite"><div class="e-content"><a href="/reply">reply</a></div></div>
+			</div></div>`,
+			want: []string{"relative-content-link /html/body/div/div/div/div/a"},
+		},
+		{
+			name: "template ignored",
+			html: `<template><p class="p-name">Alice</p></template>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("error parsing html: %v", err)
+			}
+			got := findingIDs(CheckNode(doc))
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("CheckNode() findings differ:\n%s", diff)
+			}
+		})
+	}
+
+	if got := CheckNode(nil); got != nil {
+		t.Errorf("CheckNode(nil) returned %v, want nil", got)
+	}
+}
+
+func TestFinding_JSON(t *testing.T) {
+	f := Finding{Rule: RuleOrphanProperty, Severity: Warning, Message: "m", Path: "/html"}
+	b, err := json.Marshal(f)
+	if err != nil {
+		t.Fatalf("json.Marshal returned error: %v", err)
+	}
+	want := `{"rule":"orphan-property","severity":"warning","message":"m","path":"/html"}`
+	if got := string(b); got != want {
+		t.Errorf("json.Marshal(%v) returned %s, want %s", f, got, want)
+	}
+}
@@ -9,7 +9,11 @@
 //	import "willnorris.com/go/microformats"
 //
 // Retrieve the HTML contents of a page, and call Parse or ParseNode, depending
-// on what input you have (an io.Reader or an html.Node).
+// on what input you have (an io.Reader or an html.Node).  If the page may not
+// be encoded as UTF-8, call ParseWithContentType instead of Parse.
+//
+// To change how documents are parsed, such as to sanitize the html of e-*
+// properties, call the methods of the same names on ParseOptions.
 //
 // To parse only a section of an HTML document, use a package like goquery to
 // select the root node to parse from.  For example, see cmd/gomf/main.go.
@@ -19,15 +23,18 @@ package microformats // import "willnorris.com/go/microformats"
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"net/url"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
 )
 
 var (
@@ -56,6 +63,18 @@ type Microformat struct {
 
 	// whether this is a v1 microformat parsed in backwards compatible mode
 	backcompat bool
+
+	// number of property values, for enforcing Limits.MaxProperties
+	propertyValues int
+
+	// the element this microformat was parsed from
+	node *html.Node
+}
+
+// Node returns the HTML element that m was parsed from, or nil if m was not
+// created by the parser.
+func (m *Microformat) Node() *html.Node {
+	return m.node
 }
 
 // Data specifies all of the microformats and data parsed from a single HTML
@@ -82,6 +101,57 @@ type Data struct {
 	// the metadata for the first link is included here.  Relative URL
 	// values are resolved to absolute URLs using the base URL of the page.
 	RelURLs map[string]*RelURL `json:"rel-urls"`
+
+	// Diagnostics lists problems found while parsing the page, such as
+	// values omitted because of the ParseOptions used.  Diagnostics are
+	// not part of the microformats JSON format, and are not encoded.
+	Diagnostics []Diagnostic `json:"-"`
+}
+
+// Diagnostic describes a problem found while parsing a page.
+type Diagnostic struct {
+	// Code identifies the kind of problem, such as DiagnosticURLScheme.
+	Code string
+
+	// Message describes the problem.
+	Message string
+
+	// Node is the element the problem was found on, if any.
+	Node *html.Node
+}
+
+// Diagnostic codes.
+const (
+	// DiagnosticURLScheme is reported for URLs with a scheme that is not
+	// allowed by a URLPolicy.
+	DiagnosticURLScheme = "url-scheme"
+
+	// DiagnosticMaxDepth is reported if elements were ignored because
+	// they are nested more deeply than Limits.MaxDepth.
+	DiagnosticMaxDepth = "max-depth"
+
+	// DiagnosticMaxItems is reported if microformats were ignored because
+	// there are more than Limits.MaxItems.
+	DiagnosticMaxItems = "max-items"
+
+	// DiagnosticMaxProperties is reported if property values were
+	// omitted because an item has more than Limits.MaxProperties.
+	DiagnosticMaxProperties = "max-properties"
+
+	// DiagnosticMaxPropertyBytes is reported if property values were
+	// omitted because they exceed Limits.MaxPropertyBytes.
+	DiagnosticMaxPropertyBytes = "max-property-bytes"
+
+	// DiagnosticMaxIncludes is reported if include patterns were ignored
+	// because there are more than Limits.MaxIncludes.
+	DiagnosticMaxIncludes = "max-includes"
+)
+
+func (d Diagnostic) String() string {
+	if d.Node != nil && d.Node.Type == html.ElementNode {
+		return fmt.Sprintf("%s: <%s>: %s", d.Code, d.Node.Data, d.Message)
+	}
+	return fmt.Sprintf("%s: %s", d.Code, d.Message)
 }
 
 // RelURL represents the attributes of a URL.  The URL value itself is the map
@@ -95,6 +165,126 @@ type RelURL struct {
 	Type     string   `json:"type,omitempty"`
 }
 
+// ParseOptions configures how documents are parsed.  The zero value parses
+// documents in the same way as the Parse, ParseWithContentType, and
+// ParseNode functions.
+type ParseOptions struct {
+	// Sanitizer, if not nil, sanitizes the html of e-* properties.  It
+	// is called with a copy of each e-* property element, so the parsed
+	// document is not modified.
+	Sanitizer Sanitizer
+
+	// URLPolicy, if not nil, restricts the schemes of URLs in u-*
+	// properties, implied url and photo properties, rels, and the URL
+	// attributes of e-* html.
+	URLPolicy *URLPolicy
+
+	// Limits bounds the resources used to parse a document.  The zero
+	// value imposes no limits.
+	Limits Limits
+}
+
+// Limits bounds the resources used to parse a document, which is useful
+// when parsing untrusted content.  A zero value for any field means no
+// limit.  Content exceeding a limit is ignored, and a Diagnostic is
+// reported for each limit that is reached.
+type Limits struct {
+	// MaxDepth is the maximum depth of nodes in the document.  Deeper
+	// nodes are ignored.
+	MaxDepth int
+
+	// MaxItems is the maximum number of microformats, including nested
+	// microformats.  Elements of additional microformats are ignored.
+	MaxItems int
+
+	// MaxProperties is the maximum number of property values of each
+	// microformat.  Additional values are omitted.
+	MaxProperties int
+
+	// MaxPropertyBytes is the maximum total size in bytes of all property
+	// values, including the html of e-* properties.  Values which would
+	// exceed the limit are omitted.
+	MaxPropertyBytes int
+
+	// MaxIncludes is the maximum number of elements included using the
+	// microformats v1 include pattern.  Additional includes are ignored.
+	MaxIncludes int
+}
+
+// DefaultLimits returns limits suitable for parsing untrusted documents,
+// which are generous enough for typical web pages.
+func DefaultLimits() Limits {
+	return Limits{
+		MaxDepth:         512,
+		MaxItems:         10000,
+		MaxProperties:    1000,
+		MaxPropertyBytes: 50 << 20,
+		MaxIncludes:      1000,
+	}
+}
+
+// URLPolicy restricts the schemes of parsed URLs, such as to exclude
+// javascript: URLs.  Each disallowed URL is reported as a Diagnostic with
+// the code DiagnosticURLScheme, and handled as specified by Action.
+type URLPolicy struct {
+	// Schemes are the allowed URL schemes, such as "http" and "https".
+	// Schemes are compared case-insensitively.  URLs without a scheme,
+	// which are relative URLs that could not be resolved, are always
+	// allowed.
+	Schemes []string
+
+	// Action specifies how disallowed URLs are handled.
+	Action URLAction
+}
+
+// URLAction specifies how URLs disallowed by a URLPolicy are handled.
+type URLAction int
+
+const (
+	// DropURL omits disallowed URLs.  u-* property values, implied url
+	// and photo values, and rels with a disallowed URL are omitted, and
+	// nested microformats parsed from a u-* property have an empty
+	// value.  Attributes of e-* html with a disallowed URL are removed.
+	DropURL URLAction = iota
+
+	// KeepURLAsText replaces disallowed URLs in u-* property values with
+	// the text of the element, as for p-* properties.  Other disallowed
+	// URLs are handled as for DropURL.
+	KeepURLAsText
+
+	// FlagURL keeps disallowed URLs, only reporting them as diagnostics.
+	FlagURL
+)
+
+// allowed returns whether the scheme of u is allowed by policy.  URLs that
+// cannot be parsed are not allowed.
+func (policy *URLPolicy) allowed(u string) bool {
+	if policy == nil {
+		return true
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	if parsed.Scheme == "" {
+		return true
+	}
+	for _, scheme := range policy.Schemes {
+		if strings.EqualFold(scheme, parsed.Scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sanitizer removes unsafe markup from HTML.  The sanitize package provides
+// an implementation using an allowlist policy.
+type Sanitizer interface {
+	// Sanitize removes unsafe markup from the descendants of node,
+	// modifying them in place.
+	Sanitize(node *html.Node)
+}
+
 // parser parses a single HTML page for microformats.  parser is not thread
 // safe, and should only be used to parse a single document.
 type parser struct {
@@ -102,9 +292,27 @@ type parser struct {
 	curItem   *Microformat
 	base      *url.URL
 	baseFound bool
+	opts      ParseOptions
+
+	// diagnostics already reported, to avoid duplicates when the same
+	// element is processed more than once
+	reported map[Diagnostic]bool
+
+	// resources used, for enforcing opts.Limits
+	items         int
+	propertyBytes int
+	includes      int
 
 	// root node of the parsed document
 	root *html.Node
+
+	// nodes at the maximum depth whose children are ignored, for
+	// enforcing opts.Limits.MaxDepth
+	truncated map[*html.Node]bool
+
+	// original attributes of nodes whose attributes were removed by the
+	// URL policy, restored once parsing is complete
+	savedAttrs map[*html.Node][]html.Attribute
 }
 
 // Parse the microformats found in the HTML document read from r.  baseURL is
@@ -112,8 +320,38 @@ type parser struct {
 // relative URLs.  If baseURL is nil and the base URL is not referenced in the
 // document, relative URLs are not expanded.
 func Parse(r io.Reader, baseURL *url.URL) *Data {
+	return ParseOptions{}.Parse(r, baseURL)
+}
+
+// Parse is like the Parse function, but uses the options in o.
+func (o ParseOptions) Parse(r io.Reader, baseURL *url.URL) *Data {
 	doc, _ := html.Parse(r)
-	return ParseNode(doc, baseURL)
+	return o.ParseNode(doc, baseURL)
+}
+
+// ParseWithContentType parses the microformats found in the HTML document
+// read from r, converting it to UTF-8 first if necessary.  The character
+// encoding of the document is determined from contentType (typically the
+// value of an HTTP Content-Type header, which may be empty), a byte order
+// mark, or a <meta charset> element in the document, following the HTML
+// encoding sniffing algorithm.  If no encoding can be determined,
+// windows-1252 is assumed, unless the document is valid UTF-8.
+//
+// baseURL is used to expand any relative URLs, as described for Parse.
+func ParseWithContentType(r io.Reader, contentType string, baseURL *url.URL) *Data {
+	return ParseOptions{}.ParseWithContentType(r, contentType, baseURL)
+}
+
+// ParseWithContentType is like the ParseWithContentType function, but uses
+// the options in o.
+func (o ParseOptions) ParseWithContentType(r io.Reader, contentType string, baseURL *url.URL) *Data {
+	cr, err := charset.NewReader(r, contentType)
+	if err != nil {
+		// error reading the start of the document to sniff its encoding;
+		// html.Parse will most likely encounter the same error.
+		return o.Parse(r, baseURL)
+	}
+	return o.Parse(cr, baseURL)
 }
 
 // ParseNode parses the microformats found in doc.  baseURL is the URL this
@@ -121,10 +359,15 @@ func Parse(r io.Reader, baseURL *url.URL) *Data {
 // baseURL is nil and the base URL is not referenced in the document,
 // relative URLs are not expanded.
 func ParseNode(doc *html.Node, baseURL *url.URL) *Data {
+	return ParseOptions{}.ParseNode(doc, baseURL)
+}
+
+// ParseNode is like the ParseNode function, but uses the options in o.
+func (o ParseOptions) ParseNode(doc *html.Node, baseURL *url.URL) *Data {
 	if doc == nil { // makes no sense to go further
 		return nil
 	}
-	p := new(parser)
+	p := &parser{opts: o}
 	p.curData = &Data{
 		Items:   make([]*Microformat, 0),
 		Rels:    make(map[string][]string),
@@ -136,10 +379,123 @@ func ParseNode(doc *html.Node, baseURL *url.URL) *Data {
 	}
 	p.baseFound = false
 	p.root = doc
+	defer p.restoreAttrs()
+	if o.Limits.MaxDepth > 0 {
+		p.limitDepth(doc, o.Limits.MaxDepth)
+	}
 	p.walk(doc)
 	return p.curData
 }
 
+// limitDepth records the nodes in doc at depth maxDepth which have
+// children, so that deeper nodes are skipped while parsing.  The document is
+// not modified, and is traversed without recursion, so that it can be
+// arbitrarily deep.
+func (p *parser) limitDepth(doc *html.Node, maxDepth int) {
+	n, depth := doc.FirstChild, 1
+	for n != nil {
+		if depth >= maxDepth && n.FirstChild != nil {
+			if p.truncated == nil {
+				p.truncated = make(map[*html.Node]bool)
+			}
+			p.truncated[n] = true
+		} else if n.FirstChild != nil {
+			n, depth = n.FirstChild, depth+1
+			continue
+		}
+		for n != doc && n.NextSibling == nil {
+			n, depth = n.Parent, depth-1
+		}
+		if n == doc {
+			break
+		}
+		n = n.NextSibling
+	}
+
+	if len(p.truncated) > 0 {
+		p.report(Diagnostic{
+			Code:    DiagnosticMaxDepth,
+			Message: fmt.Sprintf("nodes nested more than %d deep were ignored", maxDepth),
+		})
+	}
+}
+
+// children returns the first child of node, or nil if node has no children
+// or they are nested too deeply to be parsed.  The remaining children are
+// reached through NextSibling as usual.
+func (p *parser) children(node *html.Node) *html.Node {
+	if p.truncated[node] {
+		return nil
+	}
+	return node.FirstChild
+}
+
+// addProperty appends value to the named property of item, unless it would
+// exceed the limits of p.
+func (p *parser) addProperty(item *Microformat, name string, value any) {
+	limits := p.opts.Limits
+	if limits.MaxProperties > 0 {
+		if item.propertyValues >= limits.MaxProperties {
+			p.report(Diagnostic{
+				Code:    DiagnosticMaxProperties,
+				Message: fmt.Sprintf("property values beyond %d per item were omitted", limits.MaxProperties),
+			})
+			return
+		}
+	}
+	if limits.MaxPropertyBytes > 0 {
+		size := valueSize(value)
+		if p.propertyBytes+size > limits.MaxPropertyBytes {
+			p.report(Diagnostic{
+				Code:    DiagnosticMaxPropertyBytes,
+				Message: fmt.Sprintf("property values beyond %d bytes were omitted", limits.MaxPropertyBytes),
+			})
+			return
+		}
+		p.propertyBytes += size
+	}
+	item.Properties[name] = append(item.Properties[name], value)
+	item.propertyValues++
+}
+
+// errLimitExceeded is returned by limitWriter once its limit is reached.
+var errLimitExceeded = errors.New("microformats: limit exceeded")
+
+// limitWriter writes to w until n bytes have been written, returning
+// errLimitExceeded for writes beyond the limit.
+type limitWriter struct {
+	w io.Writer
+	n int
+}
+
+func (l *limitWriter) Write(b []byte) (int, error) {
+	if len(b) > l.n {
+		l.n = 0
+		return 0, errLimitExceeded
+	}
+	l.n -= len(b)
+	return l.w.Write(b)
+}
+
+// valueSize returns the size in bytes of property value v.  The properties
+// of nested microformats are not included, as they are counted when they
+// are parsed.
+func valueSize(v any) int {
+	switch v := v.(type) {
+	case string:
+		return len(v)
+	case map[string]string:
+		var size int
+		for _, s := range v {
+			size += len(s)
+		}
+		return size
+	case *Microformat:
+		return len(v.Value) + len(v.HTML)
+	}
+	return 0
+}
+
 // expandAttrURLs expands relative URLs in attributes to be absolute URLs.
 // Attributes are taken from https://html.spec.whatwg.org/multipage/indices.html#attributes-3.
 func (p *parser) expandAttrURLs(node *html.Node) {
@@ -169,18 +525,77 @@ func (p *parser) expandAttrURLs(node *html.Node) {
 		attr = append(attr, "poster")
 	}
 
+	// check every URL before changing any attribute, so that the original
+	// attributes can be saved if any are removed
+	type attrURL struct {
+		name, url string
+		allowed   bool
+	}
+	var urls []attrURL
 	for _, a := range attr {
-		value := getAttrPtr(node, a)
-		if value != nil {
-			*value = expandURL(*value, p.base)
+		if value := getAttrPtr(node, a); value != nil {
+			u := expandURL(*value, p.base)
+			urls = append(urls, attrURL{a, u, p.checkURL(u, node)})
+		}
+	}
+	if slices.ContainsFunc(urls, func(u attrURL) bool { return !u.allowed }) {
+		if _, ok := p.savedAttrs[node]; !ok {
+			if p.savedAttrs == nil {
+				p.savedAttrs = make(map[*html.Node][]html.Attribute)
+			}
+			p.savedAttrs[node] = slices.Clone(node.Attr)
+		}
+	}
+	for _, u := range urls {
+		if u.allowed {
+			*getAttrPtr(node, u.name) = u.url
+		} else {
+			removeAttr(node, u.name)
 		}
 	}
 
-	for c := node.FirstChild; c != nil; c = c.NextSibling {
+	for c := p.children(node); c != nil; c = c.NextSibling {
 		p.expandAttrURLs(c)
 	}
 }
 
+// restoreAttrs restores the attributes of nodes which were removed while
+// parsing.
+func (p *parser) restoreAttrs() {
+	for node, attrs := range p.savedAttrs {
+		node.Attr = attrs
+	}
+}
+
+// checkURL returns whether URL u found on node is allowed by the URL policy
+// of p, or is disallowed but should be kept anyway.  A diagnostic is
+// reported for each disallowed URL.
+func (p *parser) checkURL(u string, node *html.Node) bool {
+	policy := p.opts.URLPolicy
+	if policy.allowed(u) {
+		return true
+	}
+	p.report(Diagnostic{
+		Code:    DiagnosticURLScheme,
+		Message: fmt.Sprintf("URL %q has a disallowed scheme", u),
+		Node:    node,
+	})
+	return policy.Action == FlagURL
+}
+
+// report adds d to the diagnostics of the parsed data, unless it has
+// already been reported.
+func (p *parser) report(d Diagnostic) {
+	if p.reported[d] {
+		return
+	}
+	if p.reported == nil {
+		p.reported = make(map[Diagnostic]bool)
+	}
+	p.reported[d] = true
+	p.curData.Diagnostics = append(p.curData.Diagnostics, d)
+}
+
 // expandURL expands relative URL r into an absolute URL by resolving it relative to
 // base. If r is not a valid URL or base is nil, the original r value is returned.
 func expandURL(r string, base *url.URL) string {
@@ -221,11 +636,20 @@ func (p *parser) walk(node *html.Node) {
 	}
 
 	if len(rootclasses) > 0 {
+		if limit := p.opts.Limits.MaxItems; limit > 0 && p.items >= limit {
+			p.report(Diagnostic{
+				Code:    DiagnosticMaxItems,
+				Message: fmt.Sprintf("microformats beyond the first %d were ignored", limit),
+			})
+			return
+		}
+		p.items++
 		sort.Strings(rootclasses)
 		curItem = &Microformat{
 			Type:       rootclasses,
 			Properties: make(map[string][]any),
 			backcompat: backcompat,
+			node:       node,
 		}
 		if !backcompat {
 			curItem.ID = getAttr(node, "id")
@@ -259,10 +683,8 @@ func (p *parser) walk(node *html.Node) {
 
 	var rels []string
 	if isAtom(node, atom.A, atom.Link) {
-		if rel := getAttr(node, "rel"); rel != "" {
-			urlVal := getAttr(node, "href")
-			urlVal = expandURL(urlVal, p.base)
-
+		urlVal := expandURL(getAttr(node, "href"), p.base)
+		if rel := getAttr(node, "rel"); rel != "" && p.checkURL(urlVal, node) {
 			rels = strings.Fields(rel)
 			for _, relval := range rels {
 				var seen bool // whether we've already stored this url for this rel
@@ -279,7 +701,7 @@ func (p *parser) walk(node *html.Node) {
 			if _, ok := p.curData.RelURLs[urlVal]; !ok {
 				sort.Strings(rels)
 				p.curData.RelURLs[urlVal] = &RelURL{
-					Text:     getTextContent(node, nil),
+					Text:     p.getTextContent(node, nil),
 					Rels:     rels,
 					Media:    getAttr(node, "media"),
 					HrefLang: getAttr(node, "hreflang"),
@@ -290,7 +712,7 @@ func (p *parser) walk(node *html.Node) {
 		}
 	}
 
-	for c := node.FirstChild; c != nil; c = c.NextSibling {
+	for c := p.children(node); c != nil; c = c.NextSibling {
 		p.walk(c)
 	}
 
@@ -305,30 +727,36 @@ func (p *parser) walk(node *html.Node) {
 			// Now process implied property values.
 			if _, ok := curItem.Properties["name"]; !ok {
 				if !curItem.hasNestedMicroformats && !curItem.hasPProperties && !curItem.hasEProperties {
-					name := getImpliedName(node)
+					name := p.getImpliedName(node)
 					if name != "" {
-						curItem.Properties["name"] = append(curItem.Properties["name"], name)
+						p.addProperty(curItem, "name", name)
 					}
 				}
 			}
 			if _, ok := curItem.Properties["photo"]; !ok {
 				if !curItem.hasNestedMicroformats && !curItem.hasUProperties {
 					photo, alt := getImpliedPhoto(node, p.base)
+					if photo != "" && !p.checkURL(photo, node) {
+						photo, alt = "", ""
+					}
 					if alt != "" {
-						curItem.Properties["photo"] = append(curItem.Properties["photo"], map[string]string{
+						p.addProperty(curItem, "photo", map[string]string{
 							"alt":   alt,
 							"value": photo,
 						})
 					} else if photo != "" {
-						curItem.Properties["photo"] = append(curItem.Properties["photo"], photo)
+						p.addProperty(curItem, "photo", photo)
 					}
 				}
 			}
 			if _, ok := curItem.Properties["url"]; !ok {
 				if !curItem.hasNestedMicroformats && !curItem.hasUProperties {
 					url := getImpliedURL(node, p.base)
+					if url != "" && !p.checkURL(url, node) {
+						url = ""
+					}
 					if url != "" {
-						curItem.Properties["url"] = append(curItem.Properties["url"], url)
+						p.addProperty(curItem, "url", url)
 					}
 				}
 			}
@@ -363,7 +791,7 @@ func (p *parser) walk(node *html.Node) {
 				if p.curItem != nil {
 					p.curItem.hasPProperties = true
 				}
-				value = getValueClassPattern(node)
+				value = p.getValueClassPattern(node)
 				if value == nil && isAtom(node, atom.Abbr, atom.Link) {
 					value = getAttrPtr(node, "title")
 				}
@@ -375,7 +803,7 @@ func (p *parser) walk(node *html.Node) {
 				}
 				if value == nil {
 					value = new(string)
-					*value = strings.TrimSpace(getTextContent(node, p.imageAltSrcValue))
+					*value = strings.TrimSpace(p.getTextContent(node, p.imageAltSrcValue))
 				}
 				if curItem != nil && p.curItem != nil {
 					embedValue = getFirstPropValue(curItem, "name")
@@ -405,7 +833,7 @@ func (p *parser) walk(node *html.Node) {
 					value = getAttrPtr(node, "poster")
 				}
 				if value == nil {
-					value = getValueClassPattern(node)
+					value = p.getValueClassPattern(node)
 				}
 				if value == nil && isAtom(node, atom.Abbr) {
 					value = getAttrPtr(node, "title")
@@ -415,11 +843,28 @@ func (p *parser) walk(node *html.Node) {
 				}
 				if value == nil {
 					value = new(string)
-					*value = strings.TrimSpace(getTextContent(node, nil))
+					*value = strings.TrimSpace(p.getTextContent(node, nil))
 				}
 				if value != nil {
 					*value = strings.TrimSpace(expandURL(*value, p.base))
 				}
+				if !p.checkURL(*value, node) {
+					// value may point to an attribute, so don't modify it
+					delete(propData, "alt")
+					switch {
+					case p.opts.URLPolicy.Action == KeepURLAsText:
+						value = new(string)
+						*value = strings.TrimSpace(p.getTextContent(node, imageAltValue))
+						if isAtom(node, atom.Img) {
+							*value = imageAltValue(node)
+						}
+					case curItem != nil && p.curItem != nil:
+						value = new(string)
+					default:
+						// omit the property value
+						continue
+					}
+				}
 				if curItem != nil && p.curItem != nil {
 					embedValue = getFirstPropValue(curItem, "url")
 				}
@@ -433,15 +878,38 @@ func (p *parser) walk(node *html.Node) {
 					p.curItem.hasEProperties = true
 				}
 				value = new(string)
-				*value = strings.TrimSpace(getTextContent(node, p.imageAltSrcValue))
+				*value = strings.TrimSpace(p.getTextContent(node, p.imageAltSrcValue))
 				var buf bytes.Buffer
 
-				for c := node.FirstChild; c != nil; c = c.NextSibling {
+				for c := p.children(node); c != nil; c = c.NextSibling {
 					p.expandAttrURLs(c) // microformats/microformats2-parsing#38
+				}
+				content := node
+				if p.opts.Sanitizer != nil || p.truncated != nil {
+					content = p.cloneTree(node)
+				}
+				if p.opts.Sanitizer != nil {
+					p.opts.Sanitizer.Sanitize(content)
+				}
 
-					// ignore errors from html.Render which nearly always result from being unable
+				// stop rendering once the html would exceed the
+				// property size limit, rather than rendering it all first
+				var w io.Writer = &buf
+				if limit := p.opts.Limits.MaxPropertyBytes; limit > 0 {
+					w = &limitWriter{w: &buf, n: limit - p.propertyBytes - len(*value)}
+				}
+				var err error
+				for c := content.FirstChild; c != nil && err == nil; c = c.NextSibling {
+					// other errors from html.Render nearly always result from being unable
 					// to write to the underlying io.Writer, which never happens with bytes.Buffer.
-					_ = html.Render(&buf, c)
+					err = html.Render(w, c)
+				}
+				if errors.Is(err, errLimitExceeded) {
+					p.report(Diagnostic{
+						Code:    DiagnosticMaxPropertyBytes,
+						Message: fmt.Sprintf("property values beyond %d bytes were omitted", p.opts.Limits.MaxPropertyBytes),
+					})
+					continue
 				}
 				htmlbody := strings.TrimSpace(buf.String())
 
@@ -453,7 +921,7 @@ func (p *parser) walk(node *html.Node) {
 				propData["html"] = htmlbody
 			case "dt":
 				if value == nil {
-					value = getDateTimeValue(node)
+					value = p.getDateTimeValue(node)
 				}
 				if value == nil && isAtom(node, atom.Time, atom.Ins, atom.Del) {
 					value = getAttrPtr(node, "datetime")
@@ -466,14 +934,14 @@ func (p *parser) walk(node *html.Node) {
 				}
 				if value == nil {
 					value = new(string)
-					*value = strings.TrimSpace(getTextContent(node, nil))
+					*value = strings.TrimSpace(p.getTextContent(node, nil))
 				}
 			}
 			if curItem != nil && p.curItem != nil {
 				if embedValue == nil {
 					embedValue = value
 				}
-				p.curItem.Properties[name] = append(p.curItem.Properties[name], &Microformat{
+				p.addProperty(p.curItem, name, &Microformat{
 					ID:         curItem.ID,
 					Type:       curItem.Type,
 					Properties: curItem.Properties,
@@ -481,13 +949,14 @@ func (p *parser) walk(node *html.Node) {
 					Shape:      curItem.Shape,
 					Value:      *embedValue,
 					HTML:       propData["html"],
+					node:       curItem.node,
 				})
 			} else if value != nil && p.curItem != nil {
 				if len(propData) > 0 {
 					propData["value"] = *value
-					p.curItem.Properties[name] = append(p.curItem.Properties[name], propData)
+					p.addProperty(p.curItem, name, propData)
 				} else {
-					p.curItem.Properties[name] = append(p.curItem.Properties[name], *value)
+					p.addProperty(p.curItem, name, *value)
 				}
 			}
 		}
@@ -540,6 +1009,13 @@ func getAttrPtr(node *html.Node, name string) *string {
 	return nil
 }
 
+// removeAttr removes the attributes of node with the specified name.
+func removeAttr(node *html.Node, name string) {
+	node.Attr = slices.DeleteFunc(node.Attr, func(attr html.Attribute) bool {
+		return strings.EqualFold(attr.Key, name)
+	})
+}
+
 // hasAttr returns whether node has an attribute with the specified name.
 func hasAttr(node *html.Node, name string) bool {
 	return getAttrPtr(node, name) != nil
@@ -562,7 +1038,7 @@ func isAtom(node *html.Node, atoms ...atom.Atom) bool {
 // microformats v2 algorithm.  Nested script and style elements are ignored,
 // and img elements are run through imgFn.  If imgFn is nil, img elements are
 // ignored as well.
-func getTextContent(node *html.Node, imgFn func(*html.Node) string) string {
+func (p *parser) getTextContent(node *html.Node, imgFn func(*html.Node) string) string {
 	if node == nil {
 		return ""
 	}
@@ -576,8 +1052,8 @@ func getTextContent(node *html.Node, imgFn func(*html.Node) string) string {
 		return node.Data
 	}
 	var buf bytes.Buffer
-	for c := node.FirstChild; c != nil; c = c.NextSibling {
-		buf.WriteString(getTextContent(c, imgFn))
+	for c := p.children(node); c != nil; c = c.NextSibling {
+		buf.WriteString(p.getTextContent(c, imgFn))
 	}
 	return buf.String()
 }
@@ -638,10 +1114,26 @@ func getOnlyChildAtom(node *html.Node, atom atom.Atom) *html.Node {
 	return n
 }
 
+// cloneTree makes a copy of node and all of its descendants that are not
+// nested too deeply, detached from any parent or sibling nodes.
+func (p *parser) cloneTree(node *html.Node) *html.Node {
+	clone := &html.Node{
+		Type:      node.Type,
+		DataAtom:  node.DataAtom,
+		Data:      node.Data,
+		Namespace: node.Namespace,
+		Attr:      slices.Clone(node.Attr),
+	}
+	for c := p.children(node); c != nil; c = c.NextSibling {
+		clone.AppendChild(p.cloneTree(c))
+	}
+	return clone
+}
+
 // getImpliedName gets the implied name value for node.
 //
 // See http://microformats.org/wiki/microformats2-parsing
-func getImpliedName(node *html.Node) string {
+func (p *parser) getImpliedName(node *html.Node) string {
 	var name *string
 
 	switch {
@@ -680,7 +1172,7 @@ func getImpliedName(node *html.Node) string {
 
 	if name == nil {
 		name = new(string)
-		*name = getTextContent(node, imageAltValue)
+		*name = p.getTextContent(node, imageAltValue)
 	}
 
 	return strings.TrimSpace(*name)
@@ -790,8 +1282,8 @@ func getImpliedURL(node *html.Node, baseURL *url.URL) string {
 // getValueClassPattern gets the value of node using the value class pattern.
 //
 // See http://microformats.org/wiki/value-class-pattern
-func getValueClassPattern(node *html.Node) *string {
-	values := parseValueClassPattern(node, false)
+func (p *parser) getValueClassPattern(node *html.Node) *string {
+	values := p.parseValueClassPattern(node, false)
 	if len(values) > 0 {
 		val := strings.Join(values, "")
 		return &val
@@ -801,12 +1293,12 @@ func getValueClassPattern(node *html.Node) *string {
 
 // parseValueClassPattern parses node for values using the value class pattern.
 // If dt is true, the rules for date and time parsing will be used.
-func parseValueClassPattern(node *html.Node, dt bool) []string {
+func (p *parser) parseValueClassPattern(node *html.Node, dt bool) []string {
 	if node == nil {
 		return nil
 	}
 	var values []string
-	for c := node.FirstChild; c != nil; c = c.NextSibling {
+	for c := p.children(node); c != nil; c = c.NextSibling {
 		classes := getClasses(c)
 		var valueClass, valueTitleClass bool
 		for _, class := range classes {
@@ -830,7 +1322,7 @@ func parseValueClassPattern(node *html.Node, dt bool) []string {
 			case dt && isAtom(c, atom.Del, atom.Ins, atom.Time) && hasAttr(c, "datetime"):
 				values = append(values, getAttr(c, "datetime"))
 			default:
-				values = append(values, strings.TrimSpace(getTextContent(c, nil)))
+				values = append(values, strings.TrimSpace(p.getTextContent(c, nil)))
 			}
 		}
 	}
@@ -8,9 +8,11 @@ import (
 	"net/url"
 	"regexp"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
 )
@@ -275,7 +277,7 @@ func Test_GetTextContent(t *testing.T) {
 			t.Fatalf("Error parsing HTML: %v", err)
 		}
 
-		if got, want := getTextContent(n, tt.imgFn), tt.content; got != want {
+		if got, want := new(parser).getTextContent(n, tt.imgFn), tt.content; got != want {
 			t.Errorf("getTextContent(%q) returned %q, want %q", tt.html, got, want)
 		}
 	}
@@ -396,7 +398,7 @@ func Test_GetImpliedName(t *testing.T) {
 			t.Fatalf("Error parsing HTML: %v", err)
 		}
 
-		if got, want := getImpliedName(n), tt.name; got != want {
+		if got, want := new(parser).getImpliedName(n), tt.name; got != want {
 			t.Errorf("getImpliedName(%q) returned %v, want %v", tt.html, got, want)
 		}
 	}
@@ -531,7 +533,7 @@ func Test_GetValueClassPattern(t *testing.T) {
 			t.Fatalf("Error parsing HTML: %v", err)
 		}
 
-		if got, want := getValueClassPattern(n), tt.value; !cmp.Equal(got, want) {
+		if got, want := new(parser).getValueClassPattern(n), tt.value; !cmp.Equal(got, want) {
 			t.Errorf("getValueClassPattern(%q) returned %v, want %v", tt.html, got, want)
 		}
 	}
@@ -581,3 +583,397 @@ func Test_ParseNodeNil(t *testing.T) {
 		})
 	}
 }
+
+func Test_ParseWithContentType(t *testing.T) {
+	tests := []struct {
+		name        string
+		html        string
+		contentType string
+		want        string
+	}{
+		{"utf-8", `<p class="h-card">café</p>`, "", "café"},
+		{"content-type", "<p class=\"h-card\">caf\xe9</p>", "text/html; charset=iso-8859-1", "café"},
+		{"meta charset", "<meta charset=\"windows-1252\"><p class=\"h-card\">caf\xe9</p>", "", "café"},
+		{"meta http-equiv", "<meta http-equiv=\"Content-Type\" content=\"text/html; charset=shift_jis\"><p class=\"h-card\">\x82\xa0</p>", "", "あ"},
+		{"bom", "\xef\xbb\xbf<p class=\"h-card\">café</p>", "text/html; charset=iso-8859-1", "café"},
+		{"invalid utf-8", "<p class=\"h-card\">caf\xe9</p>", "", "café"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := ParseWithContentType(strings.NewReader(tt.html), tt.contentType, nil)
+			if len(data.Items) != 1 {
+				t.Fatalf("ParseWithContentType(%q) returned %d items, want 1", tt.html, len(data.Items))
+			}
+			if got, want := data.Items[0].Properties["name"], []any{tt.want}; !cmp.Equal(got, want) {
+				t.Errorf("ParseWithContentType(%q) returned name %q, want %q", tt.html, got, want)
+			}
+		})
+	}
+}
+
+func Test_MicroformatNode(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<div class="h-feed" id="feed">
+	  <article class="h-entry" id="entry"><p class="p-author h-card" id="author">Alice</p></article>
+	</div>
+	<div class="vcard" id="legacy"><p class="fn">Bob</p></div>`))
+	if err != nil {
+		t.Fatalf("Error parsing HTML: %v", err)
+	}
+	data := ParseNode(doc, nil)
+	if len(data.Items) != 2 || len(data.Items[0].Children) != 1 {
+		t.Fatalf("ParseNode returned unexpected items: %+v", data.Items)
+	}
+	author, _ := data.Items[0].Children[0].Properties["author"][0].(*Microformat)
+
+	tests := []struct {
+		item *Microformat
+		id   string
+	}{
+		{data.Items[0], "feed"},
+		{data.Items[0].Children[0], "entry"},
+		{author, "author"},
+		{data.Items[1], "legacy"},
+	}
+	for _, tt := range tests {
+		if got := getAttr(tt.item.Node(), "id"); got != tt.id {
+			t.Errorf("Node() of %v returned element with id %q, want %q", tt.item.Type, got, tt.id)
+		}
+	}
+
+	if got := new(Microformat).Node(); got != nil {
+		t.Errorf("Node() of unparsed microformat returned %v, want nil", got)
+	}
+}
+
+// sanitizerFunc is a Sanitizer implemented by a function.
+type sanitizerFunc func(*html.Node)
+
+func (f sanitizerFunc) Sanitize(node *html.Node) { f(node) }
+
+func Test_ParseOptions_Sanitizer(t *testing.T) {
+	// remove all script elements
+	var removeScripts sanitizerFunc
+	removeScripts = func(node *html.Node) {
+		for c := node.FirstChild; c != nil; {
+			next := c.NextSibling
+			if isAtom(c, atom.Script) {
+				node.RemoveChild(c)
+			} else {
+				removeScripts(c)
+			}
+			c = next
+		}
+	}
+
+	doc, err := html.Parse(strings.NewReader(`<div class="h-entry"><div class="e-content">
+	  <p>Hello<script>alert(1)</script> <a href="/world">world</a></p></div></div>`))
+	if err != nil {
+		t.Fatalf("Error parsing HTML: %v", err)
+	}
+	base, _ := url.Parse("https://example.com/")
+	data := ParseOptions{Sanitizer: removeScripts}.ParseNode(doc, base)
+
+	want := []any{map[string]string{
+		"value": "Hello world",
+		"html":  `<p>Hello <a href="https://example.com/world">world</a></p>`,
+	}}
+	if diff := cmp.Diff(want, data.Items[0].Properties["content"]); diff != "" {
+		t.Errorf("ParseNode() content differs:\n%s", diff)
+	}
+
+	// the parsed document is not modified
+	var buf bytes.Buffer
+	_ = html.Render(&buf, doc)
+	if !strings.Contains(buf.String(), "<script>") {
+		t.Errorf("ParseNode() removed script from parsed document: %s", buf.String())
+	}
+}
+
+func Test_ParseOptions_URLPolicy(t *testing.T) {
+	input := `<div class="h-entry"><a class="u-url" href="javascript:alert(1)">Post</a>
+	  <img class="u-photo" src="data:image/png;base64,AAAA" alt="Photo">
+	  <a class="u-in-reply-to h-cite" href="vbscript:x">Reply</a>
+	  <a class="u-syndication" href="https://example.org/1">x</a>
+	  <div class="e-content"><a href="javascript:x">link</a></div></div>
+	<div class="h-card"><a href="JavaScript:x">Alice</a></div>
+	<a rel="me" href="javascript:x">me</a><a rel="home" href="/">home</a>`
+	base, _ := url.Parse("https://example.com/")
+	policy := func(action URLAction) ParseOptions {
+		return ParseOptions{URLPolicy: &URLPolicy{Schemes: []string{"http", "https"}, Action: action}}
+	}
+
+	tests := []struct {
+		opts      ParseOptions
+		wantEntry map[string][]any
+		wantCard  map[string][]any
+		wantRels  map[string][]string
+	}{
+		{
+			opts: policy(DropURL),
+			wantEntry: map[string][]any{
+				"in-reply-to": {&Microformat{Type: []string{"h-cite"}, Properties: map[string][]any{"name": {"Reply"}}}},
+				"syndication": {"https://example.org/1"},
+				"content":     {map[string]string{"value": "link", "html": "<a>link</a>"}},
+			},
+			wantCard: map[string][]any{"name": {"Alice"}},
+			wantRels: map[string][]string{"home": {"https://example.com/"}},
+		},
+		{
+			opts: policy(KeepURLAsText),
+			wantEntry: map[string][]any{
+				"url":         {"Post"},
+				"photo":       {"Photo"},
+				"in-reply-to": {&Microformat{Type: []string{"h-cite"}, Properties: map[string][]any{"name": {"Reply"}}, Value: "Reply"}},
+				"syndication": {"https://example.org/1"},
+				"content":     {map[string]string{"value": "link", "html": "<a>link</a>"}},
+			},
+			wantCard: map[string][]any{"name": {"Alice"}},
+			wantRels: map[string][]string{"home": {"https://example.com/"}},
+		},
+		{
+			opts: policy(FlagURL),
+			wantEntry: map[string][]any{
+				"url":   {"javascript:alert(1)"},
+				"photo": {map[string]string{"value": "data:image/png;base64,AAAA", "alt": "Photo"}},
+				"in-reply-to": {&Microformat{
+					Type: []string{"h-cite"},
+					Properties: map[string][]any{
+						"name": {"Reply"},
+						"url":  {"vbscript:x"},
+					},
+					Value: "vbscript:x",
+				}},
+				"syndication": {"https://example.org/1"},
+				"content":     {map[string]string{"value": "link", "html": `<a href="javascript:x">link</a>`}},
+			},
+			wantCard: map[string][]any{"name": {"Alice"}, "url": {"javascript:x"}},
+			wantRels: map[string][]string{"home": {"https://example.com/"}, "me": {"javascript:x"}},
+		},
+	}
+
+	for _, tt := range tests {
+		doc, err := html.Parse(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("Error parsing HTML: %v", err)
+		}
+		data := tt.opts.ParseNode(doc, base)
+		if diff := cmp.Diff(tt.wantEntry, data.Items[0].Properties, cmpopts.IgnoreUnexported(Microformat{})); diff != "" {
+			t.Errorf("ParseNode() with action %v h-entry properties differ:\n%s", tt.opts.URLPolicy.Action, diff)
+		}
+		if diff := cmp.Diff(tt.wantCard, data.Items[1].Properties, cmpopts.IgnoreUnexported(Microformat{})); diff != "" {
+			t.Errorf("ParseNode() with action %v h-card properties differ:\n%s", tt.opts.URLPolicy.Action, diff)
+		}
+		if diff := cmp.Diff(tt.wantRels, data.Rels); diff != "" {
+			t.Errorf("ParseNode() with action %v rels differ:\n%s", tt.opts.URLPolicy.Action, diff)
+		}
+
+		var got []string
+		for _, d := range data.Diagnostics {
+			if d.Code != DiagnosticURLScheme {
+				t.Errorf("ParseNode() with action %v returned diagnostic code %q, want %q", tt.opts.URLPolicy.Action, d.Code, DiagnosticURLScheme)
+			}
+			got = append(got, d.Message)
+		}
+		want := []string{
+			`URL "javascript:alert(1)" has a disallowed scheme`,
+			`URL "data:image/png;base64,AAAA" has a disallowed scheme`,
+			`URL "vbscript:x" has a disallowed scheme`,
+			`URL "javascript:x" has a disallowed scheme`,
+			`URL "javascript:x" has a disallowed scheme`,
+			`URL "javascript:x" has a disallowed scheme`,
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("ParseNode() with action %v diagnostics differ:\n%s", tt.opts.URLPolicy.Action, diff)
+		}
+
+		// attributes removed from e-* properties are restored after parsing
+		var buf bytes.Buffer
+		_ = html.Render(&buf, doc)
+		if !strings.Contains(buf.String(), `<a href="javascript:x">link</a>`) {
+			t.Errorf("ParseNode() with action %v modified the document: %s", tt.opts.URLPolicy.Action, buf.String())
+		}
+	}
+}
+
+// Attributes removed by the URL policy are restored exactly as they were,
+// even if other attributes of the same element were expanded first.
+func Test_ParseOptions_URLPolicy_RestoreAttrs(t *testing.T) {
+	input := `<div class="h-entry"><div class="e-content"><a href="/more" ping="javascript:y">more</a></div></div>`
+	base, _ := url.Parse("https://example.com/")
+	doc, err := html.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Error parsing HTML: %v", err)
+	}
+	opts := ParseOptions{URLPolicy: &URLPolicy{Schemes: []string{"http", "https"}}}
+	data := opts.ParseNode(doc, base)
+
+	want := map[string]string{"value": "more", "html": `<a href="https://example.com/more">more</a>`}
+	if diff := cmp.Diff(want, data.Items[0].Properties["content"][0]); diff != "" {
+		t.Errorf("ParseNode() content differs:\n%s", diff)
+	}
+	var buf bytes.Buffer
+	_ = html.Render(&buf, doc)
+	if want := `<a href="/more" ping="javascript:y">more</a>`; !strings.Contains(buf.String(), want) {
+		t.Errorf("ParseNode() modified the document: %s, want %s", buf.String(), want)
+	}
+}
+
+func Test_ParseOptions_Limits(t *testing.T) {
+	base, _ := url.Parse("https://example.com/")
+	deep := strings.Repeat("<div>", 100) + `<span class="h-card">Deep</span>` + strings.Repeat("</div>", 100)
+
+	tests := []struct {
+		name      string
+		limits    Limits
+		html      string
+		wantItems []*Microformat
+		wantCode  string
+	}{
+		{
+			name:   "max depth",
+			limits: Limits{MaxDepth: 50},
+			html:   `<span class="h-card">Alice</span>` + deep,
+			wantItems: []*Microformat{
+				{Type: []string{"h-card"}, Properties: map[string][]any{"name": {"Alice"}}},
+			},
+			wantCode: DiagnosticMaxDepth,
+		},
+		{
+			name:   "max depth not reached",
+			limits: Limits{MaxDepth: 200},
+			html:   deep,
+			wantItems: []*Microformat{
+				{Type: []string{"h-card"}, Properties: map[string][]any{"name": {"Deep"}}},
+			},
+		},
+		{
+			name:   "max items",
+			limits: Limits{MaxItems: 2},
+			html: `<div class="h-entry"><span class="p-name">Post</span>
+			  <span class="p-author h-card">Alice</span><span class="p-author h-card">Bob</span></div>
+			  <span class="h-card">Carol</span>`,
+			wantItems: []*Microformat{
+				{Type: []string{"h-entry"}, Properties: map[string][]any{
+					"name": {"Post"},
+					"author": {&Microformat{
+						Type:       []string{"h-card"},
+						Properties: map[string][]any{"name": {"Alice"}},
+						Value:      "Alice",
+					}},
+				}},
+			},
+			wantCode: DiagnosticMaxItems,
+		},
+		{
+			name:   "max properties",
+			limits: Limits{MaxProperties: 2},
+			html: `<div class="h-card"><span class="p-name">Alice</span>
+			  <span class="p-nickname">Al</span><span class="p-nickname">Ali</span></div>`,
+			wantItems: []*Microformat{
+				{Type: []string{"h-card"}, Properties: map[string][]any{"name": {"Alice"}, "nickname": {"Al"}}},
+			},
+			wantCode: DiagnosticMaxProperties,
+		},
+		{
+			name:   "max property bytes",
+			limits: Limits{MaxPropertyBytes: 10},
+			html: `<div class="h-card"><span class="p-name">Alice</span>
+			  <span class="p-note">A long note about Alice</span><span class="p-nickname">Al</span></div>`,
+			wantItems: []*Microformat{
+				{Type: []string{"h-card"}, Properties: map[string][]any{"name": {"Alice"}, "nickname": {"Al"}}},
+			},
+			wantCode: DiagnosticMaxPropertyBytes,
+		},
+		{
+			name:   "max property bytes html",
+			limits: Limits{MaxPropertyBytes: 100},
+			html: `<div class="h-entry"><div class="e-content"><p>` + strings.Repeat("x", 5000) + `</p></div>
+			  <span class="p-name">Post</span></div>`,
+			wantItems: []*Microformat{
+				{Type: []string{"h-entry"}, Properties: map[string][]any{"name": {"Post"}}},
+			},
+			wantCode: DiagnosticMaxPropertyBytes,
+		},
+		{
+			name:   "max depth html",
+			limits: Limits{MaxDepth: 5},
+			html:   `<div class="h-entry"><div class="e-content">Hello <b><i>world</i></b></div></div>`,
+			wantItems: []*Microformat{
+				{Type: []string{"h-entry"}, Properties: map[string][]any{
+					"content": {map[string]string{"value": "Hello", "html": "Hello <b></b>"}},
+				}},
+			},
+			wantCode: DiagnosticMaxDepth,
+		},
+		{
+			name:   "max includes",
+			limits: Limits{MaxIncludes: 1},
+			html: `<div class="vcard"><a class="include" href="#n"></a><a class="include" href="#o"></a></div>
+			  <div id="n"><span class="fn">Alice</span></div><div id="o"><span class="org">Example</span></div>`,
+			wantItems: []*Microformat{
+				{Type: []string{"h-card"}, Properties: map[string][]any{"name": {"Alice"}}},
+			},
+			wantCode: DiagnosticMaxIncludes,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("Error parsing HTML: %v", err)
+			}
+			var before bytes.Buffer
+			_ = html.Render(&before, doc)
+
+			data := ParseOptions{Limits: tt.limits}.ParseNode(doc, base)
+			if diff := cmp.Diff(tt.wantItems, data.Items, cmpopts.IgnoreUnexported(Microformat{})); diff != "" {
+				t.Errorf("ParseNode() items differ:\n%s", diff)
+			}
+
+			var codes []string
+			for _, d := range data.Diagnostics {
+				codes = append(codes, d.Code)
+			}
+			var wantCodes []string
+			if tt.wantCode != "" {
+				wantCodes = []string{tt.wantCode}
+			}
+			if diff := cmp.Diff(wantCodes, codes); diff != "" {
+				t.Errorf("ParseNode() diagnostics differ:\n%s", diff)
+			}
+
+			// the document is not modified
+			var after bytes.Buffer
+			_ = html.Render(&after, doc)
+			if after.String() != before.String() {
+				t.Errorf("ParseNode() modified the document:\n%s", after.String())
+			}
+		})
+	}
+}
+
+func Test_ParseOptions_Limits_Concurrent(t *testing.T) {
+	deep := strings.Repeat("<div>", 100) + `<span class="h-card">Deep</span>` + strings.Repeat("</div>", 100)
+	doc, err := html.Parse(strings.NewReader(`<div class="h-entry"><div class="e-content">` + deep + `</div></div>`))
+	if err != nil {
+		t.Fatalf("Error parsing HTML: %v", err)
+	}
+	opts := ParseOptions{Limits: Limits{MaxDepth: 50}}
+	want := opts.ParseNode(doc, nil)
+
+	// documents are not modified while parsing, so may be shared
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got := opts.ParseNode(doc, nil)
+			if diff := cmp.Diff(want, got, cmpopts.IgnoreUnexported(Microformat{})); diff != "" {
+				t.Errorf("concurrent ParseNode() differs:\n%s", diff)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -51,6 +51,10 @@ func PostType(item *microformats.Microformat) string {
 	// compare content and name to determine if post is a note or an article
 	var content, name string
 	for _, value := range item.Properties["content"] {
+		// e-content values are parsed as a map with the plain text value
+		if m, ok := value.(map[string]string); ok {
+			value = m["value"]
+		}
 		if v, ok := value.(string); ok && v != "" {
 			content = v
 			break
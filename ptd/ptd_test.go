@@ -4,6 +4,7 @@
 package ptd
 
 import (
+	"strings"
 	"testing"
 
 	"willnorris.com/go/microformats"
@@ -86,6 +87,8 @@ func Test_PostType_Properties(t *testing.T) {
 		{pm{"content": {"foo"}, "name": {"bar"}}, "article"},
 		{pm{"content": {"foo"}, "summary": {"bar"}, "name": {"bar"}}, "article"},
 		{pm{"content": {"foo \t\n bar"}, "name": {" foo bar "}}, "note"},
+		{pm{"content": {map[string]string{"value": "foo", "html": "<b>foo</b>"}}, "name": {"bar"}}, "article"},
+		{pm{"content": {map[string]string{"value": "foo", "html": "<b>foo</b>"}}, "name": {"foo"}}, "note"},
 	}
 
 	for _, tt := range tests {
@@ -157,3 +160,21 @@ func Test_ValidURL(t *testing.T) {
 		}
 	}
 }
+
+func Test_PostType_Parsed(t *testing.T) {
+	tests := []struct {
+		html string
+		want string
+	}{
+		{`<div class="h-entry"><p class="e-content">Just a <b>note</b></p></div>`, "note"},
+		{`<div class="h-entry"><h1 class="p-name">Title</h1><div class="e-content"><p>Some <b>longer</b> content</p></div></div>`, "article"},
+		{`<div class="h-entry"><p class="p-name e-content">Just a <b>note</b></p></div>`, "note"},
+	}
+
+	for _, tt := range tests {
+		data := microformats.Parse(strings.NewReader(tt.html), nil)
+		if got, want := PostType(data.Items[0]), tt.want; got != want {
+			t.Errorf("PostType(%q) returned %q, want %q", tt.html, got, want)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+// Package render renders microformats as semantic HTML.
+//
+// Each item is rendered as an element with its h-* root classes, containing
+// an element for each property value.  Since parsed microformats do not
+// record which prefix a property was parsed with, the prefix is chosen from
+// the shape of the value:
+//
+//   - e-* maps with an "html" value are rendered as a <div> containing that
+//     HTML.
+//   - absolute URLs are rendered as u-* properties, using <img>, <video>, or
+//     <audio> for media properties such as photo, and <a href> otherwise.
+//     Photos with alt text are rendered as <img alt>.
+//   - dates of date properties such as published and start are rendered as
+//     dt-* properties using <time datetime>.
+//   - all other values are rendered as p-* properties, using <data value>
+//     when the text of an element could not represent them exactly.
+//
+// Nested microformats are rendered with both their property class and their
+// root classes.  Nested items parsed from an e-* property are rendered with
+// their original HTML, which is expected to contain their properties.
+//
+// Parsing the rendered HTML with microformats.Parse yields the same items
+// as were rendered, for items produced by the parser from a document with
+// the same base URL.
+package render
+
+import (
+	"io"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"willnorris.com/go/microformats"
+)
+
+// mediaElements maps properties whose values are typically media files to
+// the element used to render them.
+var mediaElements = map[string]string{
+	"photo":    "img",
+	"logo":     "img",
+	"featured": "img",
+	"video":    "video",
+	"audio":    "audio",
+}
+
+// dateProperties are the properties whose values are rendered as dt-*
+// properties if they are dates.
+var dateProperties = map[string]bool{
+	"accessed":    true,
+	"anniversary": true,
+	"bday":        true,
+	"deleted":     true,
+	"end":         true,
+	"published":   true,
+	"rev":         true,
+	"start":       true,
+	"updated":     true,
+}
+
+// Encode writes items to w as HTML, with each item on its own line.
+func Encode(w io.Writer, items ...*microformats.Microformat) error {
+	for _, item := range items {
+		if err := html.Render(w, Node(item)); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Node returns item rendered as an HTML <div> element.
+func Node(item *microformats.Microformat) *html.Node {
+	return itemNode(item, "div", item.Type)
+}
+
+// itemNode returns an element with the specified tag and classes containing
+// the properties and children of item.
+func itemNode(item *microformats.Microformat, tag string, classes []string, attrs ...string) *html.Node {
+	if item.ID != "" {
+		attrs = append(attrs, "id", item.ID)
+	}
+	n := element(tag, classes, attrs...)
+
+	if item.HTML != "" {
+		// the HTML of an e-* item includes the markup of its properties
+		appendHTML(n, item.HTML)
+		return n
+	}
+
+	// Item values which do not set the hasPProperties, hasEProperties, or
+	// hasNestedMicroformats flags of the parser leave the item's name to be
+	// implied from its text.  If it has no name, the text of its elements
+	// must be empty so that none is implied.
+	implied := item.Properties["name"] == nil && len(item.Children) == 0
+	for name, values := range item.Properties {
+		for _, v := range values {
+			if _, ok := v.(*microformats.Microformat); ok {
+				implied = false
+			} else if p, _ := prefix(name, v); p == "p" || p == "e" {
+				implied = false
+			}
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(item.Properties)) {
+		for _, v := range item.Properties[name] {
+			n.AppendChild(valueNode(name, v, !implied))
+		}
+	}
+	for _, child := range item.Children {
+		n.AppendChild(Node(child))
+	}
+	return n
+}
+
+// prefix returns the class prefix used to render the property value v, and
+// the element to render it with if it is not a nested microformat.  name is
+// used to select elements for media and date properties.
+func prefix(name string, v any) (string, string) {
+	switch v := v.(type) {
+	case *microformats.Microformat:
+		p, _ := embeddedPrefix(v)
+		return p, ""
+	case map[string]string:
+		if _, ok := v["html"]; ok {
+			return "e", "div"
+		}
+		if _, ok := v["alt"]; ok && isURL(v["value"]) {
+			return "u", "img"
+		}
+		return "p", "data"
+	case string:
+		if isURL(v) {
+			if tag := mediaElements[name]; tag != "" {
+				return "u", tag
+			}
+			return "u", "a"
+		}
+		if dt, ok := microformats.ParseDateTime(v); ok && dt.HasDate && dateProperties[name] {
+			return "dt", "time"
+		}
+		if v != "" && v == strings.TrimSpace(v) && !strings.ContainsAny(v, "\r\x00") {
+			return "p", "span"
+		}
+	}
+	return "p", "data"
+}
+
+// embeddedPrefix returns the class prefix for a nested microformat, and
+// whether its value must be rendered with a <data> element because it
+// cannot be implied from its properties.
+func embeddedPrefix(item *microformats.Microformat) (string, bool) {
+	name, hasName := firstString(item, "name")
+	u, hasURL := firstString(item, "url")
+	switch {
+	case item.HTML != "":
+		return "e", false
+	case hasName && item.Value == name:
+		return "p", false
+	case hasURL && item.Value == u:
+		return "u", false
+	case !hasURL && isURL(item.Value):
+		return "u", true
+	}
+	return "p", !hasName
+}
+
+// valueNode returns the element for value v of the named property.  If
+// text is false, elements whose value is taken from an attribute have no
+// text content.
+func valueNode(name string, v any, text bool) *html.Node {
+	p, tag := prefix(name, v)
+	class := []string{p + "-" + name}
+
+	switch v := v.(type) {
+	case *microformats.Microformat:
+		if _, data := embeddedPrefix(v); data {
+			return itemNode(v, "data", append(class, v.Type...), "value", v.Value)
+		}
+		return itemNode(v, "div", append(class, v.Type...))
+	case map[string]string:
+		switch tag {
+		case "div":
+			n := element(tag, class)
+			appendHTML(n, v["html"])
+			return n
+		case "img":
+			return element(tag, class, "src", v["value"], "alt", v["alt"])
+		}
+		return element(tag, class, "value", v["value"])
+	case string:
+		var n *html.Node
+		switch tag {
+		case "img":
+			return element(tag, class, "src", v, "alt", "")
+		case "video", "audio":
+			return element(tag, class, "src", v)
+		case "a":
+			n = element(tag, class, "href", v)
+		case "time":
+			n = element(tag, class, "datetime", v)
+		case "span":
+			n = element(tag, class)
+			n.AppendChild(&html.Node{Type: html.TextNode, Data: v})
+			return n
+		default:
+			return element(tag, class, "value", v)
+		}
+		if text {
+			n.AppendChild(&html.Node{Type: html.TextNode, Data: v})
+		}
+		return n
+	}
+	return element(tag, class, "value", "")
+}
+
+// element returns an HTML element with the specified tag, classes, and
+// attributes, which are specified as alternating keys and values.
+func element(tag string, classes []string, attrs ...string) *html.Node {
+	n := &html.Node{
+		Type:     html.ElementNode,
+		Data:     tag,
+		DataAtom: atom.Lookup([]byte(tag)),
+	}
+	if len(classes) > 0 {
+		n.Attr = append(n.Attr, html.Attribute{Key: "class", Val: strings.Join(classes, " ")})
+	}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		n.Attr = append(n.Attr, html.Attribute{Key: attrs[i], Val: attrs[i+1]})
+	}
+	return n
+}
+
+// appendHTML parses s as the content of n, and appends the resulting nodes
+// to n.
+func appendHTML(n *html.Node, s string) {
+	nodes, err := html.ParseFragment(strings.NewReader(s), n)
+	if err != nil {
+		n.AppendChild(&html.Node{Type: html.TextNode, Data: s})
+		return
+	}
+	for _, c := range nodes {
+		n.AppendChild(c)
+	}
+}
+
+// firstString returns the first value of the named property of item if it
+// is a string, as used by the parser to determine the value of nested
+// microformats.
+func firstString(item *microformats.Microformat, name string) (string, bool) {
+	if values := item.Properties[name]; len(values) > 0 {
+		s, ok := values[0].(string)
+		return s, ok
+	}
+	return "", false
+}
+
+// isURL returns whether s is an absolute URL which the parser would not
+// modify when resolving it against a base URL.
+func isURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return false
+	}
+	return new(url.URL).ResolveReference(u).String() == s
+}
@@ -0,0 +1,120 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package render
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"willnorris.com/go/microformats"
+)
+
+// ignoreUnexported ignores the parser's internal state when comparing
+// microformats.
+var ignoreUnexported = cmpopts.IgnoreUnexported(microformats.Microformat{})
+
+var baseURL, _ = url.Parse("http://example.com/")
+
+func TestEncode(t *testing.T) {
+	item := &microformats.Microformat{
+		ID:   "post",
+		Type: []string{"h-entry"},
+		Properties: map[string][]any{
+			"name":        {"Hello"},
+			"url":         {"https://example.com/1"},
+			"photo":       {map[string]string{"value": "https://example.com/a.jpg", "alt": "A cat"}},
+			"published":   {"2024-05-01 10:00:00-0700"},
+			"content":     {map[string]string{"value": "Hi there", "html": "<p>Hi <b>there</b></p>"}},
+			"summary":     {" padded "},
+			"in-reply-to": {"https://other.example/post"},
+			"author": {&microformats.Microformat{
+				Type:       []string{"h-card"},
+				Properties: map[string][]any{"name": {"Alice"}, "url": {"https://example.com/"}},
+				Value:      "Alice",
+			}},
+		},
+		Children: []*microformats.Microformat{{
+			Type:       []string{"h-cite"},
+			Properties: map[string][]any{"name": {"Other"}},
+		}},
+	}
+
+	var b strings.Builder
+	if err := Encode(&b, item); err != nil {
+		t.Fatalf("Encode() returned error: %v", err)
+	}
+	want := `<div class="h-entry" id="post">` +
+		`<div class="p-author h-card"><span class="p-name">Alice</span><a class="u-url" href="https://example.com/">https://example.com/</a></div>` +
+		`<div class="e-content"><p>Hi <b>there</b></p></div>` +
+		`<a class="u-in-reply-to" href="https://other.example/post">https://other.example/post</a>` +
+		`<span class="p-name">Hello</span>` +
+		`<img class="u-photo" src="https://example.com/a.jpg" alt="A cat"/>` +
+		`<time class="dt-published" datetime="2024-05-01 10:00:00-0700">2024-05-01 10:00:00-0700</time>` +
+		`<data class="p-summary" value=" padded "></data>` +
+		`<a class="u-url" href="https://example.com/1">https://example.com/1</a>` +
+		`<div class="h-cite"><span class="p-name">Other</span></div>` +
+		"</div>\n"
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("Encode() differs:\n%s", diff)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+	}{
+		{"implied properties", `<a class="h-card" href="/alice"><img src="/alice.jpg" alt="Alice"></a>`},
+		{"implied name only", `<span class="h-card">Alice</span>`},
+		{"no implied name", `<div class="h-entry"><a class="u-url" href="/1"></a></div>`},
+		{"id", `<div class="h-card" id="me"><span class="p-name">Alice</span></div>`},
+		{"untrimmed value", `<div class="h-card"><data class="p-name" value=" Alice "></data><p class="p-note">A
+		  note</p></div>`},
+		{"entry", `<article class="h-entry">
+		  <h1 class="p-name">Title</h1>
+		  <a class="u-url u-uid" href="/1">permalink</a>
+		  <time class="dt-published" datetime="2024-05-01T10:00:00-07:00">May 1</time>
+		  <a class="p-author h-card" href="/">Alice</a>
+		  <div class="u-in-reply-to h-cite"><a class="u-url p-name" href="https://other.example/post">Post</a></div>
+		  <div class="e-content"><p>Some <b>long</b> content <img src="/a.jpg" alt="a cat"></p></div>
+		  <span class="p-category">go</span><a class="u-category" href="/tags/mf2">mf2</a>
+		  <img class="u-photo" src="/b.jpg" alt="">
+		  <video class="u-video" src="/c.mp4"></video></article>`},
+		{"nested value", `<div class="h-entry"><div class="p-author h-card"><span class="p-nickname">alice</span></div>
+		  <div class="u-like-of h-cite"><span class="p-name">Liked</span></div></div>`},
+		{"embedded content", `<div class="h-entry"><div class="e-content h-entry"><p class="p-name">Nested</p> text</div></div>`},
+		{"event", `<div class="h-event"><span class="p-name">Meetup</span>
+		  <time class="dt-start" datetime="2024-05-01 18:00">May 1</time><time class="dt-end" datetime="20:00">8pm</time>
+		  <data class="p-location h-geo" value="Library"><data class="p-latitude" value="37.7"></data></data></div>`},
+		{"children", `<div class="h-feed"><span class="p-name">Blog</span>
+		  <div class="h-entry"><span class="p-name">One</span></div><div class="h-entry"><span class="p-name">Two</span></div></div>`},
+		{"backcompat", `<div class="vcard"><span class="fn">Alice</span><a class="url" href="/">home</a></div>`},
+		{"relative values", `<div class="h-card"><span class="p-name">/alice</span><span class="p-org">http://[::1]:bad</span></div>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roundTrip(t, tt.html)
+		})
+	}
+}
+
+// roundTrip parses input, renders the parsed items, and checks that parsing
+// the rendered HTML yields the same items.
+func roundTrip(t *testing.T, input string) {
+	t.Helper()
+	want := microformats.Parse(strings.NewReader(input), baseURL).Items
+
+	var b strings.Builder
+	if err := Encode(&b, want...); err != nil {
+		t.Fatalf("Encode() returned error: %v", err)
+	}
+	got := microformats.Parse(strings.NewReader(b.String()), baseURL).Items
+	if diff := cmp.Diff(want, got, ignoreUnexported); diff != "" {
+		t.Errorf("Parse(Encode()) differs:\n%s\nrendered HTML:\n%s", diff, b.String())
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+// Round trip the shared test suite from https://github.com/microformats/tests
+// through the renderer.
+
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// skip the tests which we don't round trip yet
+var skipTests = []string{}
+
+func TestSuite(t *testing.T) {
+	for _, version := range []string{"microformats-mixed", "microformats-v1", "microformats-v2"} {
+		t.Run(version, func(t *testing.T) {
+			base := filepath.Join("..", "testdata", "tests", version)
+			if _, err := os.Stat(base); os.IsNotExist(err) {
+				t.Skip("test suite not found; run 'git submodule update --init'")
+			}
+			tests, err := listTests(base)
+			if err != nil {
+				t.Fatalf("error reading test cases: %v", err)
+			}
+
+			for _, test := range tests {
+				t.Run(test, func(t *testing.T) {
+					for _, skip := range skipTests {
+						if filepath.Join(version, test) == skip {
+							t.Skip()
+						}
+					}
+
+					input, err := os.ReadFile(filepath.Join(base, test) + ".html")
+					if err != nil {
+						t.Fatalf("error reading file %q: %v", test+".html", err)
+					}
+					roundTrip(t, string(input))
+				})
+			}
+		})
+	}
+}
+
+// listTests recursively lists the .html files of microformat tests in the
+// specified root directory, returning their paths relative to root without
+// the file extension.
+func listTests(root string) ([]string, error) {
+	tests := []string{}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".html" {
+			return nil
+		}
+		test, err := filepath.Rel(root, strings.TrimSuffix(path, ".html"))
+		if err != nil {
+			return err
+		}
+		tests = append(tests, test)
+		return nil
+	})
+	return tests, err
+}
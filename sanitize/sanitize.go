@@ -0,0 +1,235 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+// Package sanitize removes unsafe markup from HTML using an allowlist
+// policy.
+//
+// A Policy lists the elements and attributes that are allowed, and the URL
+// schemes that are allowed in attributes such as href and src.  Elements
+// that are not allowed are replaced by their content, except for elements
+// such as <script> and <style> whose content is not displayed as text,
+// which are removed entirely.  Attributes that are not allowed are removed,
+// as are URL attributes with a scheme that is not allowed.
+//
+// A *Policy can be used as the Sanitizer of microformats.ParseOptions to
+// sanitize the html of e-* properties as they are parsed:
+//
+//	opts := microformats.ParseOptions{Sanitizer: sanitize.DefaultPolicy()}
+//	data := opts.Parse(r, baseURL)
+package sanitize
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Policy is an allowlist of HTML elements, attributes, and URL schemes.
+type Policy struct {
+	// Elements maps the names of allowed elements to the names of
+	// attributes allowed on that element, in addition to Attributes.
+	Elements map[string][]string
+
+	// Attributes are the names of attributes allowed on all allowed
+	// elements.
+	Attributes []string
+
+	// URLSchemes are the schemes allowed in URL attributes.  Relative
+	// URLs are always allowed.
+	URLSchemes []string
+}
+
+// dropContent are the elements whose content is removed along with the
+// element when they are not allowed.
+var dropContent = map[atom.Atom]bool{
+	atom.Embed:    true,
+	atom.Frame:    true,
+	atom.Frameset: true,
+	atom.Iframe:   true,
+	atom.Math:     true,
+	atom.Noembed:  true,
+	atom.Noframes: true,
+	atom.Noscript: true,
+	atom.Object:   true,
+	atom.Script:   true,
+	atom.Select:   true,
+	atom.Style:    true,
+	atom.Svg:      true,
+	atom.Template: true,
+	atom.Textarea: true,
+	atom.Title:    true,
+}
+
+// urlAttributes are the attributes whose values are URLs.
+var urlAttributes = map[string]bool{
+	"action":     true,
+	"background": true,
+	"cite":       true,
+	"data":       true,
+	"formaction": true,
+	"href":       true,
+	"longdesc":   true,
+	"poster":     true,
+	"src":        true,
+	"usemap":     true,
+	"xlink:href": true,
+}
+
+// DefaultPolicy returns a policy allowing common text formatting, links,
+// images, audio, and video, along with the class attribute so that
+// microformats markup is preserved.  Links and media may use http, https,
+// and mailto URLs.
+func DefaultPolicy() *Policy {
+	return &Policy{
+		Elements: map[string][]string{
+			"a":          {"href", "hreflang", "rel"},
+			"abbr":       nil,
+			"audio":      {"controls", "src"},
+			"b":          nil,
+			"blockquote": {"cite"},
+			"br":         nil,
+			"caption":    nil,
+			"cite":       nil,
+			"code":       nil,
+			"data":       {"value"},
+			"dd":         nil,
+			"del":        {"cite", "datetime"},
+			"details":    {"open"},
+			"div":        nil,
+			"dl":         nil,
+			"dt":         nil,
+			"em":         nil,
+			"figcaption": nil,
+			"figure":     nil,
+			"h1":         nil,
+			"h2":         nil,
+			"h3":         nil,
+			"h4":         nil,
+			"h5":         nil,
+			"h6":         nil,
+			"hr":         nil,
+			"i":          nil,
+			"img":        {"alt", "height", "src", "width"},
+			"ins":        {"cite", "datetime"},
+			"kbd":        nil,
+			"li":         nil,
+			"mark":       nil,
+			"ol":         {"reversed", "start"},
+			"p":          nil,
+			"pre":        nil,
+			"q":          {"cite"},
+			"s":          nil,
+			"samp":       nil,
+			"small":      nil,
+			"source":     {"src", "type"},
+			"span":       nil,
+			"strong":     nil,
+			"sub":        nil,
+			"summary":    nil,
+			"sup":        nil,
+			"table":      nil,
+			"tbody":      nil,
+			"td":         {"colspan", "rowspan"},
+			"tfoot":      nil,
+			"th":         {"colspan", "rowspan", "scope"},
+			"thead":      nil,
+			"time":       {"datetime"},
+			"tr":         nil,
+			"u":          nil,
+			"ul":         nil,
+			"video":      {"controls", "height", "poster", "src", "width"},
+		},
+		Attributes: []string{"class", "dir", "id", "lang", "title"},
+		URLSchemes: []string{"http", "https", "mailto"},
+	}
+}
+
+// Sanitize removes the elements and attributes that are not allowed by p
+// from the descendants of node, modifying them in place.  node itself is
+// not modified.
+func (p *Policy) Sanitize(node *html.Node) {
+	for c := node.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch c.Type {
+		case html.ElementNode:
+			p.sanitizeElement(c)
+		case html.CommentNode, html.DoctypeNode:
+			node.RemoveChild(c)
+		}
+		c = next
+	}
+}
+
+// sanitizeElement sanitizes element node and its descendants, removing or
+// unwrapping it if it is not allowed.
+func (p *Policy) sanitizeElement(node *html.Node) {
+	allowed, ok := p.Elements[node.Data]
+	if !ok || node.Namespace != "" {
+		parent := node.Parent
+		if dropContent[node.DataAtom] || node.Namespace != "" {
+			parent.RemoveChild(node)
+			return
+		}
+		// sanitize the children before moving them, so that they are
+		// not visited again
+		p.Sanitize(node)
+		for c := node.FirstChild; c != nil; c = node.FirstChild {
+			node.RemoveChild(c)
+			parent.InsertBefore(c, node)
+		}
+		parent.RemoveChild(node)
+		return
+	}
+
+	attrs := node.Attr[:0]
+	for _, attr := range node.Attr {
+		if attr.Namespace != "" {
+			continue
+		}
+		key := strings.ToLower(attr.Key)
+		if !slices.Contains(allowed, key) && !slices.Contains(p.Attributes, key) {
+			continue
+		}
+		if urlAttributes[key] && !p.allowedURL(attr.Val) {
+			continue
+		}
+		attrs = append(attrs, attr)
+	}
+	node.Attr = attrs
+
+	p.Sanitize(node)
+}
+
+// allowedURL returns whether s is a relative URL or has a scheme allowed
+// by p.  URLs which cannot be parsed are not allowed.
+func (p *Policy) allowedURL(s string) bool {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return false
+	}
+	return u.Scheme == "" || slices.Contains(p.URLSchemes, strings.ToLower(u.Scheme))
+}
+
+// String sanitizes the HTML fragment s, as if it were the content of a
+// <div> element, and returns the result.
+func (p *Policy) String(s string) string {
+	context := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := html.ParseFragment(strings.NewReader(s), context)
+	if err != nil {
+		return ""
+	}
+	for _, n := range nodes {
+		context.AppendChild(n)
+	}
+	p.Sanitize(context)
+
+	var b strings.Builder
+	for c := context.FirstChild; c != nil; c = c.NextSibling {
+		// rendering to a strings.Builder does not fail
+		_ = html.Render(&b, c)
+	}
+	return b.String()
+}
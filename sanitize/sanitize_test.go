@@ -0,0 +1,86 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package sanitize
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"willnorris.com/go/microformats"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`<p>Hello <b>world</b></p>`, `<p>Hello <b>world</b></p>`},
+		{`<p class="h-card" id="me" onclick="x()">Alice</p>`, `<p class="h-card" id="me">Alice</p>`},
+
+		// disallowed elements are removed with or without their content
+		{`a<script>alert(1)</script>b<style>p{}</style>c`, `abc`},
+		{`<iframe src="https://example.com/"></iframe><object data="x.swf">fallback</object>`, ``},
+		{`<svg><a href="https://example.com/">x</a></svg>ok`, `ok`},
+		{`<font color="red">red <i>text</i></font>`, `red <i>text</i>`},
+		{`<form action="/post"><button>Go</button></form>`, `Go`},
+		{`a<!-- comment -->b`, `ab`},
+
+		// URL attributes
+		{`<a href="/relative">x</a>`, `<a href="/relative">x</a>`},
+		{`<a href="https://example.com/" rel="nofollow" target="_blank">x</a>`, `<a href="https://example.com/" rel="nofollow">x</a>`},
+		{`<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href="JavaScript:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href=" javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href="java&#x09;script:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href="vbscript:x">x</a>`, `<a>x</a>`},
+		{`<a href="mailto:alice@example.com">x</a>`, `<a href="mailto:alice@example.com">x</a>`},
+		{`<img src="data:image/png;base64,AAAA" alt="x">`, `<img alt="x"/>`},
+		{`<blockquote cite="javascript:x">q</blockquote>`, `<blockquote>q</blockquote>`},
+		{`<p style="background:url(x)">x</p>`, `<p>x</p>`},
+	}
+
+	p := DefaultPolicy()
+	for _, tt := range tests {
+		if got := p.String(tt.input); got != tt.want {
+			t.Errorf("String(%q) returned %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	p := &Policy{
+		Elements:   map[string][]string{"a": {"href"}, "p": nil},
+		URLSchemes: []string{"https"},
+	}
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`<p class="x"><a href="https://example.com/">x</a></p>`, `<p><a href="https://example.com/">x</a></p>`},
+		{`<a href="http://example.com/">x</a>`, `<a>x</a>`},
+		{`<ul><li><b>x</b></li></ul>`, `x`},
+	}
+	for _, tt := range tests {
+		if got := p.String(tt.input); got != tt.want {
+			t.Errorf("String(%q) returned %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	input := `<div class="h-entry"><div class="e-content"><p onmouseover="x()">Hi <a href="javascript:alert(1)">there</a>
+	  <span class="h-card">Alice</span></p><script>alert(2)</script></div></div>`
+	base, _ := url.Parse("https://example.com/")
+	data := microformats.ParseOptions{Sanitizer: DefaultPolicy()}.Parse(strings.NewReader(input), base)
+
+	want := []any{map[string]string{
+		"value": "Hi there\n\t  Alice",
+		"html":  "<p>Hi <a>there</a>\n\t  <span class=\"h-card\">Alice</span></p>",
+	}}
+	if diff := cmp.Diff(want, data.Items[0].Properties["content"]); diff != "" {
+		t.Errorf("Parse() content differs:\n%s", diff)
+	}
+}
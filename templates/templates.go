@@ -0,0 +1,252 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+// Package templates provides html/template functions for rendering parsed
+// microformats, and default partial templates for common vocabularies.
+//
+// The functions in FuncMap read property values from a
+// *microformats.Microformat, returning them as types that html/template
+// escapes for the context they are used in:
+//
+//	mfprop ITEM NAME     first plain text value of a property
+//	mfprops ITEM NAME    all plain text values of a property
+//	mfurl ITEM NAME      first URL value of a property
+//	mfurls ITEM NAME     all URL values of a property
+//	mftime ITEM NAME     first value of a property as a time.Time
+//	mfhtml ITEM NAME     first value of a property as sanitized HTML
+//	mfrawhtml ITEM NAME  first value of a property as unsanitized HTML
+//	mfcard ITEM NAME     first value of a property as an h-card
+//	mfposttype ITEM      post type of an item, as determined by the ptd package
+//
+// New returns a template with these functions and a partial template for
+// each of h-card, h-entry, h-event, and h-cite, named after the vocabulary.
+// The partials can be replaced by defining templates with the same name
+// before the template is executed, and are applied to items by the mfitem
+// function or the ExecuteItem function.
+//
+// mfhtml sanitizes the HTML of e-* properties with sanitize.DefaultPolicy,
+// so the h-entry partial can safely include e-content from untrusted
+// documents.  mfrawhtml returns the HTML of e-* properties as is, and
+// should only be used for trusted documents, or those already sanitized by
+// a Sanitizer in microformats.ParseOptions.
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"html"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"willnorris.com/go/microformats"
+	"willnorris.com/go/microformats/ptd"
+	"willnorris.com/go/microformats/sanitize"
+)
+
+//go:embed partials/*.html
+var partials embed.FS
+
+// ErrNoTemplate is returned when executing an item for which there is no
+// partial template.
+var ErrNoTemplate = errors.New("templates: no template for item type")
+
+// FuncMap returns the functions for reading microformat properties in
+// templates.
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"mfprop":     prop,
+		"mfprops":    props,
+		"mfurl":      propURL,
+		"mfurls":     urls,
+		"mftime":     propTime,
+		"mfhtml":     propHTML,
+		"mfrawhtml":  propRawHTML,
+		"mfcard":     card,
+		"mfposttype": ptd.PostType,
+	}
+}
+
+// New returns a new template with the given name, the functions of FuncMap,
+// and the default partial templates.  It also includes an mfitem function,
+// which applies the partial template of this template named after the type
+// of an item to the item.
+func New(name string) (*template.Template, error) {
+	t := template.New(name).Funcs(FuncMap())
+	t.Funcs(template.FuncMap{
+		"mfitem": func(item *microformats.Microformat) (template.HTML, error) {
+			var b bytes.Buffer
+			err := ExecuteItem(&b, t, item)
+			// the output of t has been escaped by html/template
+			return template.HTML(b.String()), err
+		},
+	})
+	return t.ParseFS(partials, "partials/*.html")
+}
+
+// ExecuteItem applies the template in t named after the type of item to
+// item, writing the output to w.  If item has multiple types, the first type
+// with a template is used.  It returns ErrNoTemplate if t has no template
+// for any of the types of item.
+func ExecuteItem(w io.Writer, t *template.Template, item *microformats.Microformat) error {
+	if item != nil {
+		for _, typ := range item.Type {
+			if tt := t.Lookup(typ); tt != nil {
+				return tt.Execute(w, item)
+			}
+		}
+	}
+	return ErrNoTemplate
+}
+
+// prop returns the first plain text value of the named property of item.
+func prop(item *microformats.Microformat, name string) string {
+	if v := props(item, name); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// props returns the plain text values of the named property of item,
+// skipping empty values.
+func props(item *microformats.Microformat, name string) []string {
+	if item == nil {
+		return nil
+	}
+	var values []string
+	for _, v := range item.Properties[name] {
+		var s string
+		switch v := v.(type) {
+		case string:
+			s = v
+		case map[string]string:
+			s = v["value"]
+		case *microformats.Microformat:
+			s = v.Value
+		}
+		if s != "" {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+// propURL returns the first URL value of the named property of item.
+//
+// The URL is returned as a string rather than a template.URL, so that
+// html/template rejects URLs with unsafe schemes such as javascript:.
+func propURL(item *microformats.Microformat, name string) string {
+	if v := urls(item, name); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// urls returns the URL values of the named property of item.  The URL of a
+// nested microformat, such as an h-cite, is its url property.
+func urls(item *microformats.Microformat, name string) []string {
+	if item == nil {
+		return nil
+	}
+	var values []string
+	for _, v := range item.Properties[name] {
+		var s string
+		switch v := v.(type) {
+		case string:
+			s = v
+		case map[string]string:
+			s = v["value"]
+		case *microformats.Microformat:
+			if s = prop(v, "url"); s == "" {
+				s = v.Value
+			}
+		}
+		if s != "" {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+// propTime returns the first value of the named property of item that is a
+// valid datetime, or the zero time if there is none.
+func propTime(item *microformats.Microformat, name string) time.Time {
+	for _, s := range props(item, name) {
+		if dt, ok := microformats.ParseDateTime(s); ok {
+			return dt.Time
+		}
+	}
+	return time.Time{}
+}
+
+// sanitizer is the policy used by propHTML.
+var sanitizer = sanitize.DefaultPolicy()
+
+// propHTML returns the first value of the named property of item as HTML.
+// The html of e-* values is sanitized with sanitizer.  Other values are
+// escaped.
+func propHTML(item *microformats.Microformat, name string) template.HTML {
+	if h, ok := rawHTML(item, name); ok {
+		return template.HTML(sanitizer.String(h))
+	}
+	return template.HTML(html.EscapeString(prop(item, name)))
+}
+
+// propRawHTML is like propHTML, but returns the html of e-* values as is,
+// which is only as safe as the parsed document.
+func propRawHTML(item *microformats.Microformat, name string) template.HTML {
+	if h, ok := rawHTML(item, name); ok {
+		return template.HTML(h)
+	}
+	return template.HTML(html.EscapeString(prop(item, name)))
+}
+
+// rawHTML returns the html of the first value of the named property of
+// item, and whether the value has html.
+func rawHTML(item *microformats.Microformat, name string) (string, bool) {
+	if item == nil {
+		return "", false
+	}
+	for _, v := range item.Properties[name] {
+		if m, ok := v.(map[string]string); ok {
+			if h, ok := m["html"]; ok {
+				return h, true
+			}
+		}
+		if mf, ok := v.(*microformats.Microformat); ok && mf.HTML != "" {
+			return mf.HTML, true
+		}
+		break
+	}
+	return "", false
+}
+
+// card returns the first value of the named property of item as an h-card.
+// Nested microformats are returned as is, and plain text values are returned
+// as an h-card with that name, and url if the value is a URL.  It returns nil
+// if the property has no value.
+func card(item *microformats.Microformat, name string) *microformats.Microformat {
+	if item == nil {
+		return nil
+	}
+	for _, v := range item.Properties[name] {
+		if mf, ok := v.(*microformats.Microformat); ok {
+			return mf
+		}
+	}
+	s := prop(item, name)
+	if s == "" {
+		return nil
+	}
+	c := &microformats.Microformat{
+		Type:       []string{"h-card"},
+		Properties: map[string][]any{"name": {s}},
+		Value:      s,
+	}
+	if strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "http://") {
+		c.Properties["url"] = []any{s}
+	}
+	return c
+}
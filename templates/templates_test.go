@@ -0,0 +1,194 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package templates
+
+import (
+	"errors"
+	"html/template"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"willnorris.com/go/microformats"
+)
+
+func parse(t *testing.T, html string) *microformats.Microformat {
+	t.Helper()
+	base, _ := url.Parse("https://example.com/")
+	data := microformats.Parse(strings.NewReader(html), base)
+	if len(data.Items) == 0 {
+		t.Fatalf("no items found in %q", html)
+	}
+	return data.Items[0]
+}
+
+func TestFuncMap(t *testing.T) {
+	item := parse(t, `<article class="h-entry">
+	  <h1 class="p-name">Fish &amp; &lt;chips&gt;</h1>
+	  <a class="u-url" href="/1">x</a>
+	  <a class="u-syndication" href="javascript:alert(1)">x</a>
+	  <time class="dt-published" datetime="2024-05-01 10:00:00-07:00">May 1</time>
+	  <div class="e-content"><p>Some <b>bold</b> content</p><img src="x.png" onerror="alert(1)"><script>alert(2)</script></div>
+	  <span class="p-summary">a &lt;b&gt; tag</span>
+	  <span class="p-category">go</span><span class="p-category">mf2</span>
+	  <a class="p-author" href="/">Alice</a>
+	  <div class="u-in-reply-to h-cite"><a class="u-url" href="https://other.example/post">Post</a></div>
+	</article>`)
+
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{`{{mfprop . "name"}}`, `Fish &amp; &lt;chips&gt;`},
+		{`{{mfprop . "missing"}}`, ``},
+		{`{{range mfprops . "category"}}[{{.}}]{{end}}`, `[go][mf2]`},
+		{`<a href="{{mfurl . "url"}}">`, `<a href="https://example.com/1">`},
+		{`<a href="{{mfurl . "syndication"}}">`, `<a href="#ZgotmplZ">`},
+		{`{{range mfurls . "in-reply-to"}}{{.}}{{end}}`, `https://other.example/post`},
+		{`{{(mftime . "published").Format "2006-01-02 15:04 MST"}}`, `2024-05-01 10:00 -0700`},
+		{`{{(mftime . "updated").IsZero}}`, `true`},
+		{`{{mfhtml . "content"}}`, `<p>Some <b>bold</b> content</p><img src="https://example.com/x.png"/>`},
+		{`{{mfhtml . "summary"}}`, `a &lt;b&gt; tag`},
+		{`{{mfrawhtml . "content"}}`, `<p>Some <b>bold</b> content</p><img src="https://example.com/x.png" onerror="alert(1)"><script>alert(2)</script>`},
+		{`{{mfrawhtml . "summary"}}`, `a &lt;b&gt; tag`},
+		{`{{with mfcard . "author"}}{{mfprop . "name"}}{{end}}`, `Alice`},
+		{`{{with mfcard . "missing"}}{{.}}{{else}}none{{end}}`, `none`},
+		{`{{mfposttype .}}`, `article`},
+	}
+
+	for _, tt := range tests {
+		tmpl, err := template.New("test").Funcs(FuncMap()).Parse(tt.tmpl)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned error: %v", tt.tmpl, err)
+		}
+		var b strings.Builder
+		if err := tmpl.Execute(&b, item); err != nil {
+			t.Fatalf("Execute(%q) returned error: %v", tt.tmpl, err)
+		}
+		if got := b.String(); got != tt.want {
+			t.Errorf("Execute(%q) returned %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+}
+
+func TestCard(t *testing.T) {
+	item := &microformats.Microformat{
+		Type: []string{"h-entry"},
+		Properties: map[string][]any{
+			"author":    {"https://example.com/"},
+			"organizer": {"Alice"},
+		},
+	}
+	tests := []struct {
+		name string
+		want map[string][]any
+	}{
+		{"author", map[string][]any{"name": {"https://example.com/"}, "url": {"https://example.com/"}}},
+		{"organizer", map[string][]any{"name": {"Alice"}}},
+	}
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, card(item, tt.name).Properties); diff != "" {
+			t.Errorf("card(item, %q) differs:\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestPartials(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "h-card",
+			html: `<div class="h-card"><img class="u-photo" src="/alice.jpg" alt=""><a class="p-name u-url" href="/">Alice</a>
+			  <span class="p-org">Example</span><p class="p-note">Hi</p></div>`,
+			want: `<div class="h-card"><img class="u-photo" src="https://example.com/alice.jpg" alt=""><a class="p-name u-url" href="https://example.com/">Alice</a> <span class="p-org">Example</span><p class="p-note">Hi</p></div>`,
+		},
+		{
+			name: "h-entry",
+			html: `<article class="h-entry"><h1 class="p-name">Title</h1><a class="u-url" href="/1">x</a>
+			  <a class="p-author h-card" href="/">Alice</a>
+			  <time class="dt-published" datetime="2024-05-01 10:00:00-07:00">May 1</time>
+			  <div class="e-content"><p>Some long content</p></div><span class="p-category">go</span></article>`,
+			want: `<article class="h-entry"><h1 class="p-name">Title</h1><span class="p-author h-card"><a class="p-name u-url" href="https://example.com/">Alice</a></span>` +
+				`<div class="e-content"><p>Some long content</p></div> <span class="p-category">go</span>` +
+				`<a class="u-url" href="https://example.com/1"><time class="dt-published" datetime="2024-05-01 10:00:00-07:00">May 1, 2024</time></a></article>`,
+		},
+		{
+			name: "h-event",
+			html: `<div class="h-event"><span class="p-name">Meetup</span>
+			  <time class="dt-start" datetime="2024-05-01 18:00">May 1</time><span class="p-location">Library</span></div>`,
+			want: `<div class="h-event"><span class="p-name">Meetup</span> <time class="dt-start" datetime="2024-05-01 18:00">May 1, 2024 6:00 PM</time>` +
+				` at <span class="p-location h-card"><span class="p-name">Library</span></span></div>`,
+		},
+		{
+			name: "h-cite",
+			html: `<div class="h-cite"><a class="p-name u-url" href="https://other.example/post">Post</a>
+			  by <span class="p-author">Bob</span></div>`,
+			want: `<cite class="h-cite"><a class="p-name u-url" href="https://other.example/post">Post</a> by <span class="p-author h-card"><span class="p-name">Bob</span></span></cite>`,
+		},
+	}
+
+	tmpl, err := New("test")
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := parse(t, tt.html)
+			var b strings.Builder
+			if err := ExecuteItem(&b, tmpl, item); err != nil {
+				t.Fatalf("ExecuteItem() returned error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, b.String()); diff != "" {
+				t.Errorf("ExecuteItem() differs:\n%s", diff)
+			}
+
+			// the rendered partial is itself a microformat of the same type
+			got := parse(t, b.String())
+			if diff := cmp.Diff(item.Type, got.Type); diff != "" {
+				t.Errorf("parsed partial type differs:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOverride(t *testing.T) {
+	tmpl, err := New("page")
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+	tmpl, err = tmpl.Parse(`<main>{{range .}}{{mfitem .}}{{end}}</main>` +
+		`{{define "h-card"}}<p class="h-card">{{mfprop . "name"}}</p>{{end}}`)
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	items := []*microformats.Microformat{
+		parse(t, `<span class="h-card">Alice &amp; Bob</span>`),
+		parse(t, `<cite class="h-cite">Post</cite>`),
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, items); err != nil {
+		t.Fatalf("Execute() returned error: %v", err)
+	}
+	want := `<main><p class="h-card">Alice &amp; Bob</p><cite class="h-cite"><span class="p-name">Post</span></cite></main>`
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("Execute() differs:\n%s", diff)
+	}
+}
+
+func TestExecuteItem_NoTemplate(t *testing.T) {
+	tmpl, err := New("test")
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+	for _, item := range []*microformats.Microformat{nil, {Type: []string{"h-product"}}} {
+		if err := ExecuteItem(&strings.Builder{}, tmpl, item); !errors.Is(err, ErrNoTemplate) {
+			t.Errorf("ExecuteItem(%v) returned %v, want %v", item, err, ErrNoTemplate)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package vcard
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"willnorris.com/go/microformats"
+	"willnorris.com/go/microformats/internal/contentline"
+)
+
+// textProperties maps vCard properties with a single text value to h-card
+// property names.
+var textProperties = map[string]string{
+	"FN":    "name",
+	"IMPP":  "impp",
+	"KEY":   "key",
+	"LABEL": "label",
+	"NOTE":  "note",
+	"ROLE":  "role",
+	"TEL":   "tel",
+	"TITLE": "job-title",
+	"TZ":    "tz",
+	"UID":   "uid",
+	"URL":   "url",
+}
+
+// sexValues maps the sex component of vCard GENDER values to h-card sex
+// values.
+var sexValues = map[string]string{
+	"M": "male",
+	"F": "female",
+	"O": "other",
+	"N": "none",
+	"U": "unknown",
+}
+
+// Decode reads the vCard 3.0 or 4.0 data in r and returns each vCard as an
+// h-card.  Properties and their values have the same names and shapes as
+// those produced by the microformats parser; for example, photos with
+// alternative text are a map with "value" and "alt" keys, and addresses are
+// nested h-adr microformats.  Properties with no h-card equivalent are
+// ignored.
+func Decode(r io.Reader) ([]*microformats.Microformat, error) {
+	lines, err := contentline.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var cards []*microformats.Microformat
+	var card *microformats.Microformat
+	for _, l := range lines {
+		switch {
+		case l.Name == "BEGIN" && strings.EqualFold(l.Value, "VCARD"):
+			card = &microformats.Microformat{
+				Type:       []string{"h-card"},
+				Properties: make(map[string][]any),
+			}
+		case l.Name == "END" && strings.EqualFold(l.Value, "VCARD"):
+			if card != nil {
+				cards = append(cards, card)
+			}
+			card = nil
+		case card != nil:
+			decodeProperty(card, l)
+		}
+	}
+	if card != nil {
+		return cards, errors.New("vcard: missing END:VCARD")
+	}
+	return cards, nil
+}
+
+// decodeProperty adds the h-card properties for the vCard property l to card.
+func decodeProperty(card *microformats.Microformat, l contentline.Line) {
+	add := func(name string, v any) {
+		if s, ok := v.(string); ok && s == "" {
+			return
+		}
+		card.Properties[name] = append(card.Properties[name], v)
+	}
+
+	if name, ok := textProperties[l.Name]; ok {
+		add(name, contentline.Unescape(l.Value))
+		return
+	}
+
+	switch l.Name {
+	case "N":
+		c := structured(l.Value)
+		for i, name := range []string{"family-name", "given-name", "additional-name", "honorific-prefix", "honorific-suffix"} {
+			if i < len(c) {
+				for _, v := range c[i] {
+					add(name, v)
+				}
+			}
+		}
+	case "NICKNAME", "CATEGORIES":
+		name := "nickname"
+		if l.Name == "CATEGORIES" {
+			name = "category"
+		}
+		for _, v := range contentline.Split(l.Value, ',') {
+			add(name, contentline.Unescape(v))
+		}
+	case "PHOTO", "LOGO":
+		value := binaryURI(l)
+		if alt := l.Param("X-ALT"); alt != "" && l.Name == "PHOTO" {
+			add("photo", map[string]string{"value": value, "alt": alt})
+		} else {
+			add(strings.ToLower(l.Name), value)
+		}
+	case "BDAY", "ANNIVERSARY":
+		name := strings.ToLower(l.Name)
+		if dt, ok := contentline.ParseDateTime(l.Value, nil); ok && !l.HasParam("VALUE", "text") {
+			add(name, dt.String())
+		} else {
+			add(name, contentline.Unescape(l.Value))
+		}
+	case "GENDER":
+		c := structured(l.Value)
+		if len(c) > 0 && len(c[0]) > 0 {
+			add("sex", sexValues[strings.ToUpper(c[0][0])])
+		}
+		if len(c) > 1 && len(c[1]) > 0 {
+			add("gender-identity", c[1][0])
+		}
+	case "EMAIL":
+		if v := contentline.Unescape(l.Value); v != "" {
+			add("email", "mailto:"+strings.TrimPrefix(v, "mailto:"))
+		}
+	case "ADR":
+		if adr := decodeAddress(l); adr != nil {
+			add("adr", adr)
+		} else if label := l.Param("LABEL"); label != "" {
+			add("adr", label)
+		}
+	case "GEO":
+		if geo := decodeGeo(l.Value); geo != nil {
+			add("geo", geo)
+		}
+	case "ORG":
+		// organizational units are not supported by h-card
+		if c := structured(l.Value); len(c) > 0 && len(c[0]) > 0 {
+			add("org", c[0][0])
+		}
+	}
+}
+
+// decodeAddress returns an h-adr for the ADR property l, or nil if all of its
+// components are empty.
+func decodeAddress(l contentline.Line) *microformats.Microformat {
+	adr := &microformats.Microformat{
+		Type:       []string{"h-adr"},
+		Properties: make(map[string][]any),
+	}
+	var parts []string
+	for i, c := range structured(l.Value) {
+		if i >= len(addressProperties) {
+			break
+		}
+		for _, v := range c {
+			if v != "" {
+				adr.Properties[addressProperties[i]] = append(adr.Properties[addressProperties[i]], v)
+				parts = append(parts, v)
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	adr.Value = l.Param("LABEL")
+	if adr.Value == "" {
+		adr.Value = strings.Join(parts, ", ")
+	}
+	return adr
+}
+
+// decodeGeo returns an h-geo for a GEO value, which may be a geo URI (vCard
+// 4.0) or a latitude and longitude separated by a semicolon (vCard 3.0).
+func decodeGeo(value string) *microformats.Microformat {
+	var lat, long string
+	if coords, ok := strings.CutPrefix(value, "geo:"); ok {
+		coords, _, _ = strings.Cut(coords, ";")
+		lat, long, ok = strings.Cut(coords, ",")
+		if !ok {
+			return nil
+		}
+		long, _, _ = strings.Cut(long, ",") // ignore altitude
+	} else {
+		var ok bool
+		if lat, long, ok = strings.Cut(value, ";"); !ok {
+			return nil
+		}
+	}
+	lat, long = strings.TrimSpace(lat), strings.TrimSpace(long)
+	return &microformats.Microformat{
+		Type: []string{"h-geo"},
+		Properties: map[string][]any{
+			"latitude":  {lat},
+			"longitude": {long},
+		},
+		Value: lat + ";" + long,
+	}
+}
+
+// binaryURI returns the URI value of a PHOTO or LOGO property.  Inline binary
+// data from vCard 3.0 is converted to a data URI.
+func binaryURI(l contentline.Line) string {
+	if !l.HasParam("ENCODING", "b") && !l.HasParam("ENCODING", "base64") {
+		return l.Value
+	}
+	mediatype := "application/octet-stream"
+	if t := l.Param("TYPE"); t != "" {
+		mediatype = "image/" + strings.ToLower(t)
+	}
+	return "data:" + mediatype + ";base64," + l.Value
+}
+
+// structured splits a structured value into its unescaped components, each
+// of which may have multiple values.
+func structured(value string) [][]string {
+	var c [][]string
+	for _, component := range contentline.Split(value, ';') {
+		var values []string
+		for _, v := range contentline.Split(component, ',') {
+			values = append(values, contentline.Unescape(v))
+		}
+		c = append(c, values)
+	}
+	return c
+}
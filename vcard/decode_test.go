@@ -0,0 +1,196 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package vcard
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"willnorris.com/go/microformats"
+)
+
+// ignoreUnexported ignores the parser's internal state when comparing
+// microformats.
+var ignoreUnexported = cmpopts.IgnoreUnexported(microformats.Microformat{})
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[string][]any
+	}{
+		{
+			name: "vcard 3",
+			input: "BEGIN:VCARD\r\nVERSION:3.0\r\n" +
+				"FN:Alice Smith\r\n" +
+				"N:Smith;Alice;B,C;Dr.;\r\n" +
+				"NICKNAME:Al,Ally\r\n" +
+				"ORG:Acme\\, Inc.;Research\r\n" +
+				"TITLE:CEO\r\n" +
+				"EMAIL;TYPE=INTERNET,HOME:alice@example.com\r\n" +
+				"TEL;HOME;VOICE:+1 555 0100\r\n" +
+				"ADR;TYPE=WORK:;;1 Main St;Portland;OR;97201;US\r\n" +
+				"GEO:45.5;-122.6\r\n" +
+				"BDAY:1990-04-15\r\n" +
+				"PHOTO;ENCODING=b;TYPE=JPEG:AAAA\r\n" +
+				"NOTE:line one\\nline two\r\n" +
+				"X-UNKNOWN:ignored\r\n" +
+				"END:VCARD\r\n",
+			want: map[string][]any{
+				"name":             {"Alice Smith"},
+				"family-name":      {"Smith"},
+				"given-name":       {"Alice"},
+				"additional-name":  {"B", "C"},
+				"honorific-prefix": {"Dr."},
+				"nickname":         {"Al", "Ally"},
+				"org":              {"Acme, Inc."},
+				"job-title":        {"CEO"},
+				"email":            {"mailto:alice@example.com"},
+				"tel":              {"+1 555 0100"},
+				"adr": {&microformats.Microformat{
+					Type: []string{"h-adr"},
+					Properties: map[string][]any{
+						"street-address": {"1 Main St"},
+						"locality":       {"Portland"},
+						"region":         {"OR"},
+						"postal-code":    {"97201"},
+						"country-name":   {"US"},
+					},
+					Value: "1 Main St, Portland, OR, 97201, US",
+				}},
+				"geo": {&microformats.Microformat{
+					Type:       []string{"h-geo"},
+					Properties: map[string][]any{"latitude": {"45.5"}, "longitude": {"-122.6"}},
+					Value:      "45.5;-122.6",
+				}},
+				"bday":  {"1990-04-15"},
+				"photo": {"data:image/jpeg;base64,AAAA"},
+				"note":  {"line one\nline two"},
+			},
+		},
+		{
+			name: "vcard 4",
+			input: "BEGIN:VCARD\nVERSION:4.0\n" +
+				"FN:Bob\n" +
+				"PHOTO;X-ALT=\"Bob; smiling\":https://example.com/b.jpg\n" +
+				"TEL;VALUE=uri:tel:+15550101\n" +
+				"GEO:geo:45.5,-122.6\n" +
+				"GENDER:M;he/him\n" +
+				"BDAY:19900415T103000Z\n" +
+				"ANNIVERSARY;VALUE=text:spring\n" +
+				"ADR;LABEL=\"somewhere nice\":;;;;;;\n" +
+				"CATEGORIES:a\\,b,c\n" +
+				"URL:https://example.com/\n" +
+				"END:VCARD\n",
+			want: map[string][]any{
+				"name":            {"Bob"},
+				"photo":           {map[string]string{"value": "https://example.com/b.jpg", "alt": "Bob; smiling"}},
+				"tel":             {"tel:+15550101"},
+				"sex":             {"male"},
+				"gender-identity": {"he/him"},
+				"bday":            {"1990-04-15 10:30:00Z"},
+				"anniversary":     {"spring"},
+				"adr":             {"somewhere nice"},
+				"category":        {"a,b", "c"},
+				"url":             {"https://example.com/"},
+				"geo": {&microformats.Microformat{
+					Type:       []string{"h-geo"},
+					Properties: map[string][]any{"latitude": {"45.5"}, "longitude": {"-122.6"}},
+					Value:      "45.5;-122.6",
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cards, err := Decode(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Decode() returned error: %v", err)
+			}
+			if len(cards) != 1 {
+				t.Fatalf("Decode() returned %d cards, want 1", len(cards))
+			}
+			want := &microformats.Microformat{Type: []string{"h-card"}, Properties: tt.want}
+			if diff := cmp.Diff(want, cards[0], ignoreUnexported); diff != "" {
+				t.Errorf("Decode() differs:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Alice\r\n", "vcard: missing END:VCARD"},
+		{"BEGIN:VCARD\r\nFN Alice\r\nEND:VCARD\r\n", "line 2: missing ':'"},
+		// vCard 3.0 parameters may be quoted, but must be terminated
+		{"BEGIN:VCARD\r\nVERSION:3.0\r\nTEL;TYPE=\"work:555-0100\r\nEND:VCARD\r\n", "unterminated quoted value in property TEL"},
+	}
+	for _, tt := range tests {
+		_, err := Decode(strings.NewReader(tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Decode(%q) returned error %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestDecode_RoundTrip(t *testing.T) {
+	card := parseCard(t, `<div class="h-card">
+	  <span class="p-honorific-prefix">Dr.</span> <span class="p-given-name">Alice</span> <span class="p-family-name">Smith</span>
+	  <img class="u-photo" src="/a.jpg" alt="Alice">
+	  <a class="u-email" href="mailto:alice@example.com">email</a> <a class="u-url" href="/">home</a>
+	  <time class="dt-bday" datetime="1990-04-15">Apr 15</time>
+	  <span class="p-category">a, b</span><span class="p-category">c</span>
+	  <div class="p-adr h-adr"><span class="p-locality">Portland</span>, <span class="p-country-name">US</span></div>
+	</div>`)
+
+	var b strings.Builder
+	if err := Encode(&b, card); err != nil {
+		t.Fatalf("Encode() returned error: %v", err)
+	}
+	cards, err := Decode(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("Decode() returned error: %v", err)
+	}
+	if len(cards) != 1 {
+		t.Fatalf("Decode() returned %d cards, want 1", len(cards))
+	}
+
+	// compare only properties that the h-card had, since FN is always
+	// included in the vCard
+	got := cards[0]
+	delete(got.Properties, "name")
+	if diff := cmp.Diff(card, got, ignoreUnexported); diff != "" {
+		t.Errorf("Decode(Encode()) differs:\n%s", diff)
+	}
+}
+
+func TestDecode_RoundTripOffset(t *testing.T) {
+	for _, datetime := range []string{"1990-04-15T10:00-08:00", "1990-04-15T10:00+05:30", "1990-04-15T10:00Z"} {
+		card := parseCard(t, `<div class="h-card"><span class="p-name">Alice</span><time class="dt-bday" datetime="`+datetime+`">Apr 15</time></div>`)
+		var b strings.Builder
+		if err := Encode(&b, card); err != nil {
+			t.Fatalf("Encode() returned error: %v", err)
+		}
+		cards, err := Decode(strings.NewReader(b.String()))
+		if err != nil {
+			t.Fatalf("Decode() returned error: %v", err)
+		}
+		if len(cards) != 1 {
+			t.Fatalf("Decode() returned %d cards, want 1", len(cards))
+		}
+		// the decoded value is formatted as the parser formats datetimes, so
+		// compare the time it represents
+		got, _ := cards[0].Properties["bday"][0].(string)
+		want, _ := microformats.ParseDateTime(datetime)
+		if dt, ok := microformats.ParseDateTime(got); !ok || !dt.Time.Equal(want.Time) {
+			t.Errorf("Decode(Encode()) bday for %q is %q", datetime, got)
+		}
+	}
+}
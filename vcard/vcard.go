@@ -0,0 +1,459 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+// Package vcard converts h-card microformats to vCard 4.0 (RFC 6350) and
+// jCard (RFC 7095), and reads vCard 3.0 and 4.0 data as h-cards.
+//
+// h-card property names are derived from vCard, so most properties map
+// directly to their vCard equivalent.  Nested h-adr and h-geo microformats
+// are converted to ADR and GEO properties, and alternative text for photos
+// is included in a non-standard X-ALT parameter.
+package vcard
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"strings"
+
+	"willnorris.com/go/microformats"
+	"willnorris.com/go/microformats/internal/contentline"
+	"willnorris.com/go/microformats/internal/mftext"
+)
+
+// ErrNotCard is returned when converting a microformat that is not an h-card.
+var ErrNotCard = errors.New("vcard: microformat is not an h-card")
+
+// property is a single vCard property.
+type property struct {
+	name   string // lowercase property name
+	params []param
+
+	// typ is the value type, such as "text" or "uri", as used by jCard.
+	typ string
+
+	// values are the values of the property.  Each value is a list of
+	// components, and each component a list of values.  Most properties
+	// have a single value with a single component.
+	values [][][]string
+
+	// structured is whether the property value has multiple components,
+	// such as N or ADR.
+	structured bool
+}
+
+// param is a single property parameter.
+type param struct {
+	name  string // lowercase parameter name
+	value string
+}
+
+// text returns a property with a single text value.
+func text(name, value string) property {
+	return property{name: name, typ: "text", values: [][][]string{{{value}}}}
+}
+
+// uri returns a property with a single URI value.
+func uri(name, value string) property {
+	return property{name: name, typ: "uri", values: [][][]string{{{value}}}}
+}
+
+// defaultTypes are the default value types of properties, which need not be
+// specified with a VALUE parameter.
+var defaultTypes = map[string][]string{
+	"anniversary": {"date", "date-time", "date-and-or-time"},
+	"bday":        {"date", "date-time", "date-and-or-time"},
+	"geo":         {"uri"},
+	"impp":        {"uri"},
+	"key":         {"uri"},
+	"logo":        {"uri"},
+	"photo":       {"uri"},
+	"uid":         {"uri"},
+	"url":         {"uri"},
+}
+
+// properties returns the vCard properties for card.
+func properties(card *microformats.Microformat) ([]property, error) {
+	if card == nil || !slices.Contains(card.Type, "h-card") {
+		return nil, ErrNotCard
+	}
+
+	props := []property{text("version", "4.0")}
+
+	// FN is required, so construct one if the h-card has no name
+	fn := mftext.First(card, "name")
+	if fn == "" {
+		var parts []string
+		for _, name := range []string{"honorific-prefix", "given-name", "additional-name", "family-name", "honorific-suffix"} {
+			parts = append(parts, mftext.Values(card, name)...)
+		}
+		fn = strings.Join(parts, " ")
+	}
+	if fn == "" {
+		fn = mftext.First(card, "nickname")
+	}
+	if fn == "" {
+		fn = mftext.First(card, "org")
+	}
+	props = append(props, text("fn", fn))
+
+	n := components(card, "family-name", "given-name", "additional-name", "honorific-prefix", "honorific-suffix")
+	if n != nil {
+		props = append(props, property{name: "n", typ: "text", values: [][][]string{n}, structured: true})
+	}
+
+	for _, v := range mftext.Values(card, "nickname") {
+		props = append(props, text("nickname", v))
+	}
+	for _, v := range card.Properties["photo"] {
+		p := uri("photo", mftext.Value(v))
+		if m, ok := v.(map[string]string); ok && m["alt"] != "" {
+			p.params = append(p.params, param{"x-alt", m["alt"]})
+		}
+		props = append(props, p)
+	}
+	for _, name := range []string{"bday", "anniversary"} {
+		for _, v := range mftext.Values(card, name) {
+			props = append(props, dateProperty(name, v))
+		}
+	}
+	if sex, identity := mftext.First(card, "sex"), mftext.First(card, "gender-identity"); sex != "" || identity != "" {
+		props = append(props, property{
+			name: "gender", typ: "text", structured: true,
+			values: [][][]string{{{genderSex(sex)}, {identity}}},
+		})
+	}
+
+	props = append(props, addresses(card)...)
+
+	for _, v := range mftext.Values(card, "tel") {
+		if strings.HasPrefix(v, "tel:") {
+			props = append(props, uri("tel", v))
+		} else {
+			props = append(props, text("tel", v))
+		}
+	}
+	for _, v := range mftext.Values(card, "email") {
+		props = append(props, text("email", strings.TrimPrefix(v, "mailto:")))
+	}
+	for _, v := range mftext.Values(card, "impp") {
+		props = append(props, uri("impp", v))
+	}
+	for _, v := range mftext.Values(card, "tz") {
+		props = append(props, text("tz", v))
+	}
+	props = append(props, geos(card)...)
+
+	for _, v := range mftext.Values(card, "job-title") {
+		props = append(props, text("title", v))
+	}
+	for _, v := range mftext.Values(card, "role") {
+		props = append(props, text("role", v))
+	}
+	for _, v := range card.Properties["logo"] {
+		props = append(props, uri("logo", mftext.Value(v)))
+	}
+	for _, v := range card.Properties["org"] {
+		if mf, ok := v.(*microformats.Microformat); ok {
+			if name := mftext.First(mf, "name"); name != "" {
+				props = append(props, text("org", name))
+			}
+			continue
+		}
+		props = append(props, text("org", mftext.Value(v)))
+	}
+	if categories := mftext.Values(card, "category"); len(categories) > 0 {
+		p := text("categories", "")
+		p.values = [][][]string{{categories}}
+		props = append(props, p)
+	}
+	for _, v := range mftext.Values(card, "note") {
+		props = append(props, text("note", v))
+	}
+	for _, v := range mftext.Values(card, "uid") {
+		if contentline.IsURI(v) {
+			props = append(props, uri("uid", v))
+		} else {
+			props = append(props, text("uid", v))
+		}
+	}
+	for _, v := range mftext.Values(card, "url") {
+		props = append(props, uri("url", v))
+	}
+	for _, v := range mftext.Values(card, "key") {
+		props = append(props, uri("key", v))
+	}
+
+	return props, nil
+}
+
+// addressProperties are the h-adr properties that make up the components of
+// an ADR value, in order.
+var addressProperties = []string{
+	"post-office-box", "extended-address", "street-address",
+	"locality", "region", "postal-code", "country-name",
+}
+
+// addresses returns the ADR properties for card, from nested h-adr
+// microformats, plain text adr values, and address properties of the h-card
+// itself.
+func addresses(card *microformats.Microformat) []property {
+	var props []property
+	for _, v := range card.Properties["adr"] {
+		if mf, ok := v.(*microformats.Microformat); ok {
+			if c := components(mf, addressProperties...); c != nil {
+				props = append(props, property{name: "adr", typ: "text", values: [][][]string{c}, structured: true})
+			}
+			continue
+		}
+		// unstructured addresses are included as a label
+		p := property{name: "adr", typ: "text", values: [][][]string{emptyComponents(len(addressProperties))}, structured: true}
+		p.params = append(p.params, param{"label", mftext.Value(v)})
+		props = append(props, p)
+	}
+	if c := components(card, addressProperties...); c != nil {
+		props = append(props, property{name: "adr", typ: "text", values: [][][]string{c}, structured: true})
+	}
+	return props
+}
+
+// geos returns the GEO properties for card, from nested h-geo microformats,
+// plain text geo values, and latitude and longitude properties of the h-card
+// itself.
+func geos(card *microformats.Microformat) []property {
+	var props []property
+	for _, v := range card.Properties["geo"] {
+		if mf, ok := v.(*microformats.Microformat); ok {
+			if lat, long := mftext.First(mf, "latitude"), mftext.First(mf, "longitude"); lat != "" && long != "" {
+				props = append(props, uri("geo", "geo:"+lat+","+long))
+			}
+			continue
+		}
+		// plain text geo values are formatted as "latitude;longitude"
+		s := mftext.Value(v)
+		if lat, long, ok := strings.Cut(s, ";"); ok {
+			props = append(props, uri("geo", "geo:"+strings.TrimSpace(lat)+","+strings.TrimSpace(long)))
+		} else if strings.HasPrefix(s, "geo:") {
+			props = append(props, uri("geo", s))
+		}
+	}
+	if lat, long := mftext.First(card, "latitude"), mftext.First(card, "longitude"); lat != "" && long != "" {
+		props = append(props, uri("geo", "geo:"+lat+","+long))
+	}
+	return props
+}
+
+// dateProperty returns a date property with value s.  Values that are not
+// valid dates are included as text.
+func dateProperty(name, s string) property {
+	dt, ok := microformats.ParseDateTime(s)
+	if !ok || !dt.HasDate {
+		return text(name, s)
+	}
+	if !dt.HasTime {
+		return property{name: name, typ: "date", values: [][][]string{{{dt.Time.Format("2006-01-02")}}}}
+	}
+	layout := "2006-01-02T15:04"
+	if dt.HasSeconds {
+		layout += ":05"
+	}
+	if dt.HasTZ {
+		layout += "Z07:00"
+	}
+	return property{name: name, typ: "date-time", values: [][][]string{{{dt.Time.Format(layout)}}}}
+}
+
+// genderSex returns the vCard sex component for the h-card sex value s.
+func genderSex(s string) string {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "":
+		return ""
+	case "m", "male":
+		return "M"
+	case "f", "female":
+		return "F"
+	case "n", "none":
+		return "N"
+	case "u", "unknown":
+		return "U"
+	}
+	return "O"
+}
+
+// components returns the values of the named properties of item as the
+// components of a structured value, or nil if none of the properties are
+// present.
+func components(item *microformats.Microformat, names ...string) [][]string {
+	c := make([][]string, len(names))
+	var found bool
+	for i, name := range names {
+		c[i] = mftext.Values(item, name)
+		if len(c[i]) > 0 {
+			found = true
+		} else {
+			c[i] = []string{""}
+		}
+	}
+	if !found {
+		return nil
+	}
+	return c
+}
+
+// emptyComponents returns n empty components.
+func emptyComponents(n int) [][]string {
+	c := make([][]string, n)
+	for i := range c {
+		c[i] = []string{""}
+	}
+	return c
+}
+
+// Encode writes cards to w in vCard 4.0 format.  It returns ErrNotCard if any
+// of cards is not an h-card.
+func Encode(w io.Writer, cards ...*microformats.Microformat) error {
+	var b strings.Builder
+	for _, card := range cards {
+		props, err := properties(card)
+		if err != nil {
+			return err
+		}
+		contentline.WriteLine(&b, "BEGIN:VCARD")
+		for _, p := range props {
+			contentline.WriteLine(&b, p.String())
+		}
+		contentline.WriteLine(&b, "END:VCARD")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// String returns p in vCard text format, without line folding.
+func (p property) String() string {
+	var b strings.Builder
+	b.WriteString(strings.ToUpper(p.name))
+
+	params := p.params
+	if !p.hasDefaultType() {
+		params = append([]param{{"value", p.typ}}, params...)
+	}
+	for _, prm := range params {
+		b.WriteString(";")
+		b.WriteString(strings.ToUpper(prm.name))
+		b.WriteString("=")
+		b.WriteString(paramValue(prm.value))
+	}
+	b.WriteString(":")
+
+	for i, value := range p.values {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		for j, component := range value {
+			if j > 0 {
+				b.WriteString(";")
+			}
+			for k, v := range component {
+				if k > 0 {
+					b.WriteString(",")
+				}
+				b.WriteString(p.escape(v))
+			}
+		}
+	}
+	return b.String()
+}
+
+// hasDefaultType returns whether p has the default value type for its
+// property, and so does not need a VALUE parameter.
+func (p property) hasDefaultType() bool {
+	if types, ok := defaultTypes[p.name]; ok {
+		return slices.Contains(types, p.typ)
+	}
+	return p.typ == "text"
+}
+
+// escape returns v escaped for use in the value of p.
+func (p property) escape(v string) string {
+	switch p.typ {
+	case "date", "date-time":
+		return basicFormat(v)
+	case "uri":
+		return v
+	}
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`).Replace(v)
+}
+
+// basicFormat converts date or date-time v from the extended format used by
+// jCard to the basic format used by vCard, removing the separators of the
+// date and time but preserving a leading "--" for dates without a year and
+// the sign of a UTC offset.
+func basicFormat(v string) string {
+	date, tm, hasTime := strings.Cut(v, "T")
+	prefix := ""
+	if strings.HasPrefix(date, "--") {
+		prefix, date = "--", date[2:]
+	}
+	date = prefix + strings.ReplaceAll(date, "-", "")
+	if !hasTime {
+		return date
+	}
+	offset := ""
+	if i := strings.IndexAny(tm, "+-"); i >= 0 {
+		tm, offset = tm[:i], tm[i:]
+	}
+	return date + "T" + strings.ReplaceAll(tm, ":", "") + strings.ReplaceAll(offset, ":", "")
+}
+
+// paramValue returns v formatted as a parameter value, using the encoding of
+// RFC 6868 and quoting the value if needed.
+func paramValue(v string) string {
+	v = strings.NewReplacer("^", "^^", "\r\n", "^n", "\n", "^n", `"`, "^'").Replace(v)
+	if strings.ContainsAny(v, ":;,") {
+		return `"` + v + `"`
+	}
+	return v
+}
+
+// JCard returns card in jCard format, suitable for encoding as JSON.  It
+// returns ErrNotCard if card is not an h-card.
+func JCard(card *microformats.Microformat) ([]any, error) {
+	props, err := properties(card)
+	if err != nil {
+		return nil, err
+	}
+	jprops := make([]any, 0, len(props))
+	for _, p := range props {
+		params := make(map[string]any)
+		for _, prm := range p.params {
+			params[prm.name] = prm.value
+		}
+		jp := []any{p.name, params, p.typ}
+		for _, value := range p.values {
+			if p.structured {
+				c := make([]any, len(value))
+				for i, component := range value {
+					c[i] = jcardComponent(component)
+				}
+				jp = append(jp, c)
+				continue
+			}
+			for _, component := range value {
+				for _, v := range component {
+					jp = append(jp, v)
+				}
+			}
+		}
+		jprops = append(jprops, jp)
+	}
+	return []any{"vcard", jprops}, nil
+}
+
+// jcardComponent returns a single component of a structured jCard value.
+// Components with multiple values are represented as arrays.
+func jcardComponent(values []string) any {
+	if len(values) == 1 {
+		return values[0]
+	}
+	return values
+}
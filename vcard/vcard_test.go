@@ -0,0 +1,162 @@
+// Copyright (c) The microformats project authors.
+// SPDX-License-Identifier: MIT
+
+package vcard
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"willnorris.com/go/microformats"
+)
+
+// parseCard returns the first item parsed from the HTML in s.
+func parseCard(t *testing.T, s string) *microformats.Microformat {
+	t.Helper()
+	base, _ := url.Parse("https://example.com/")
+	data := microformats.Parse(strings.NewReader(s), base)
+	if len(data.Items) == 0 {
+		t.Fatalf("no items parsed from %q", s)
+	}
+	return data.Items[0]
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want []string // lines between VERSION and END
+	}{
+		{
+			name: "name",
+			html: `<div class="h-card">Alice</div>`,
+			want: []string{"FN:Alice"},
+		},
+		{
+			name: "structured name",
+			html: `<div class="h-card"><span class="p-honorific-prefix">Dr.</span>
+			  <span class="p-given-name">Alice</span> <span class="p-additional-name">B</span>
+			  <span class="p-additional-name">C</span> <span class="p-family-name">Smith</span></div>`,
+			want: []string{"FN:Dr. Alice B C Smith", "N:Smith;Alice;B,C;Dr.;"},
+		},
+		{
+			name: "photo with alt",
+			html: `<div class="h-card"><img class="u-photo" src="/a.jpg" alt="Alice; smiling"><span class="p-name">Alice</span></div>`,
+			want: []string{"FN:Alice", `PHOTO;X-ALT="Alice; smiling":https://example.com/a.jpg`},
+		},
+		{
+			name: "multiple emails and tels",
+			html: `<div class="h-card"><span class="p-name">Alice</span>
+			  <a class="u-email" href="mailto:a@example.com">a</a><a class="u-email" href="mailto:b@example.com">b</a>
+			  <span class="p-tel">+1 555 0100</span><a class="p-tel" href="tel:+15550101">call</a></div>`,
+			want: []string{
+				"FN:Alice",
+				"TEL:+1 555 0100",
+				"TEL:call",
+				"EMAIL:a@example.com",
+				"EMAIL:b@example.com",
+			},
+		},
+		{
+			name: "bday",
+			html: `<div class="h-card"><span class="p-name">Alice</span><time class="dt-bday" datetime="1990-04-15">Apr 15</time></div>`,
+			want: []string{"FN:Alice", "BDAY:19900415"},
+		},
+		{
+			name: "bday with offset",
+			html: `<div class="h-card"><span class="p-name">Alice</span><time class="dt-bday" datetime="1990-04-15T10:00-08:00">Apr 15</time></div>`,
+			want: []string{"FN:Alice", "BDAY:19900415T1000-0800"},
+		},
+		{
+			name: "bday text",
+			html: `<div class="h-card"><span class="p-name">Alice</span><span class="dt-bday">April</span></div>`,
+			want: []string{"FN:Alice", "BDAY;VALUE=text:April"},
+		},
+		{
+			name: "nested adr and geo",
+			html: `<div class="h-card"><span class="p-name">Acme</span>
+			  <div class="p-adr h-adr"><span class="p-street-address">1 Main St, Suite 2</span>
+			    <span class="p-locality">Portland</span><span class="p-region">OR</span>
+			    <span class="p-postal-code">97201</span><span class="p-country-name">US</span></div>
+			  <div class="p-geo h-geo"><data class="p-latitude" value="45.5">N</data><data class="p-longitude" value="-122.6">W</data></div></div>`,
+			want: []string{
+				"FN:Acme",
+				`ADR:;;1 Main St\, Suite 2;Portland;OR;97201;US`,
+				"GEO:geo:45.5,-122.6",
+			},
+		},
+		{
+			name: "flat address",
+			html: `<div class="h-card"><span class="p-name">Bob</span><span class="p-locality">Paris</span><span class="p-adr">somewhere</span></div>`,
+			want: []string{"FN:Bob", "ADR;LABEL=somewhere:;;;;;;", "ADR:;;;Paris;;;"},
+		},
+		{
+			name: "org, categories, note, url",
+			html: `<div class="h-card"><a class="p-name u-url" href="/">Alice</a>
+			  <span class="p-job-title">CEO</span><span class="p-org h-card">Acme</span>
+			  <span class="p-category">a,b</span><span class="p-category">c</span>
+			  <p class="p-note">line one
+line two</p></div>`,
+			want: []string{
+				"FN:Alice",
+				"TITLE:CEO",
+				"ORG:Acme",
+				`CATEGORIES:a\,b,c`,
+				`NOTE:line one\nline two`,
+				"URL:https://example.com/",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := Encode(&b, parseCard(t, tt.html)); err != nil {
+				t.Fatalf("Encode() returned error: %v", err)
+			}
+			want := "BEGIN:VCARD\r\nVERSION:4.0\r\n" + strings.Join(tt.want, "\r\n") + "\r\nEND:VCARD\r\n"
+			if diff := cmp.Diff(want, b.String()); diff != "" {
+				t.Errorf("Encode() differs:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEncode_Errors(t *testing.T) {
+	entry := &microformats.Microformat{Type: []string{"h-entry"}}
+	if err := Encode(new(strings.Builder), entry); !errors.Is(err, ErrNotCard) {
+		t.Errorf("Encode(h-entry) returned error %v, want %v", err, ErrNotCard)
+	}
+	if err := Encode(new(strings.Builder), nil); !errors.Is(err, ErrNotCard) {
+		t.Errorf("Encode(nil) returned error %v, want %v", err, ErrNotCard)
+	}
+}
+
+func TestJCard(t *testing.T) {
+	card := parseCard(t, `<div class="h-card"><span class="p-given-name">Alice</span> <span class="p-family-name">Smith</span>
+	  <img class="u-photo" src="/a.jpg" alt="Alice"><time class="dt-bday" datetime="1990-04-15">Apr 15</time>
+	  <span class="p-category">a</span><span class="p-category">b</span></div>`)
+	got, err := JCard(card)
+	if err != nil {
+		t.Fatalf("JCard() returned error: %v", err)
+	}
+	b, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("error marshaling jCard: %v", err)
+	}
+
+	want := `["vcard",[` +
+		`["version",{},"text","4.0"],` +
+		`["fn",{},"text","Alice Smith"],` +
+		`["n",{},"text",["Smith","Alice","","",""]],` +
+		`["photo",{"x-alt":"Alice"},"uri","https://example.com/a.jpg"],` +
+		`["bday",{},"date","1990-04-15"],` +
+		`["categories",{},"text","a","b"]]]`
+	if diff := cmp.Diff(want, string(b)); diff != "" {
+		t.Errorf("JCard() differs:\n%s", diff)
+	}
+}